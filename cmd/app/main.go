@@ -5,12 +5,14 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"app/internal/api/v1/router"
 	"app/internal/config"
 	"app/internal/logger"
+	"app/internal/telemetry"
 
 	"github.com/joho/godotenv"
 )
@@ -37,8 +39,14 @@ func main() {
 		logger.Fatal().Msgf("Error loading config: %v", err)
 	}
 
-	// 2. Build router (and get DB connection)
-	r, pool, err := router.New(cfg, logger)
+	// 2. Set up tracing before any instrumented client is created
+	shutdownTracing, err := telemetry.Setup(context.Background(), cfg)
+	if err != nil {
+		logger.Fatal().Msgf("Failed to set up tracing: %v", err)
+	}
+
+	// 3. Build router (and get DB connection)
+	r, pool, workers, err := router.New(cfg, logger)
 	if err != nil {
 		logger.Fatal().Msgf("Failed to build router: %v", err)
 	}
@@ -50,7 +58,7 @@ func main() {
 		port = cfg.Port
 	}
 
-	// 3. Create HTTP server
+	// 4. Create HTTP server
 	// WriteTimeout is set to 5 minutes to accommodate long-running streaming responses
 	// (e.g., AI chat streaming which involves query rewriting, RAG retrieval, and LLM streaming)
 	srv := &http.Server{
@@ -61,14 +69,24 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	// 4. Start server in a goroutine
+	// 5. Start background workers and the server in goroutines
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workersWG sync.WaitGroup
+	for _, worker := range workers {
+		workersWG.Add(1)
+		go func() {
+			defer workersWG.Done()
+			worker.Run(workerCtx)
+		}()
+	}
+
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Fatal().Msgf("Listen: %s\n", err)
 		}
 	}()
 
-	// 5. Graceful shutdown
+	// 6. Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -78,4 +96,13 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Fatal().Msgf("Server forced to shutdown: %v", err)
 	}
+
+	// Stop background workers before the DB pool is closed
+	stopWorkers()
+	workersWG.Wait()
+
+	// Flush the spans of the last requests
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error().Err(err).Msg("Failed to flush traces")
+	}
 }
@@ -0,0 +1,36 @@
+package dto
+
+import "time"
+
+// AdminUserResponseDTO is a user as shown to operators.
+type AdminUserResponseDTO struct {
+	UserID       string    `json:"user_id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	AvatarURL    string    `json:"avatar_url"`
+	Roles        []string  `json:"roles"`
+	LectureCount int       `json:"lecture_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// AdminLectureResponseDTO is a lecture as shown to operators, with its processing state and
+// the error details of a failed ingestion.
+type AdminLectureResponseDTO struct {
+	ID                  string                 `json:"id"`
+	UserID              string                 `json:"user_id"`
+	CourseID            string                 `json:"course_id"`
+	Title               string                 `json:"title"`
+	FileType            string                 `json:"file_type"`
+	StoragePath         string                 `json:"storage_path"`
+	Status              string                 `json:"status"`
+	ErrorDetails        map[string]interface{} `json:"error_details,omitempty"`
+	TotalSlides         int                    `json:"total_slides"`
+	TotalSubImages      int                    `json:"total_sub_images"`
+	ProcessedSubImages  int                    `json:"processed_sub_images"`
+	EmbeddingsComplete  bool                   `json:"embeddings_complete"`
+	CreatedAt           time.Time              `json:"created_at"`
+	UpdatedAt           time.Time              `json:"updated_at"`
+	ProcessingStartedAt *time.Time             `json:"processing_started_at,omitempty"`
+	CompletedAt         *time.Time             `json:"completed_at,omitempty"`
+}
@@ -12,7 +12,8 @@ type ChatUpdateDTO struct {
 
 type ChatResponseDTO struct {
 	ID        string    `json:"id"`
-	LectureID string    `json:"lecture_id"`
+	LectureID *string   `json:"lecture_id"` // set for lecture chats
+	CourseID  *string   `json:"course_id"`  // set for course chats
 	UserID    string    `json:"user_id"`
 	Title     string    `json:"title"`
 	CreatedAt time.Time `json:"created_at"`
@@ -20,10 +21,20 @@ type ChatResponseDTO struct {
 }
 
 type MessagePartDTO struct {
-	Type      string            `json:"type"`
-	Text      string            `json:"text,omitempty"`
-	Reference *ReferenceDTO     `json:"reference,omitempty"`
-	Data      *ReferencePartDTO `json:"data,omitempty"`
+	Type       string            `json:"type"`
+	Text       string            `json:"text,omitempty"`
+	Reference  *ReferenceDTO     `json:"reference,omitempty"`
+	Data       *ReferencePartDTO `json:"data,omitempty"`
+	SourceID   string            `json:"sourceId,omitempty"`
+	URL        string            `json:"url,omitempty"`
+	Title      string            `json:"title,omitempty"`
+	MediaType  string            `json:"mediaType,omitempty"`
+	Filename   string            `json:"filename,omitempty"`
+	ToolCallID string            `json:"toolCallId,omitempty"`
+	ToolName   string            `json:"toolName,omitempty"`
+	State      string            `json:"state,omitempty"`
+	Input      interface{}       `json:"input,omitempty"`
+	Output     interface{}       `json:"output,omitempty"`
 }
 
 type ReferencePartDTO struct {
@@ -43,14 +54,33 @@ type MessageCreateDTO struct {
 }
 
 type MessageResponseDTO struct {
-	ID        string           `json:"id"`
-	ChatID    string           `json:"chat_id"`
-	Role      string           `json:"role"`
-	Parts     []MessagePartDTO `json:"parts"`
-	CreatedAt time.Time        `json:"created_at"`
+	ID       string           `json:"id"`
+	ChatID   string           `json:"chat_id"`
+	ParentID *string          `json:"parent_id"`
+	Role     string           `json:"role"`
+	Parts    []MessagePartDTO `json:"parts"`
+	// Metadata holds the model used and, for assistant replies, a status of complete, stopped, aborted
+	// or error with the error reason, so interrupted replies can be offered for retry.
+	Metadata map[string]interface{} `json:"metadata"`
+	// SiblingIDs lists the alternative versions of this message, including itself, oldest first.
+	// Pass one to the active-branch endpoint to switch to it.
+	SiblingIDs []string  `json:"sibling_ids"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 type ChatStreamRequestDTO struct {
 	Parts []MessagePartDTO `json:"parts" validate:"required"`
 	Model string           `json:"model" validate:"required"`
 }
+
+// ChatRegenerateRequestDTO asks for a new assistant reply, optionally from a different model.
+// MessageID names the reply to regenerate; when omitted, the last reply on the active branch is used.
+type ChatRegenerateRequestDTO struct {
+	MessageID string `json:"message_id,omitempty"`
+	Model     string `json:"model" validate:"required"`
+}
+
+// ChatActiveBranchRequestDTO selects the branch containing MessageID, usually one of a message's siblings.
+type ChatActiveBranchRequestDTO struct {
+	MessageID string `json:"message_id" validate:"required"`
+}
@@ -1,5 +1,10 @@
 package dto
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // PubSubPushRequest is the request body for a Pub/Sub push notification.
 type PubSubPushRequest struct {
 	Message      PubSubMessage `json:"message"`
@@ -12,3 +17,32 @@ type PubSubMessage struct {
 	MessageID  string            `json:"messageId"`
 	Attributes map[string]string `json:"attributes"`
 }
+
+// DLQMessageResponseDTO is the admin view of a persisted dead-letter message.
+type DLQMessageResponseDTO struct {
+	ID               string            `json:"id"`
+	SubscriptionName string            `json:"subscription_name"`
+	MessageID        string            `json:"message_id"`
+	Payload          json.RawMessage   `json:"payload" swaggertype:"object"`
+	Attributes       map[string]string `json:"attributes,omitempty"`
+	Status           string            `json:"status"`
+	ReplayCount      int               `json:"replay_count"`
+	LastReplayedAt   *time.Time        `json:"last_replayed_at,omitempty"`
+	LastReplayedBy   *string           `json:"last_replayed_by,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+}
+
+// DLQStatusUpdateDTO is the request body for resolving a dead-letter message.
+type DLQStatusUpdateDTO struct {
+	Status string `json:"status" validate:"required,oneof=unprocessed processed ignored"`
+}
+
+// DLQReplayResponseDTO is the response for a successful dead-letter message replay.
+type DLQReplayResponseDTO struct {
+	Message            DLQMessageResponseDTO `json:"message"`
+	PublishedMessageID string                `json:"published_message_id,omitempty"`
+	// OutboxMessageID is set instead of PublishedMessageID when the replay is queued for the
+	// outbox dispatcher to publish.
+	OutboxMessageID string `json:"outbox_message_id,omitempty"`
+}
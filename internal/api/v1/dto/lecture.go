@@ -7,9 +7,18 @@ type LectureResponseDTO struct {
 	CourseID              string                 `json:"course_id"`
 	Title                 string                 `json:"title"`
 	StoragePath           string                 `json:"storage_path"`
+	FileType              string                 `json:"file_type"`
+	MimeType              string                 `json:"mime_type"`
 	Status                string                 `json:"status"`
 	EmbeddingErrorDetails map[string]interface{} `json:"embedding_error_details"`
 	TotalSlides           int                    `json:"total_slides"`
+	TotalSubImages        int                    `json:"total_sub_images"`
+	ProcessedSubImages    int                    `json:"processed_sub_images"`
+	Stage                 string                 `json:"stage"`
+	PercentComplete       int                    `json:"percent_complete"`
+	ProcessingStartedAt   *time.Time             `json:"processing_started_at"`
+	CompletedAt           *time.Time             `json:"completed_at"`
+	ElapsedSeconds        int64                  `json:"elapsed_seconds"`
 	CreatedAt             time.Time              `json:"created_at"`
 	UpdatedAt             time.Time              `json:"updated_at"`
 	AccessedAt            time.Time              `json:"accessed_at"`
@@ -20,3 +29,33 @@ type LectureUpdateDTO struct {
 	AccessedAt *time.Time `json:"accessed_at,omitempty"`
 	CourseID   *string    `json:"course_id,omitempty"`
 }
+
+// LectureReprocessResponseDTO is the response for a successfully re-queued lecture.
+type LectureReprocessResponseDTO struct {
+	LectureID string `json:"lecture_id"`
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+}
+
+// LectureProgressDTO reports how far a lecture has got through processing.
+// Stage is one of uploading, queued, parsing, analyzing_images, embedding, finalizing, complete or failed.
+type LectureProgressDTO struct {
+	LectureID          string     `json:"lecture_id"`
+	Status             string     `json:"status"`
+	Stage              string     `json:"stage"`
+	PercentComplete    int        `json:"percent_complete"`
+	TotalSlides        int        `json:"total_slides"`
+	TotalSubImages     int        `json:"total_sub_images"`
+	ProcessedSubImages int        `json:"processed_sub_images"`
+	EmbeddingsComplete bool       `json:"embeddings_complete"`
+	StartedAt          *time.Time `json:"started_at"`
+	CompletedAt        *time.Time `json:"completed_at"`
+	ElapsedSeconds     int64      `json:"elapsed_seconds"`
+	// EtaSeconds is estimated from recent lectures of a similar slide count; null when unknown.
+	EtaSeconds *int64 `json:"eta_seconds"`
+}
+
+// LectureProgressListResponseDTO lists the progress of every in-flight lecture a user owns.
+type LectureProgressListResponseDTO struct {
+	Lectures []LectureProgressDTO `json:"lectures"`
+}
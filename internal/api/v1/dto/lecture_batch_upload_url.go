@@ -2,14 +2,26 @@ package dto
 
 // LectureUploadURLRequestDTO is the request body for getting upload URLs.
 type LectureUploadURLRequestDTO struct {
-	CourseID  string   `json:"course_id" validate:"required"`
-	Filenames []string `json:"filenames" validate:"required,min=1,max=10"`
+	CourseID string                 `json:"course_id" validate:"required"`
+	Files    []LectureUploadFileDTO `json:"files" validate:"required,min=1,max=10,dive"`
+}
+
+// LectureUploadFileDTO describes a file the client is about to upload.
+// The declared size is pinned into the presigned URL, so the upload must match it exactly.
+// When FileType is omitted it is derived from the filename's extension.
+type LectureUploadFileDTO struct {
+	Filename  string `json:"filename" validate:"required"`
+	FileType  string `json:"file_type,omitempty" validate:"omitempty,oneof=pdf pptx docx png jpeg"`
+	SizeBytes int64  `json:"size_bytes" validate:"required,gt=0"`
 }
 
 // LectureUploadURLResponseDTO is the response for a successful upload URL request.
+// The upload must be sent with ContentType as its Content-Type header.
 type LectureUploadURLResponseDTO struct {
-	LectureID string `json:"lecture_id"`
-	UploadURL string `json:"upload_url"`
+	LectureID   string `json:"lecture_id"`
+	UploadURL   string `json:"upload_url"`
+	FileType    string `json:"file_type"`
+	ContentType string `json:"content_type"`
 }
 
 // LectureBatchUploadURLResponseDTO is the response for a successful batch upload URL request.
@@ -0,0 +1,23 @@
+package dto
+
+import "time"
+
+// LectureStatusEventDTO is sent as a "lecture" event whenever the lecture's processing state changes.
+type LectureStatusEventDTO struct {
+	LectureID          string    `json:"lecture_id"`
+	Status             string    `json:"status"`
+	TotalSlides        int       `json:"total_slides"`
+	TotalSubImages     int       `json:"total_sub_images"`
+	ProcessedSubImages int       `json:"processed_sub_images"`
+	EmbeddingsComplete bool      `json:"embeddings_complete"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// ChatTitleEventDTO is sent as a "chat-title" event when a chat of the lecture is renamed,
+// e.g. once its title has been generated.
+type ChatTitleEventDTO struct {
+	LectureID string    `json:"lecture_id"`
+	ChatID    string    `json:"chat_id"`
+	Title     string    `json:"title"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
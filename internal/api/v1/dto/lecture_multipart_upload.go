@@ -0,0 +1,48 @@
+package dto
+
+import "time"
+
+// LectureMultipartUploadRequestDTO is the request body for starting a multipart upload.
+type LectureMultipartUploadRequestDTO struct {
+	SizeBytes int64 `json:"size_bytes" validate:"required,gt=0"`
+}
+
+// LectureMultipartUploadResponseDTO describes how the client should split the file.
+// Every part except the last must be exactly PartSizeBytes long.
+type LectureMultipartUploadResponseDTO struct {
+	LectureID     string `json:"lecture_id"`
+	UploadID      string `json:"upload_id"`
+	PartSizeBytes int64  `json:"part_size_bytes"`
+	PartCount     int32  `json:"part_count"`
+	ContentType   string `json:"content_type"`
+}
+
+// LectureUploadPartURLsRequestDTO is the request body for presigning part upload URLs.
+type LectureUploadPartURLsRequestDTO struct {
+	PartNumbers []int32 `json:"part_numbers" validate:"required,min=1,max=100,dive,min=1,max=10000"`
+}
+
+// LectureUploadPartURLDTO is a presigned URL for uploading one part.
+type LectureUploadPartURLDTO struct {
+	PartNumber int32     `json:"part_number"`
+	UploadURL  string    `json:"upload_url"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// LectureUploadPartURLsResponseDTO is the response for a part URL request.
+type LectureUploadPartURLsResponseDTO struct {
+	Parts []LectureUploadPartURLDTO `json:"parts"`
+}
+
+// LectureUploadedPartDTO is a part storage has already received.
+type LectureUploadedPartDTO struct {
+	PartNumber   int32      `json:"part_number"`
+	ETag         string     `json:"etag"`
+	SizeBytes    int64      `json:"size_bytes"`
+	LastModified *time.Time `json:"last_modified,omitempty"`
+}
+
+// LectureUploadedPartsResponseDTO lists the parts uploaded so far, so a client can resume.
+type LectureUploadedPartsResponseDTO struct {
+	Parts []LectureUploadedPartDTO `json:"parts"`
+}
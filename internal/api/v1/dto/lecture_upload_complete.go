@@ -1,8 +1,16 @@
 package dto
 
 // LectureUploadCompleteRequestDTO is the request body for completing an upload.
+// The lecture ID is in the URL path. Parts only apply to multipart uploads; when they are
+// omitted, every part storage has received is assembled.
 type LectureUploadCompleteRequestDTO struct {
-	// No fields needed - the lecture ID is in the URL path
+	Parts []LectureCompletedPartDTO `json:"parts,omitempty" validate:"omitempty,max=10000,dive"`
+}
+
+// LectureCompletedPartDTO identifies an uploaded part by its number and the ETag storage returned for it.
+type LectureCompletedPartDTO struct {
+	PartNumber int32  `json:"part_number" validate:"required,min=1,max=10000"`
+	ETag       string `json:"etag" validate:"required"`
 }
 
 // LectureUploadCompleteResponseDTO is the response for a successful upload completion.
@@ -0,0 +1,41 @@
+package dto
+
+import "time"
+
+// SearchResultDTO is one match of a search. LectureID and ChatID point at where the match lives:
+// chat messages have both, notes and lectures only a lecture, and courses neither.
+type SearchResultDTO struct {
+	Kind         string    `json:"kind"` // 'course', 'lecture', 'note' or 'message'
+	ID           string    `json:"id"`
+	CourseID     string    `json:"course_id"`
+	CourseTitle  string    `json:"course_title"`
+	LectureID    *string   `json:"lecture_id"`
+	LectureTitle *string   `json:"lecture_title"`
+	ChatID       *string   `json:"chat_id"`
+	ChatTitle    *string   `json:"chat_title"`
+	Snippet      string    `json:"snippet"` // HTML with matches wrapped in <mark>
+	Rank         float64   `json:"rank"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type SearchResponseDTO struct {
+	Results []SearchResultDTO `json:"results"`
+	Limit   int               `json:"limit"`
+	Offset  int               `json:"offset"`
+}
+
+// ChunkMatchDTO is a passage of a lecture found by semantic search. Score is the cosine
+// similarity to the query, higher being closer.
+type ChunkMatchDTO struct {
+	ChunkID      string  `json:"chunk_id"`
+	LectureID    string  `json:"lecture_id"`
+	LectureTitle string  `json:"lecture_title"`
+	CourseID     string  `json:"course_id"`
+	SlideNumber  int     `json:"slide_number"`
+	Text         string  `json:"text"`
+	Score        float64 `json:"score"`
+}
+
+type SemanticSearchResponseDTO struct {
+	Results []ChunkMatchDTO `json:"results"`
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"app/internal/api/v1/dto"
+	"app/internal/model"
+	"app/internal/service"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+)
+
+// AdminHandler serves the support endpoints under /admin.
+type AdminHandler struct {
+	service  service.AdminService
+	validate *validator.Validate
+	logger   zerolog.Logger
+}
+
+func NewAdminHandler(s service.AdminService, v *validator.Validate, l zerolog.Logger) *AdminHandler {
+	return &AdminHandler{service: s, validate: v, logger: l}
+}
+
+// RegisterRoutes mounts the admin endpoints.
+// adminMw must authenticate the caller and restrict access to admins.
+func (h *AdminHandler) RegisterRoutes(mux *http.ServeMux, adminMw func(http.Handler) http.Handler) {
+	mux.Handle("GET /admin/users", adminMw(http.HandlerFunc(h.findUser)))
+	mux.Handle("GET /admin/users/{id}", adminMw(http.HandlerFunc(h.getUser)))
+	mux.Handle("GET /admin/users/{id}/lectures", adminMw(http.HandlerFunc(h.listUserLectures)))
+	mux.Handle("GET /admin/ingestions/failed", adminMw(http.HandlerFunc(h.listFailedIngestions)))
+}
+
+// findUser godoc
+// @Summary Find a user by email
+// @Description Looks up a user by email, ignoring case, with their database roles and lecture count. Admin only.
+// @Tags admin
+// @Produce json
+// @Param email query string true "Email address"
+// @Success 200 {object} dto.AdminUserResponseDTO
+// @Failure 400 {string} string "Missing email"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Failed to find user"
+// @Router /admin/users [get]
+func (h *AdminHandler) findUser(w http.ResponseWriter, r *http.Request) {
+	email := r.URL.Query().Get("email")
+	if email == "" {
+		http.Error(w, "Missing email", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.service.FindUserByEmail(r.Context(), email)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to find user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, toAdminUserResponseDTO(user))
+}
+
+// getUser godoc
+// @Summary Get a user
+// @Description Retrieves a user's profile with their database roles and lecture count. Admin only.
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} dto.AdminUserResponseDTO
+// @Failure 400 {string} string "Invalid user ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Failed to get user"
+// @Router /admin/users/{id} [get]
+func (h *AdminHandler) getUser(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if err := h.validate.Var(userID, "uuid"); err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.service.GetUser(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, toAdminUserResponseDTO(user))
+}
+
+// listUserLectures godoc
+// @Summary List a user's lectures
+// @Description Lists a user's lectures with their processing status and error details, most recently updated first. Admin only.
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Param status query string false "Lecture status" Enums(uploading, pending_processing, parsing, processing, complete, failed)
+// @Param limit query int false "Maximum number of lectures to return, at most 100" default(50)
+// @Param offset query int false "Number of lectures to skip" default(0)
+// @Success 200 {array} dto.AdminLectureResponseDTO
+// @Failure 400 {string} string "Invalid user ID or status"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Failed to list lectures"
+// @Router /admin/users/{id}/lectures [get]
+func (h *AdminHandler) listUserLectures(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if err := h.validate.Var(userID, "uuid"); err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	limit, offset := parseAdminPage(r)
+
+	lectures, err := h.service.ListUserLectures(r.Context(), userID, r.URL.Query().Get("status"), limit, offset)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidLectureStatus) {
+			http.Error(w, "Invalid status filter", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to list lectures: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, toAdminLectureResponseDTOs(lectures))
+}
+
+// listFailedIngestions godoc
+// @Summary List failed ingestions
+// @Description Lists lectures whose processing failed, with the recorded error details, most recent first. Dead-lettered ingestion jobs are listed under /admin/dlq. Admin only.
+// @Tags admin
+// @Produce json
+// @Param user_id query string false "Only lectures of this user"
+// @Param limit query int false "Maximum number of lectures to return, at most 100" default(50)
+// @Param offset query int false "Number of lectures to skip" default(0)
+// @Success 200 {array} dto.AdminLectureResponseDTO
+// @Failure 400 {string} string "Invalid user ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Failed to list failed ingestions"
+// @Router /admin/ingestions/failed [get]
+func (h *AdminHandler) listFailedIngestions(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if err := h.validate.Var(userID, "omitempty,uuid"); err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	limit, offset := parseAdminPage(r)
+
+	lectures, err := h.service.ListFailedIngestions(r.Context(), userID, limit, offset)
+	if err != nil {
+		http.Error(w, "Failed to list failed ingestions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, toAdminLectureResponseDTOs(lectures))
+}
+
+func (h *AdminHandler) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// adminMaxLimit caps the page size of admin listings.
+const adminMaxLimit = 100
+
+// parseAdminPage reads the limit and offset query parameters, defaulting to 50 and 0.
+func parseAdminPage(r *http.Request) (int, int) {
+	q := r.URL.Query()
+	limit := 50
+	if l := q.Get("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = min(v, adminMaxLimit)
+		}
+	}
+	offset := 0
+	if o := q.Get("offset"); o != "" {
+		if v, err := strconv.Atoi(o); err == nil && v >= 0 {
+			offset = v
+		}
+	}
+	return limit, offset
+}
+
+func toAdminUserResponseDTO(u *service.AdminUser) dto.AdminUserResponseDTO {
+	roles := u.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	return dto.AdminUserResponseDTO{
+		UserID:       u.User.UserID,
+		Name:         u.User.Name,
+		Email:        u.User.Email,
+		AvatarURL:    u.User.AvatarURL,
+		Roles:        roles,
+		LectureCount: u.LectureCount,
+		CreatedAt:    u.User.CreatedAt,
+		UpdatedAt:    u.User.UpdatedAt,
+	}
+}
+
+func toAdminLectureResponseDTOs(lectures []model.Lecture) []dto.AdminLectureResponseDTO {
+	resp := make([]dto.AdminLectureResponseDTO, len(lectures))
+	for i, l := range lectures {
+		resp[i] = dto.AdminLectureResponseDTO{
+			ID:                  l.ID,
+			UserID:              l.UserID,
+			CourseID:            l.CourseID,
+			Title:               l.Title,
+			FileType:            l.FileType,
+			StoragePath:         l.StoragePath,
+			Status:              l.Status,
+			ErrorDetails:        l.EmbeddingErrorDetails,
+			TotalSlides:         l.TotalSlides,
+			TotalSubImages:      l.TotalSubImages,
+			ProcessedSubImages:  l.ProcessedSubImages,
+			EmbeddingsComplete:  l.EmbeddingsComplete,
+			CreatedAt:           l.CreatedAt,
+			UpdatedAt:           l.UpdatedAt,
+			ProcessingStartedAt: l.ProcessingStartedAt,
+			CompletedAt:         l.CompletedAt,
+		}
+	}
+	return resp
+}
@@ -1,16 +1,13 @@
 package handler
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"app/internal/api/v1/dto"
 	"app/internal/middleware"
@@ -40,43 +37,75 @@ func (h *ChatHandler) RegisterRoutes(mux *http.ServeMux, authMw func(http.Handle
 	// Chat routes are handled via delegation from LectureHandler.handleLecture
 }
 
+// handleChatRoutes serves the chat routes of a lecture, under /lectures/{id}/chats, and of a
+// course, under /courses/{id}/chats. Apart from creating and listing, they behave the same, and
+// only reach chats about the lecture or course in the path.
 func (h *ChatHandler) handleChatRoutes(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
-	if !strings.HasPrefix(path, "/lectures/") {
-		http.NotFound(w, r)
-		return
-	}
 
-	// Extract lecture ID and remaining path
-	pathParts := strings.Split(strings.TrimPrefix(path, "/lectures/"), "/")
-	if len(pathParts) < 2 {
+	// Extract the lecture or course ID and remaining path
+	var scope model.ChatScope
+	var remainingPath string
+	var ok bool
+	switch {
+	case strings.HasPrefix(path, "/lectures/"):
+		scope.LectureID, remainingPath, ok = strings.Cut(strings.TrimPrefix(path, "/lectures/"), "/")
+	case strings.HasPrefix(path, "/courses/"):
+		scope.CourseID, remainingPath, ok = strings.Cut(strings.TrimPrefix(path, "/courses/"), "/")
+	}
+	if !ok {
 		http.NotFound(w, r)
 		return
 	}
 
-	lectureID := pathParts[0]
-	remainingPath := strings.Join(pathParts[1:], "/")
-
 	switch {
+	case remainingPath == "chats" && r.Method == http.MethodPost && scope.CourseID != "":
+		h.createCourseChat(w, r, scope)
 	case remainingPath == "chats" && r.Method == http.MethodPost:
-		h.createChat(w, r, lectureID)
+		h.createChat(w, r, scope)
+	case remainingPath == "chats" && r.Method == http.MethodGet && scope.CourseID != "":
+		h.listCourseChats(w, r, scope)
 	case remainingPath == "chats" && r.Method == http.MethodGet:
-		h.listChats(w, r, lectureID)
+		h.listChats(w, r, scope)
 	case strings.HasPrefix(remainingPath, "chats/") && strings.HasSuffix(remainingPath, "/stream") && r.Method == http.MethodPost:
 		chatID := strings.TrimSuffix(strings.TrimPrefix(remainingPath, "chats/"), "/stream")
-		h.streamChat(w, r, lectureID, chatID)
+		h.streamChat(w, r, scope, chatID)
+	case strings.HasPrefix(remainingPath, "chats/") && strings.Contains(remainingPath, "/stream/") && r.Method == http.MethodGet:
+		// chats/{chatId}/stream/{streamId}
+		chatID, streamID, _ := strings.Cut(strings.TrimPrefix(remainingPath, "chats/"), "/stream/")
+		h.resumeStream(w, r, scope, chatID, streamID)
+	case strings.HasPrefix(remainingPath, "chats/") && strings.HasSuffix(remainingPath, "/stop") && r.Method == http.MethodPost:
+		chatID := strings.TrimSuffix(strings.TrimPrefix(remainingPath, "chats/"), "/stop")
+		h.stopReply(w, r, scope, chatID)
+	case strings.HasPrefix(remainingPath, "chats/") && strings.HasSuffix(remainingPath, "/regenerate") && r.Method == http.MethodPost:
+		chatID := strings.TrimSuffix(strings.TrimPrefix(remainingPath, "chats/"), "/regenerate")
+		h.regenerateReply(w, r, scope, chatID)
+	case strings.HasPrefix(remainingPath, "chats/") && strings.HasSuffix(remainingPath, "/active-branch") && r.Method == http.MethodPut:
+		chatID := strings.TrimSuffix(strings.TrimPrefix(remainingPath, "chats/"), "/active-branch")
+		h.switchBranch(w, r, scope, chatID)
+	case strings.HasPrefix(remainingPath, "chats/") && strings.HasSuffix(remainingPath, "/edit") && r.Method == http.MethodPost:
+		// chats/{chatId}/messages/{messageId}/edit
+		segments := strings.Split(strings.TrimSuffix(strings.TrimPrefix(remainingPath, "chats/"), "/edit"), "/")
+		if len(segments) != 3 || segments[1] != "messages" {
+			http.NotFound(w, r)
+			return
+		}
+		h.editMessage(w, r, scope, segments[0], segments[2])
+	case strings.HasPrefix(remainingPath, "chats/") && strings.HasSuffix(remainingPath, "/export") && r.Method == http.MethodGet:
+		chatID := strings.TrimSuffix(strings.TrimPrefix(remainingPath, "chats/"), "/export")
+		h.exportChat(w, r, scope, chatID)
 	case strings.HasPrefix(remainingPath, "chats/") && strings.HasSuffix(remainingPath, "/messages") && r.Method == http.MethodGet:
 		chatID := strings.TrimSuffix(strings.TrimPrefix(remainingPath, "chats/"), "/messages")
-		h.listMessages(w, r, lectureID, chatID)
+		h.listMessages(w, r, scope, chatID)
 	case strings.HasPrefix(remainingPath, "chats/") && r.Method == http.MethodPatch:
 		chatID := strings.TrimPrefix(remainingPath, "chats/")
-		h.updateChat(w, r, lectureID, chatID)
+		h.updateChat(w, r, scope, chatID)
 	case strings.HasPrefix(remainingPath, "chats/") && r.Method == http.MethodGet:
 		chatID := strings.TrimPrefix(remainingPath, "chats/")
-		h.getChat(w, r, lectureID, chatID)
+		h.getChat(w, r, scope, chatID)
 	case strings.HasPrefix(remainingPath, "chats/") && r.Method == http.MethodDelete:
 		chatID := strings.TrimPrefix(remainingPath, "chats/")
-		h.deleteChat(w, r, lectureID, chatID)
+		h.deleteChat(w, r, scope, chatID)
 	default:
 		http.NotFound(w, r)
 	}
@@ -96,25 +125,19 @@ func (h *ChatHandler) handleChatRoutes(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {string} string "Lecture not found"
 // @Failure 500 {string} string "Failed to create chat"
 // @Router /lectures/{lectureId}/chats [post]
-func (h *ChatHandler) createChat(w http.ResponseWriter, r *http.Request, lectureID string) {
+func (h *ChatHandler) createChat(w http.ResponseWriter, r *http.Request, scope model.ChatScope) {
 	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
 	if !ok || userID == "" {
 		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
 		return
 	}
 
-	var req dto.ChatCreateDTO
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+	title, ok := decodeChatTitle(w, r)
+	if !ok {
 		return
 	}
 
-	title := "New Chat"
-	if req.Title != nil && *req.Title != "" {
-		title = *req.Title
-	}
-
-	chat, err := h.chatService.CreateChat(r.Context(), lectureID, userID, title)
+	chat, err := h.chatService.CreateChat(r.Context(), scope.LectureID, userID, title)
 	if err != nil {
 		if err == service.ErrUnauthorized || err == service.ErrLectureNotFound {
 			http.Error(w, "Lecture not found", http.StatusNotFound)
@@ -124,20 +147,7 @@ func (h *ChatHandler) createChat(w http.ResponseWriter, r *http.Request, lecture
 		return
 	}
 
-	resp := dto.ChatResponseDTO{
-		ID:        chat.ID,
-		LectureID: chat.LectureID,
-		UserID:    chat.UserID,
-		Title:     chat.Title,
-		CreatedAt: chat.CreatedAt,
-		UpdatedAt: chat.UpdatedAt,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		h.logger.Error().Err(err).Msg("Failed to encode response")
-	}
+	h.writeCreatedChat(w, chat)
 }
 
 // listChats godoc
@@ -153,27 +163,15 @@ func (h *ChatHandler) createChat(w http.ResponseWriter, r *http.Request, lecture
 // @Failure 404 {string} string "Lecture not found"
 // @Failure 500 {string} string "Failed to list chats"
 // @Router /lectures/{lectureId}/chats [get]
-func (h *ChatHandler) listChats(w http.ResponseWriter, r *http.Request, lectureID string) {
+func (h *ChatHandler) listChats(w http.ResponseWriter, r *http.Request, scope model.ChatScope) {
 	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
 	if !ok || userID == "" {
 		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
 		return
 	}
 
-	limit := 50
-	offset := 0
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-		}
-	}
-	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
-		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
-			offset = parsedOffset
-		}
-	}
-
-	chats, err := h.chatService.ListChats(r.Context(), lectureID, userID, limit, offset)
+	limit, offset := parseChatPage(r)
+	chats, err := h.chatService.ListChats(r.Context(), scope.LectureID, userID, limit, offset)
 	if err != nil {
 		if err == service.ErrUnauthorized || err == service.ErrLectureNotFound {
 			http.Error(w, "Lecture not found", http.StatusNotFound)
@@ -183,22 +181,7 @@ func (h *ChatHandler) listChats(w http.ResponseWriter, r *http.Request, lectureI
 		return
 	}
 
-	resp := make([]dto.ChatResponseDTO, len(chats))
-	for i, chat := range chats {
-		resp[i] = dto.ChatResponseDTO{
-			ID:        chat.ID,
-			LectureID: chat.LectureID,
-			UserID:    chat.UserID,
-			Title:     chat.Title,
-			CreatedAt: chat.CreatedAt,
-			UpdatedAt: chat.UpdatedAt,
-		}
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		h.logger.Error().Err(err).Msg("Failed to encode response")
-	}
+	h.writeChats(w, chats)
 }
 
 // getChat godoc
@@ -213,17 +196,16 @@ func (h *ChatHandler) listChats(w http.ResponseWriter, r *http.Request, lectureI
 // @Failure 404 {string} string "Chat not found"
 // @Failure 500 {string} string "Failed to get chat"
 // @Router /lectures/{lectureId}/chats/{chatId} [get]
-func (h *ChatHandler) getChat(w http.ResponseWriter, r *http.Request, lectureID, chatID string) {
-	_ = lectureID
+func (h *ChatHandler) getChat(w http.ResponseWriter, r *http.Request, scope model.ChatScope, chatID string) {
 	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
 	if !ok || userID == "" {
 		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
 		return
 	}
 
-	chat, err := h.chatService.GetChat(r.Context(), chatID, userID)
+	chat, err := h.chatService.GetChat(r.Context(), scope, chatID, userID)
 	if err != nil {
-		if err == service.ErrChatNotFound {
+		if err == service.ErrChatNotFound || err == service.ErrUnauthorized {
 			http.Error(w, "Chat not found", http.StatusNotFound)
 			return
 		}
@@ -231,14 +213,7 @@ func (h *ChatHandler) getChat(w http.ResponseWriter, r *http.Request, lectureID,
 		return
 	}
 
-	resp := dto.ChatResponseDTO{
-		ID:        chat.ID,
-		LectureID: chat.LectureID,
-		UserID:    chat.UserID,
-		Title:     chat.Title,
-		CreatedAt: chat.CreatedAt,
-		UpdatedAt: chat.UpdatedAt,
-	}
+	resp := toChatResponseDTO(chat)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -257,15 +232,14 @@ func (h *ChatHandler) getChat(w http.ResponseWriter, r *http.Request, lectureID,
 // @Failure 404 {string} string "Chat not found"
 // @Failure 500 {string} string "Failed to delete chat"
 // @Router /lectures/{lectureId}/chats/{chatId} [delete]
-func (h *ChatHandler) deleteChat(w http.ResponseWriter, r *http.Request, lectureID, chatID string) {
-	_ = lectureID
+func (h *ChatHandler) deleteChat(w http.ResponseWriter, r *http.Request, scope model.ChatScope, chatID string) {
 	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
 	if !ok || userID == "" {
 		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
 		return
 	}
 
-	err := h.chatService.DeleteChat(r.Context(), chatID, userID)
+	err := h.chatService.DeleteChat(r.Context(), scope, chatID, userID)
 	if err != nil {
 		if err == service.ErrChatNotFound || err == service.ErrUnauthorized {
 			http.Error(w, "Chat not found", http.StatusNotFound)
@@ -278,6 +252,127 @@ func (h *ChatHandler) deleteChat(w http.ResponseWriter, r *http.Request, lecture
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// createCourseChat godoc
+// @Summary Create a course chat
+// @Description Creates a new chat conversation that answers from every lecture of a course. The chat title is optional and defaults to "New Chat". Messages, streaming and the other chat routes work as for lecture chats, under /courses/{courseId}/chats/{chatId}.
+// @Tags chats
+// @Accept json
+// @Produce json
+// @Param courseId path string true "Course ID"
+// @Param chat body dto.ChatCreateDTO false "Chat creation request"
+// @Success 201 {object} dto.ChatResponseDTO
+// @Failure 400 {string} string "Invalid JSON payload"
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "Course not found"
+// @Failure 500 {string} string "Failed to create chat"
+// @Router /courses/{courseId}/chats [post]
+func (h *ChatHandler) createCourseChat(w http.ResponseWriter, r *http.Request, scope model.ChatScope) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	title, ok := decodeChatTitle(w, r)
+	if !ok {
+		return
+	}
+
+	chat, err := h.chatService.CreateCourseChat(r.Context(), scope.CourseID, userID, title)
+	if err != nil {
+		if err == service.ErrUnauthorized || err == service.ErrCourseNotFound {
+			http.Error(w, "Course not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to create chat: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeCreatedChat(w, chat)
+}
+
+// listCourseChats godoc
+// @Summary List chats for a course
+// @Description Retrieves the course-level chats of a course with pagination support. Chats about a single lecture are listed under the lecture.
+// @Tags chats
+// @Produce json
+// @Param courseId path string true "Course ID"
+// @Param limit query int false "Maximum number of chats to return" default(50)
+// @Param offset query int false "Number of chats to skip" default(0)
+// @Success 200 {array} dto.ChatResponseDTO
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "Course not found"
+// @Failure 500 {string} string "Failed to list chats"
+// @Router /courses/{courseId}/chats [get]
+func (h *ChatHandler) listCourseChats(w http.ResponseWriter, r *http.Request, scope model.ChatScope) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	limit, offset := parseChatPage(r)
+	chats, err := h.chatService.ListCourseChats(r.Context(), scope.CourseID, userID, limit, offset)
+	if err != nil {
+		if err == service.ErrUnauthorized || err == service.ErrCourseNotFound {
+			http.Error(w, "Course not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to list chats: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeChats(w, chats)
+}
+
+// exportChat godoc
+// @Summary Export a chat
+// @Description Downloads the chat's active branch as Markdown (md), standalone HTML that prints cleanly to PDF from a browser (html), or JSON (json). The export includes the chat title, the lecture or course title, and each message's role, model and timestamp. Slide references are written as citations such as "[Slide 12]"; in course chats they also name the lecture. Reasoning and tool calls are left out of the Markdown and HTML text but kept in the JSON parts.
+// @Tags chats
+// @Produce text/markdown
+// @Produce text/html
+// @Produce json
+// @Param lectureId path string true "Lecture ID"
+// @Param chatId path string true "Chat ID"
+// @Param format query string false "Export format" Enums(md, html, json) default(md)
+// @Success 200 {object} model.ChatExport
+// @Failure 400 {string} string "Invalid export format"
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "Chat not found"
+// @Failure 500 {string} string "Failed to export chat"
+// @Router /lectures/{lectureId}/chats/{chatId}/export [get]
+func (h *ChatHandler) exportChat(w http.ResponseWriter, r *http.Request, scope model.ChatScope, chatID string) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = model.ChatExportMarkdown
+	}
+
+	file, err := h.chatService.ExportChat(r.Context(), scope, chatID, userID, format)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidExportFormat):
+			http.Error(w, "Invalid export format: "+err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrChatNotFound) || errors.Is(err, service.ErrUnauthorized):
+			http.Error(w, "Chat not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to export chat: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename}))
+	if _, err := w.Write(file.Body); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to write export")
+	}
+}
+
 // updateChat godoc
 // @Summary Update a chat
 // @Description Updates a chat's title.
@@ -293,8 +388,7 @@ func (h *ChatHandler) deleteChat(w http.ResponseWriter, r *http.Request, lecture
 // @Failure 404 {string} string "Chat not found"
 // @Failure 500 {string} string "Failed to update chat"
 // @Router /lectures/{lectureId}/chats/{chatId} [patch]
-func (h *ChatHandler) updateChat(w http.ResponseWriter, r *http.Request, lectureID, chatID string) {
-	_ = lectureID
+func (h *ChatHandler) updateChat(w http.ResponseWriter, r *http.Request, scope model.ChatScope, chatID string) {
 	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
 	if !ok || userID == "" {
 		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
@@ -317,7 +411,7 @@ func (h *ChatHandler) updateChat(w http.ResponseWriter, r *http.Request, lecture
 		return
 	}
 
-	chat, err := h.chatService.UpdateChat(r.Context(), chatID, userID, *req.Title)
+	chat, err := h.chatService.UpdateChat(r.Context(), scope, chatID, userID, *req.Title)
 	if err != nil {
 		if err == service.ErrChatNotFound || err == service.ErrUnauthorized {
 			http.Error(w, "Chat not found", http.StatusNotFound)
@@ -327,14 +421,7 @@ func (h *ChatHandler) updateChat(w http.ResponseWriter, r *http.Request, lecture
 		return
 	}
 
-	resp := dto.ChatResponseDTO{
-		ID:        chat.ID,
-		LectureID: chat.LectureID,
-		UserID:    chat.UserID,
-		Title:     chat.Title,
-		CreatedAt: chat.CreatedAt,
-		UpdatedAt: chat.UpdatedAt,
-	}
+	resp := toChatResponseDTO(chat)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -344,7 +431,7 @@ func (h *ChatHandler) updateChat(w http.ResponseWriter, r *http.Request, lecture
 
 // listMessages godoc
 // @Summary List messages in a chat
-// @Description Retrieves all messages for a specific chat. Messages are returned in chronological order (oldest first).
+// @Description Retrieves the messages on the active branch of a chat in chronological order (oldest first). Each message lists its siblings, the alternative versions created by edits and regenerations.
 // @Tags chats
 // @Produce json
 // @Param lectureId path string true "Lecture ID"
@@ -355,8 +442,7 @@ func (h *ChatHandler) updateChat(w http.ResponseWriter, r *http.Request, lecture
 // @Failure 404 {string} string "Chat not found"
 // @Failure 500 {string} string "Failed to list messages"
 // @Router /lectures/{lectureId}/chats/{chatId}/messages [get]
-func (h *ChatHandler) listMessages(w http.ResponseWriter, r *http.Request, lectureID, chatID string) {
-	_ = lectureID
+func (h *ChatHandler) listMessages(w http.ResponseWriter, r *http.Request, scope model.ChatScope, chatID string) {
 	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
 	if !ok || userID == "" {
 		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
@@ -370,7 +456,7 @@ func (h *ChatHandler) listMessages(w http.ResponseWriter, r *http.Request, lectu
 		}
 	}
 
-	messages, err := h.chatService.ListMessages(r.Context(), chatID, userID, limit)
+	messages, err := h.chatService.ListMessages(r.Context(), scope, chatID, userID, limit)
 	if err != nil {
 		if err == service.ErrChatNotFound || err == service.ErrUnauthorized {
 			http.Error(w, "Chat not found", http.StatusNotFound)
@@ -380,48 +466,7 @@ func (h *ChatHandler) listMessages(w http.ResponseWriter, r *http.Request, lectu
 		return
 	}
 
-	resp := make([]dto.MessageResponseDTO, len(messages))
-	for i, msg := range messages {
-		parts := make([]dto.MessagePartDTO, len(msg.Parts))
-		for j, part := range msg.Parts {
-			var ref *dto.ReferenceDTO
-			if part.Reference != nil {
-				ref = &dto.ReferenceDTO{
-					Type:     part.Reference.Type,
-					ID:       part.Reference.ID,
-					Metadata: part.Reference.Metadata,
-				}
-			}
-			var data *dto.ReferencePartDTO
-			if part.Data != nil {
-				data = &dto.ReferencePartDTO{
-					Type: part.Data.Type,
-					Text: part.Data.Text,
-				}
-				if part.Data.Reference != nil {
-					data.Reference = &dto.ReferenceDTO{
-						Type:     part.Data.Reference.Type,
-						ID:       part.Data.Reference.ID,
-						Metadata: part.Data.Reference.Metadata,
-					}
-				}
-			}
-			parts[j] = dto.MessagePartDTO{
-				Type:      part.Type,
-				Text:      part.Text,
-				Reference: ref,
-				Data:      data,
-			}
-		}
-		resp[i] = dto.MessageResponseDTO{
-			ID:        msg.ID,
-			ChatID:    msg.ChatID,
-			Role:      msg.Role,
-			Parts:     parts,
-			CreatedAt: msg.CreatedAt,
-		}
-	}
-
+	resp := toMessageResponseDTOs(messages)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		h.logger.Error().Err(err).Msg("Failed to encode response")
@@ -430,7 +475,7 @@ func (h *ChatHandler) listMessages(w http.ResponseWriter, r *http.Request, lectu
 
 // streamChat godoc
 // @Summary Stream chat response
-// @Description Sends a user message and streams the AI assistant's response using Server-Sent Events (SSE). The user message is saved immediately, and the assistant response is saved after streaming completes. The model parameter specifies which LLM model to use for the response.
+// @Description Sends a user message and streams the AI assistant's response using Server-Sent Events (SSE). The user message is appended to the active branch immediately, and the assistant response is saved after streaming ends with a status of complete, stopped, aborted or error in its metadata. Text, reasoning, slide references (data-reference), source citations (source-document, source-url) and tool calls are forwarded as the matching AI SDK stream parts and saved as structured message parts. If the model fails mid-stream, an error part is sent instead of finish. A reply still being generated for the chat is stopped and saved before the new one starts. The model parameter specifies which LLM model to use for the response.
 // @Tags chats
 // @Accept json
 // @Produce text/event-stream
@@ -443,7 +488,7 @@ func (h *ChatHandler) listMessages(w http.ResponseWriter, r *http.Request, lectu
 // @Failure 404 {string} string "Chat or lecture not found"
 // @Failure 500 {string} string "Failed to stream chat response"
 // @Router /lectures/{lectureId}/chats/{chatId}/stream [post]
-func (h *ChatHandler) streamChat(w http.ResponseWriter, r *http.Request, lectureID, chatID string) {
+func (h *ChatHandler) streamChat(w http.ResponseWriter, r *http.Request, scope model.ChatScope, chatID string) {
 	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
 	if !ok || userID == "" {
 		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
@@ -461,44 +506,11 @@ func (h *ChatHandler) streamChat(w http.ResponseWriter, r *http.Request, lecture
 		return
 	}
 
-	// Convert DTO to model
-	messageParts := make(model.MessageParts, len(req.Parts))
-	for i, part := range req.Parts {
-		var ref *model.Reference
-		if part.Reference != nil {
-			ref = &model.Reference{
-				Type:     part.Reference.Type,
-				ID:       part.Reference.ID,
-				Metadata: part.Reference.Metadata,
-			}
-		}
-		var data *model.ReferencePart
-		if part.Data != nil {
-			data = &model.ReferencePart{
-				Type: part.Data.Type,
-				Text: part.Data.Text,
-			}
-			if part.Data.Reference != nil {
-				data.Reference = &model.Reference{
-					Type:     part.Data.Reference.Type,
-					ID:       part.Data.Reference.ID,
-					Metadata: part.Data.Reference.Metadata,
-				}
-			}
-		}
-		messageParts[i] = model.MessagePart{
-			Type:      part.Type,
-			Text:      part.Text,
-			Reference: ref,
-			Data:      data,
-		}
-	}
-
 	// Save user message first
 	userMetadata := map[string]interface{}{
 		"model": req.Model,
 	}
-	userMessage, err := h.chatService.CreateMessage(r.Context(), chatID, userID, "user", messageParts, userMetadata)
+	userMessage, err := h.chatService.CreateMessage(r.Context(), scope, chatID, userID, "user", toMessageParts(req.Parts), userMetadata)
 	if err != nil {
 		if err == service.ErrChatNotFound || err == service.ErrUnauthorized {
 			http.Error(w, "Chat not found", http.StatusNotFound)
@@ -507,10 +519,16 @@ func (h *ChatHandler) streamChat(w http.ResponseWriter, r *http.Request, lecture
 		http.Error(w, "Failed to create message: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	_ = userMessage // User message saved
 
+	h.streamReply(w, r, scope, chatID, userID, userMessage, req.Model)
+}
+
+// streamReply starts the assistant's reply to userMessage and relays it to the client. The reply
+// keeps generating and is saved even if the client disconnects; it can be resumed through
+// resumeStream with the ID sent in the X-Chat-Stream-ID header and the start part.
+func (h *ChatHandler) streamReply(w http.ResponseWriter, r *http.Request, scope model.ChatScope, chatID, userID string, userMessage *model.Message, modelName string) {
 	// Stream response from Python service
-	stream, err := h.chatService.StreamChatResponse(r.Context(), lectureID, chatID, userID, messageParts, req.Model)
+	stream, err := h.chatService.StartReply(r.Context(), scope, chatID, userID, userMessage, modelName)
 	if err != nil {
 		if err == service.ErrChatNotFound || err == service.ErrUnauthorized || err == service.ErrLectureNotFound {
 			http.Error(w, "Chat or lecture not found", http.StatusNotFound)
@@ -519,179 +537,446 @@ func (h *ChatHandler) streamChat(w http.ResponseWriter, r *http.Request, lecture
 		http.Error(w, "Failed to stream chat response: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer func() {
-		if err := stream.Close(); err != nil {
-			h.logger.Error().Err(err).Msg("Failed to close stream")
-		}
-	}()
 
-	// Set SSE headers according to AI SDK Data Stream Protocol
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("X-Accel-Buffering", "no")             // Disable nginx buffering
-	w.Header().Set("x-vercel-ai-ui-message-stream", "v1") // Required for AI SDK Data Stream Protocol
+	w.Header().Set("X-Chat-Stream-ID", stream.ID)
+	h.relayStream(w, r, stream, 0)
+}
 
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+// resumeStream godoc
+// @Summary Resume a chat stream
+// @Description Reconnects to an assistant reply that is still streaming or finished within the last few minutes. Events after the one named by the Last-Event-ID header are replayed, then the stream continues live. The reply is saved when it ends whether or not a client is connected.
+// @Tags chats
+// @Produce text/event-stream
+// @Param lectureId path string true "Lecture ID"
+// @Param chatId path string true "Chat ID"
+// @Param streamId path string true "Stream ID from the X-Chat-Stream-ID header or the start part"
+// @Param Last-Event-ID header int false "ID of the last event received"
+// @Success 200 {string} string "Server-Sent Events stream"
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "Stream not found or expired"
+// @Router /lectures/{lectureId}/chats/{chatId}/stream/{streamId} [get]
+func (h *ChatHandler) resumeStream(w http.ResponseWriter, r *http.Request, scope model.ChatScope, chatID, streamID string) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
 		return
 	}
 
-	// Read from Python service stream and convert to AI SDK Data Stream Protocol
-	reader := bufio.NewReader(stream)
-	var fullContent strings.Builder
-	// Generate a unique text part ID for this stream
-	textPartID := fmt.Sprintf("part_%s_%d", chatID, time.Now().UnixNano())
+	stream, err := h.chatService.GetChatStream(scope, streamID, chatID, userID)
+	if err != nil {
+		http.Error(w, "Stream not found or expired", http.StatusNotFound)
+		return
+	}
 
-	// Send text-start part (SDK will create the message internally)
-	textStartPart := map[string]interface{}{
-		"type": "text-start",
-		"id":   textPartID,
+	lastEventID := 0
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
+			lastEventID = parsed
+		}
 	}
-	textStartJSON, _ := json.Marshal(textStartPart)
-	if _, err := fmt.Fprintf(w, "data: %s\n\n", textStartJSON); err != nil {
-		h.logger.Error().Err(err).Msg("Failed to write text-start part")
+
+	h.relayStream(w, r, stream, lastEventID)
+}
+
+// stopReply godoc
+// @Summary Stop generating a chat reply
+// @Description Cancels the assistant reply being generated for a chat, from any device or tab. The partial reply is saved with a stopped status, and the open stream ends with a normal finish part. Responds once the partial reply has been saved.
+// @Tags chats
+// @Param lectureId path string true "Lecture ID"
+// @Param chatId path string true "Chat ID"
+// @Success 204 {string} string "No Content"
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "No reply is being generated for this chat"
+// @Failure 500 {string} string "Failed to stop reply"
+// @Router /lectures/{lectureId}/chats/{chatId}/stop [post]
+func (h *ChatHandler) stopReply(w http.ResponseWriter, r *http.Request, scope model.ChatScope, chatID string) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.chatService.StopReply(r.Context(), scope, chatID, userID); err != nil {
+		if errors.Is(err, service.ErrNoActiveChatStream) {
+			http.Error(w, "No reply is being generated for this chat", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			// The caller went away before the reply was saved; the stop itself has taken effect
+			h.logger.Debug().Err(err).Str("chat_id", chatID).Msg("Stopped reply before it was saved")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h.logger.Error().Err(err).Str("chat_id", chatID).Msg("Failed to stop reply")
+		http.Error(w, "Failed to stop reply: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// relayStream writes the stream's events after lastEventID to the client as they arrive, until the
+// stream ends or the client disconnects.
+func (h *ChatHandler) relayStream(w http.ResponseWriter, r *http.Request, stream *service.ChatStream, lastEventID int) {
+	// Set SSE headers according to AI SDK Data Stream Protocol
+	sse, err := newSSEWriter(w)
+	if err != nil {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
 		return
 	}
-	flusher.Flush()
+	w.Header().Set("x-vercel-ai-ui-message-stream", "v1") // Required for AI SDK Data Stream Protocol
 
 	for {
-		chunk, err := service.ParseSSEChunk(reader)
-		if err != nil {
-			if err == io.EOF {
-				break
+		events, done, changed := stream.Next(lastEventID)
+		for _, event := range events {
+			if err := sse.WriteEvent("", strconv.Itoa(event.ID), event.Data); err != nil {
+				h.logger.Debug().Err(err).Str("stream_id", stream.ID).Msg("Failed to write stream part: client disconnected")
+				return
 			}
-			// If the context was canceled, it's likely a user-initiated stop, so we log it as Info/Debug
-			if errors.Is(err, context.Canceled) || errors.Is(r.Context().Err(), context.Canceled) {
-				h.logger.Debug().Err(err).Msg("Stream reading stopped by user (context canceled)")
-			} else {
-				h.logger.Error().Err(err).Msg("Error reading from Python service stream")
+			lastEventID = event.ID
+		}
+		if done {
+			// Send [DONE] marker to terminate stream
+			if err := sse.WriteRaw("[DONE]"); err != nil {
+				h.logger.Debug().Err(err).Msg("Failed to write [DONE] marker: client disconnected")
 			}
-			break
+			return
 		}
 
-		content, ok := chunk["content"].(string)
-		if !ok {
-			h.logger.Warn().Interface("chunk", chunk).Msg("Chunk missing or invalid content field, skipping")
-			continue
+		select {
+		case <-r.Context().Done():
+			h.logger.Debug().Str("stream_id", stream.ID).Msg("Client disconnected from chat stream; reply continues in the background")
+			return
+		case <-changed:
 		}
+	}
+}
 
-		done, _ := chunk["done"].(bool)
+// regenerateReply godoc
+// @Summary Regenerate an assistant reply
+// @Description Streams a new reply to the user message that an assistant reply answered, optionally with a different model. The new reply becomes the active branch; the previous reply is kept as a sibling that can be switched back to. When message_id is omitted, the last reply on the active branch is regenerated.
+// @Tags chats
+// @Accept json
+// @Produce text/event-stream
+// @Param lectureId path string true "Lecture ID"
+// @Param chatId path string true "Chat ID"
+// @Param request body dto.ChatRegenerateRequestDTO true "Reply to regenerate and model"
+// @Success 200 {string} string "Server-Sent Events stream"
+// @Failure 400 {string} string "Invalid JSON payload or validation failed"
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "Chat or message not found"
+// @Failure 409 {string} string "No assistant reply to regenerate"
+// @Failure 500 {string} string "Failed to regenerate reply"
+// @Router /lectures/{lectureId}/chats/{chatId}/regenerate [post]
+func (h *ChatHandler) regenerateReply(w http.ResponseWriter, r *http.Request, scope model.ChatScope, chatID string) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
 
-		// Send text-delta parts according to AI SDK protocol
-		if content != "" {
-			deltaPart := map[string]interface{}{
-				"type":  "text-delta",
-				"id":    textPartID,
-				"delta": content,
-			}
-			deltaJSON, err := json.Marshal(deltaPart)
-			if err != nil {
-				h.logger.Error().Err(err).Interface("deltaPart", deltaPart).Msg("Failed to marshal delta part")
-				continue
-			}
-			if _, err := fmt.Fprintf(w, "data: %s\n\n", deltaJSON); err != nil {
-				if errors.Is(r.Context().Err(), context.Canceled) {
-					h.logger.Debug().Err(err).Msg("Failed to write delta part: client disconnected")
-				} else {
-					h.logger.Error().Err(err).Msg("Failed to write delta part")
-				}
-				break
-			}
-			flusher.Flush()
-		}
+	var req dto.ChatRegenerateRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		// Accumulate content for saving
-		fullContent.WriteString(content)
+	if err := h.validate.Struct(&req); err != nil {
+		http.Error(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		if done {
-			break
+	userMessage, err := h.chatService.GetRegenerationTarget(r.Context(), scope, chatID, userID, req.MessageID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrChatNotFound), errors.Is(err, service.ErrUnauthorized), errors.Is(err, service.ErrMessageNotFound):
+			http.Error(w, "Chat or message not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrNothingToRegenerate):
+			http.Error(w, "Conflict: "+err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to regenerate reply: "+err.Error(), http.StatusInternalServerError)
 		}
+		return
+	}
+
+	h.streamReply(w, r, scope, chatID, userID, userMessage, req.Model)
+}
+
+// editMessage godoc
+// @Summary Edit a user message and continue from it
+// @Description Saves the edited message as a new branch alongside the original and streams the assistant's reply to it. The original message and everything after it are kept and can be switched back to.
+// @Tags chats
+// @Accept json
+// @Produce text/event-stream
+// @Param lectureId path string true "Lecture ID"
+// @Param chatId path string true "Chat ID"
+// @Param messageId path string true "ID of the user message to edit"
+// @Param request body dto.ChatStreamRequestDTO true "Edited message parts and model"
+// @Success 200 {string} string "Server-Sent Events stream"
+// @Failure 400 {string} string "Invalid JSON payload, validation failed, or message is not a user message"
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "Chat or message not found"
+// @Failure 500 {string} string "Failed to edit message"
+// @Router /lectures/{lectureId}/chats/{chatId}/messages/{messageId}/edit [post]
+func (h *ChatHandler) editMessage(w http.ResponseWriter, r *http.Request, scope model.ChatScope, chatID, messageID string) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.ChatStreamRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Send text-end part according to AI SDK protocol
-	textEndPart := map[string]interface{}{
-		"type": "text-end",
-		"id":   textPartID,
+	if err := h.validate.Struct(&req); err != nil {
+		http.Error(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
 	}
-	textEndJSON, _ := json.Marshal(textEndPart)
-	if _, err := fmt.Fprintf(w, "data: %s\n\n", textEndJSON); err != nil {
-		if errors.Is(r.Context().Err(), context.Canceled) {
-			h.logger.Debug().Err(err).Msg("Failed to write text-end part: client disconnected")
-		} else {
-			h.logger.Error().Err(err).Msg("Failed to write text-end part")
+
+	userMetadata := map[string]interface{}{
+		"model":       req.Model,
+		"edited_from": messageID,
+	}
+	userMessage, err := h.chatService.EditMessage(r.Context(), scope, chatID, userID, messageID, toMessageParts(req.Parts), userMetadata)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrChatNotFound), errors.Is(err, service.ErrUnauthorized), errors.Is(err, service.ErrMessageNotFound):
+			http.Error(w, "Chat or message not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrMessageNotEditable):
+			http.Error(w, "Invalid message: "+err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to edit message: "+err.Error(), http.StatusInternalServerError)
 		}
-	} else {
-		flusher.Flush()
+		return
 	}
 
-	// Send finish part
-	finishPart := map[string]interface{}{
-		"type": "finish",
+	h.streamReply(w, r, scope, chatID, userID, userMessage, req.Model)
+}
+
+// switchBranch godoc
+// @Summary Switch the active branch of a chat
+// @Description Makes the branch containing the given message active, following it down to its most recent message, and returns the messages of the new active branch in chronological order.
+// @Tags chats
+// @Accept json
+// @Produce json
+// @Param lectureId path string true "Lecture ID"
+// @Param chatId path string true "Chat ID"
+// @Param request body dto.ChatActiveBranchRequestDTO true "Message on the branch to switch to"
+// @Success 200 {array} dto.MessageResponseDTO
+// @Failure 400 {string} string "Invalid JSON payload or validation failed"
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "Chat or message not found"
+// @Failure 500 {string} string "Failed to switch branch"
+// @Router /lectures/{lectureId}/chats/{chatId}/active-branch [put]
+func (h *ChatHandler) switchBranch(w http.ResponseWriter, r *http.Request, scope model.ChatScope, chatID string) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
 	}
-	finishJSON, _ := json.Marshal(finishPart)
-	if _, err := fmt.Fprintf(w, "data: %s\n\n", finishJSON); err != nil {
-		if errors.Is(r.Context().Err(), context.Canceled) {
-			h.logger.Debug().Err(err).Msg("Failed to write finish part: client disconnected")
-		} else {
-			h.logger.Error().Err(err).Msg("Failed to write finish part")
+
+	var req dto.ChatActiveBranchRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		http.Error(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.chatService.SwitchBranch(r.Context(), scope, chatID, userID, req.MessageID); err != nil {
+		if errors.Is(err, service.ErrChatNotFound) || errors.Is(err, service.ErrUnauthorized) || errors.Is(err, service.ErrMessageNotFound) {
+			http.Error(w, "Chat or message not found", http.StatusNotFound)
+			return
 		}
-	} else {
-		flusher.Flush()
+		http.Error(w, "Failed to switch branch: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	messages, err := h.chatService.ListMessages(r.Context(), scope, chatID, userID, 100)
+	if err != nil {
+		http.Error(w, "Failed to list messages: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(toMessageResponseDTOs(messages)); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// toChatResponseDTO converts a chat to its response; exactly one of LectureID and CourseID is set.
+func toChatResponseDTO(chat *model.Chat) dto.ChatResponseDTO {
+	return dto.ChatResponseDTO{
+		ID:        chat.ID,
+		LectureID: chat.LectureID,
+		CourseID:  chat.CourseID,
+		UserID:    chat.UserID,
+		Title:     chat.Title,
+		CreatedAt: chat.CreatedAt,
+		UpdatedAt: chat.UpdatedAt,
 	}
+}
 
-	// Send [DONE] marker to terminate stream
-	if _, err := fmt.Fprintf(w, "data: [DONE]\n\n"); err != nil {
-		if errors.Is(r.Context().Err(), context.Canceled) {
-			h.logger.Debug().Err(err).Msg("Failed to write [DONE] marker: client disconnected")
-		} else {
-			h.logger.Error().Err(err).Msg("Failed to write [DONE] marker")
+// decodeChatTitle reads the title of a chat to create, defaulting to "New Chat". It writes the
+// error response and reports false when the body is invalid.
+func decodeChatTitle(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req dto.ChatCreateDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	if req.Title != nil && *req.Title != "" {
+		return *req.Title, true
+	}
+	return "New Chat", true
+}
+
+// parseChatPage reads the limit and offset of a chat listing, ignoring invalid values.
+func parseChatPage(r *http.Request) (limit, offset int) {
+	limit = 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
 		}
-	} else {
-		flusher.Flush()
 	}
+	return limit, offset
+}
+
+func (h *ChatHandler) writeCreatedChat(w http.ResponseWriter, chat *model.Chat) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(toChatResponseDTO(chat)); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *ChatHandler) writeChats(w http.ResponseWriter, chats []model.Chat) {
+	resp := make([]dto.ChatResponseDTO, len(chats))
+	for i := range chats {
+		resp[i] = toChatResponseDTO(&chats[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
 
-	// Save assistant message
-	if fullContent.Len() > 0 {
-		contentStr := fullContent.String()
-		assistantParts := model.MessageParts{
-			{Type: "text", Text: contentStr},
+// toMessageParts converts request message parts to the model.
+func toMessageParts(parts []dto.MessagePartDTO) model.MessageParts {
+	messageParts := make(model.MessageParts, len(parts))
+	for i, part := range parts {
+		var ref *model.Reference
+		if part.Reference != nil {
+			ref = &model.Reference{
+				Type:     part.Reference.Type,
+				ID:       part.Reference.ID,
+				Metadata: part.Reference.Metadata,
+			}
 		}
-		assistantMetadata := map[string]interface{}{
-			"model": req.Model,
+		var data *model.ReferencePart
+		if part.Data != nil {
+			data = &model.ReferencePart{
+				Type: part.Data.Type,
+				Text: part.Data.Text,
+			}
+			if part.Data.Reference != nil {
+				data.Reference = &model.Reference{
+					Type:     part.Data.Reference.Type,
+					ID:       part.Data.Reference.ID,
+					Metadata: part.Data.Reference.Metadata,
+				}
+			}
 		}
+		messageParts[i] = model.MessagePart{
+			Type:       part.Type,
+			Text:       part.Text,
+			Reference:  ref,
+			Data:       data,
+			SourceID:   part.SourceID,
+			URL:        part.URL,
+			Title:      part.Title,
+			MediaType:  part.MediaType,
+			Filename:   part.Filename,
+			ToolCallID: part.ToolCallID,
+			ToolName:   part.ToolName,
+			State:      part.State,
+			Input:      part.Input,
+			Output:     part.Output,
+		}
+	}
+	return messageParts
+}
 
-		// Use background context for saving the message, as the request context might be canceled
-		// if the user stopped the response halfway. We still want to save the partial response.
-		saveCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		_, err := h.chatService.CreateMessage(saveCtx, chatID, userID, "assistant", assistantParts, assistantMetadata)
-		if err != nil {
-			h.logger.Error().Err(err).Str("chat_id", chatID).Msg("Failed to save assistant message")
-		} else {
-			// Check if this is the first conversation (user + assistant = 2 messages) and trigger title generation
-			messageCount, err := h.chatService.GetMessageCount(saveCtx, chatID, userID)
-			if err == nil && messageCount == 2 {
-				// This is the first conversation - generate title from both messages
-				// Title generation happens asynchronously, frontend will poll for updates
-				// Use background context with timeout instead of request context (which gets canceled)
-				titleCtx, titleCancel := context.WithTimeout(context.Background(), 30*time.Second)
-				go func() {
-					defer titleCancel()
-					h.chatService.GenerateAndUpdateTitle(
-						titleCtx,
-						lectureID,
-						chatID,
-						userID,
-						messageParts,   // User message
-						assistantParts, // Assistant response
-					)
-				}()
+func toMessageResponseDTOs(messages []model.Message) []dto.MessageResponseDTO {
+	resp := make([]dto.MessageResponseDTO, len(messages))
+	for i, msg := range messages {
+		parts := make([]dto.MessagePartDTO, len(msg.Parts))
+		for j, part := range msg.Parts {
+			var ref *dto.ReferenceDTO
+			if part.Reference != nil {
+				ref = &dto.ReferenceDTO{
+					Type:     part.Reference.Type,
+					ID:       part.Reference.ID,
+					Metadata: part.Reference.Metadata,
+				}
 			}
+			var data *dto.ReferencePartDTO
+			if part.Data != nil {
+				data = &dto.ReferencePartDTO{
+					Type: part.Data.Type,
+					Text: part.Data.Text,
+				}
+				if part.Data.Reference != nil {
+					data.Reference = &dto.ReferenceDTO{
+						Type:     part.Data.Reference.Type,
+						ID:       part.Data.Reference.ID,
+						Metadata: part.Data.Reference.Metadata,
+					}
+				}
+			}
+			parts[j] = dto.MessagePartDTO{
+				Type:       part.Type,
+				Text:       part.Text,
+				Reference:  ref,
+				Data:       data,
+				SourceID:   part.SourceID,
+				URL:        part.URL,
+				Title:      part.Title,
+				MediaType:  part.MediaType,
+				Filename:   part.Filename,
+				ToolCallID: part.ToolCallID,
+				ToolName:   part.ToolName,
+				State:      part.State,
+				Input:      part.Input,
+				Output:     part.Output,
+			}
+		}
+		siblingIDs := msg.SiblingIDs
+		if len(siblingIDs) == 0 {
+			siblingIDs = []string{msg.ID}
+		}
+		resp[i] = dto.MessageResponseDTO{
+			ID:         msg.ID,
+			ChatID:     msg.ChatID,
+			ParentID:   msg.ParentID,
+			Role:       msg.Role,
+			Parts:      parts,
+			Metadata:   msg.Metadata,
+			SiblingIDs: siblingIDs,
+			CreatedAt:  msg.CreatedAt,
 		}
-	} else {
-		h.logger.Warn().Str("chat_id", chatID).Msg("No content to save for assistant message")
 	}
+	return resp
 }
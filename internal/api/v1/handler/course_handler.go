@@ -17,14 +17,16 @@ import (
 // CourseHandler handles course-related endpoints
 type CourseHandler struct {
 	courseService service.CourseService
+	chatHandler   *ChatHandler
 	validate      *validator.Validate
 	logger        zerolog.Logger
 }
 
 // NewCourseHandler creates a new CourseHandler
-func NewCourseHandler(courseService service.CourseService, validate *validator.Validate, logger zerolog.Logger) *CourseHandler {
+func NewCourseHandler(courseService service.CourseService, chatHandler *ChatHandler, validate *validator.Validate, logger zerolog.Logger) *CourseHandler {
 	return &CourseHandler{
 		courseService: courseService,
+		chatHandler:   chatHandler,
 		validate:      validate,
 		logger:        logger,
 	}
@@ -109,6 +111,13 @@ func (h *CourseHandler) handleCourse(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	// Delegate course chat routes to ChatHandler
+	if strings.Contains(path, "/chats") {
+		if h.chatHandler != nil {
+			h.chatHandler.handleChatRoutes(w, r)
+			return
+		}
+	}
 	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
 	if !ok || userID == "" {
 		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
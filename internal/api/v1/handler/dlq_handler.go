@@ -2,22 +2,33 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"app/internal/api/v1/dto"
+	"app/internal/middleware"
+	"app/internal/model"
+	"app/internal/repository"
 	"app/internal/service"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/rs/zerolog"
 )
 
-// DLQHandler handles dead-letter queue push events
+// dlqMaxLimit caps the page size of DLQ message listings.
+const dlqMaxLimit = 100
+
+// DLQHandler handles dead-letter queue push events and the admin endpoints for resolving them
 type DLQHandler struct {
-	service service.DLQService
-	logger  zerolog.Logger
+	service  service.DLQService
+	validate *validator.Validate
+	logger   zerolog.Logger
 }
 
-func NewDLQHandler(s service.DLQService, l zerolog.Logger) *DLQHandler {
-	return &DLQHandler{service: s, logger: l}
+func NewDLQHandler(s service.DLQService, v *validator.Validate, l zerolog.Logger) *DLQHandler {
+	return &DLQHandler{service: s, validate: v, logger: l}
 }
 
 // RegisterRoutes mounts the DLQ handler.
@@ -26,6 +37,15 @@ func (h *DLQHandler) RegisterRoutes(mux *http.ServeMux, authMw func(http.Handler
 	mux.Handle("POST /dlq", authMw(http.HandlerFunc(h.HandleDLQ)))
 }
 
+// RegisterAdminRoutes mounts the operator endpoints for browsing and resolving DLQ messages.
+// adminMw must authenticate the caller and restrict access to admins.
+func (h *DLQHandler) RegisterAdminRoutes(mux *http.ServeMux, adminMw func(http.Handler) http.Handler) {
+	mux.Handle("GET /admin/dlq", adminMw(http.HandlerFunc(h.listMessages)))
+	mux.Handle("GET /admin/dlq/{id}", adminMw(http.HandlerFunc(h.getMessage)))
+	mux.Handle("PATCH /admin/dlq/{id}", adminMw(http.HandlerFunc(h.updateMessageStatus)))
+	mux.Handle("POST /admin/dlq/{id}/replay", adminMw(http.HandlerFunc(h.replayMessage)))
+}
+
 // HandleDLQ godoc
 // @Summary Process dead-letter queue message
 // @Description Receives a Pub/Sub push from a dead-letter topic and persists the message to the database for manual inspection.
@@ -71,3 +91,212 @@ func (h *DLQHandler) HandleDLQ(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// listMessages godoc
+// @Summary List dead-letter messages
+// @Description Lists persisted dead-letter messages, newest first, optionally filtered by subscription, status and creation date range. Admin only.
+// @Tags admin
+// @Produce json
+// @Param subscription query string false "Subscription name"
+// @Param status query string false "Message status" Enums(unprocessed, processed, ignored)
+// @Param from query string false "Only messages created at or after this RFC3339 timestamp"
+// @Param to query string false "Only messages created before this RFC3339 timestamp"
+// @Param limit query int false "Maximum number of messages to return, at most 100" default(50)
+// @Param offset query int false "Number of messages to skip" default(0)
+// @Success 200 {array} dto.DLQMessageResponseDTO
+// @Failure 400 {string} string "Invalid filter"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Failed to list DLQ messages"
+// @Router /admin/dlq [get]
+func (h *DLQHandler) listMessages(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := repository.DLQListFilter{
+		SubscriptionName: q.Get("subscription"),
+		Status:           q.Get("status"),
+	}
+	if from := q.Get("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			http.Error(w, "Invalid from timestamp: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.CreatedFrom = &t
+	}
+	if to := q.Get("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			http.Error(w, "Invalid to timestamp: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.CreatedTo = &t
+	}
+
+	limit := 50
+	if l := q.Get("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = min(v, dlqMaxLimit)
+		}
+	}
+	offset := 0
+	if o := q.Get("offset"); o != "" {
+		if v, err := strconv.Atoi(o); err == nil && v >= 0 {
+			offset = v
+		}
+	}
+
+	messages, err := h.service.ListMessages(r.Context(), filter, limit, offset)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidDLQStatus) {
+			http.Error(w, "Invalid status filter", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to list DLQ messages: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]dto.DLQMessageResponseDTO, len(messages))
+	for i := range messages {
+		resp[i] = h.toDLQMessageResponse(&messages[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// getMessage godoc
+// @Summary Get a dead-letter message
+// @Description Retrieves a single dead-letter message with its decoded payload and attributes. Admin only.
+// @Tags admin
+// @Produce json
+// @Param id path string true "DLQ message ID"
+// @Success 200 {object} dto.DLQMessageResponseDTO
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "DLQ message not found"
+// @Failure 500 {string} string "Failed to get DLQ message"
+// @Router /admin/dlq/{id} [get]
+func (h *DLQHandler) getMessage(w http.ResponseWriter, r *http.Request) {
+	message, err := h.service.GetMessage(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, service.ErrDLQMessageNotFound) {
+			http.Error(w, "DLQ message not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get DLQ message: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.toDLQMessageResponse(message)); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// updateMessageStatus godoc
+// @Summary Resolve a dead-letter message
+// @Description Marks a dead-letter message as processed or ignored, or reopens it as unprocessed. Admin only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "DLQ message ID"
+// @Param request body dto.DLQStatusUpdateDTO true "New status"
+// @Success 200 {object} dto.DLQMessageResponseDTO
+// @Failure 400 {string} string "Invalid JSON payload or validation failed"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "DLQ message not found"
+// @Failure 500 {string} string "Failed to update DLQ message"
+// @Router /admin/dlq/{id} [patch]
+func (h *DLQHandler) updateMessageStatus(w http.ResponseWriter, r *http.Request) {
+	var req dto.DLQStatusUpdateDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		http.Error(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.service.UpdateStatus(r.Context(), r.PathValue("id"), req.Status)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrDLQMessageNotFound):
+			http.Error(w, "DLQ message not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrInvalidDLQStatus):
+			http.Error(w, "Invalid status", http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to update DLQ message: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.toDLQMessageResponse(message)); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// replayMessage godoc
+// @Summary Replay a dead-letter message
+// @Description Republishes the message payload to the topic its DLQ subscription belongs to, marks it processed and records who replayed it. A replayed ingestion job moves its lecture back to pending_processing and is queued in the outbox, so the response carries its outbox_message_id instead of a published_message_id. Admin only.
+// @Tags admin
+// @Produce json
+// @Param id path string true "DLQ message ID"
+// @Success 200 {object} dto.DLQReplayResponseDTO
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "DLQ message not found"
+// @Failure 500 {string} string "Failed to replay DLQ message"
+// @Router /admin/dlq/{id}/replay [post]
+func (h *DLQHandler) replayMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	message, result, err := h.service.Replay(r.Context(), r.PathValue("id"), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrDLQMessageNotFound) {
+			http.Error(w, "DLQ message not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to replay DLQ message: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.DLQReplayResponseDTO{
+		Message:            h.toDLQMessageResponse(message),
+		PublishedMessageID: result.PublishedMessageID,
+		OutboxMessageID:    result.OutboxMessageID,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *DLQHandler) toDLQMessageResponse(m *model.DeadLetterMessage) dto.DLQMessageResponseDTO {
+	resp := dto.DLQMessageResponseDTO{
+		ID:               m.ID,
+		SubscriptionName: m.SubscriptionName,
+		MessageID:        m.MessageID,
+		Payload:          json.RawMessage(m.Payload),
+		Status:           m.Status,
+		ReplayCount:      m.ReplayCount,
+		LastReplayedAt:   m.LastReplayedAt,
+		LastReplayedBy:   m.LastReplayedBy,
+		CreatedAt:        m.CreatedAt,
+		UpdatedAt:        m.UpdatedAt,
+	}
+	if len(m.Attributes) > 0 {
+		if err := json.Unmarshal(m.Attributes, &resp.Attributes); err != nil {
+			h.logger.Warn().Err(err).Str("dlq_id", m.ID).Msg("Failed to decode DLQ message attributes")
+		}
+	}
+	return resp
+}
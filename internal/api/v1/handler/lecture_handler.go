@@ -2,18 +2,25 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"app/internal/api/v1/dto"
 	"app/internal/middleware"
+	"app/internal/model"
 	"app/internal/service"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/rs/zerolog"
 )
 
+// lectureEventsHeartbeat is how often an idle lecture event stream is pinged to keep proxies from closing it.
+const lectureEventsHeartbeat = 15 * time.Second
+
 // LectureHandler handles flat lecture endpoints
 
 type LectureHandler struct {
@@ -21,6 +28,7 @@ type LectureHandler struct {
 	courseService  service.CourseService
 	noteService    service.NoteService
 	chatHandler    *ChatHandler
+	lectureEvents  service.LectureEventSubscriber
 	validate       *validator.Validate
 	s3BaseURL      string
 	s3Bucket       string
@@ -33,6 +41,7 @@ func NewLectureHandler(
 	courseService service.CourseService,
 	noteService service.NoteService,
 	chatHandler *ChatHandler,
+	lectureEvents service.LectureEventSubscriber,
 	validate *validator.Validate,
 	s3BaseURL string,
 	s3Bucket string,
@@ -43,6 +52,7 @@ func NewLectureHandler(
 		courseService:  courseService,
 		noteService:    noteService,
 		chatHandler:    chatHandler,
+		lectureEvents:  lectureEvents,
 		validate:       validate,
 		s3BaseURL:      s3BaseURL,
 		s3Bucket:       s3Bucket,
@@ -69,12 +79,28 @@ func (h *LectureHandler) handleLecture(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if strings.Contains(path, "/multipart-upload") {
+		h.handleMultipartUpload(w, r)
+		return
+	}
 	switch r.Method {
 	case http.MethodGet:
+		if path == "/lectures/progress" {
+			h.listLectureProgress(w, r)
+			return
+		}
+		if strings.HasSuffix(path, "/progress") {
+			h.getLectureProgress(w, r)
+			return
+		}
 		if strings.HasSuffix(path, "/url") {
 			h.getSignedURL(w, r)
 			return
 		}
+		if strings.HasSuffix(path, "/events") {
+			h.streamLectureEvents(w, r)
+			return
+		}
 		h.getLecture(w, r)
 	case http.MethodPatch:
 		if strings.HasSuffix(path, "/note") {
@@ -95,6 +121,10 @@ func (h *LectureHandler) handleLecture(w http.ResponseWriter, r *http.Request) {
 			h.getBatchUploadURL(w, r)
 			return
 		}
+		if strings.HasSuffix(path, "/reprocess") {
+			h.reprocessLecture(w, r)
+			return
+		}
 	case http.MethodDelete:
 		h.deleteLecture(w, r)
 	default:
@@ -135,14 +165,24 @@ func (h *LectureHandler) getLecture(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Lecture not found", http.StatusNotFound)
 		return
 	}
+	progress := service.NewLectureProgress(lecture, time.Now())
 	resp := dto.LectureResponseDTO{
 		LectureID:             lecture.ID,
 		CourseID:              lecture.CourseID,
 		Title:                 lecture.Title,
 		StoragePath:           lecture.StoragePath,
+		FileType:              lecture.FileType,
+		MimeType:              lecture.MimeType,
 		Status:                lecture.Status,
 		EmbeddingErrorDetails: map[string]interface{}(lecture.EmbeddingErrorDetails),
 		TotalSlides:           lecture.TotalSlides,
+		TotalSubImages:        lecture.TotalSubImages,
+		ProcessedSubImages:    lecture.ProcessedSubImages,
+		Stage:                 progress.Stage,
+		PercentComplete:       progress.Percent,
+		ProcessingStartedAt:   lecture.ProcessingStartedAt,
+		CompletedAt:           lecture.CompletedAt,
+		ElapsedSeconds:        int64(progress.Elapsed.Seconds()),
 		CreatedAt:             lecture.CreatedAt,
 		UpdatedAt:             lecture.UpdatedAt,
 		AccessedAt:            lecture.AccessedAt,
@@ -225,14 +265,24 @@ func (h *LectureHandler) updateLecture(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to update lecture: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	progress := service.NewLectureProgress(lecture, time.Now())
 	resp := dto.LectureResponseDTO{
 		LectureID:             lecture.ID,
 		CourseID:              lecture.CourseID,
 		Title:                 lecture.Title,
 		StoragePath:           lecture.StoragePath,
+		FileType:              lecture.FileType,
+		MimeType:              lecture.MimeType,
 		Status:                lecture.Status,
 		EmbeddingErrorDetails: map[string]interface{}(lecture.EmbeddingErrorDetails),
 		TotalSlides:           lecture.TotalSlides,
+		TotalSubImages:        lecture.TotalSubImages,
+		ProcessedSubImages:    lecture.ProcessedSubImages,
+		Stage:                 progress.Stage,
+		PercentComplete:       progress.Percent,
+		ProcessingStartedAt:   lecture.ProcessingStartedAt,
+		CompletedAt:           lecture.CompletedAt,
+		ElapsedSeconds:        int64(progress.Elapsed.Seconds()),
 		CreatedAt:             lecture.CreatedAt,
 		UpdatedAt:             lecture.UpdatedAt,
 		AccessedAt:            lecture.AccessedAt,
@@ -344,16 +394,27 @@ func (h *LectureHandler) listLectures(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to retrieve lectures: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	now := time.Now()
 	var resp []dto.LectureResponseDTO
 	for _, lec := range lectures {
+		progress := service.NewLectureProgress(&lec, now)
 		resp = append(resp, dto.LectureResponseDTO{
 			LectureID:             lec.ID,
 			CourseID:              lec.CourseID,
 			Title:                 lec.Title,
 			StoragePath:           lec.StoragePath,
+			FileType:              lec.FileType,
+			MimeType:              lec.MimeType,
 			Status:                lec.Status,
 			EmbeddingErrorDetails: lec.EmbeddingErrorDetails,
 			TotalSlides:           lec.TotalSlides,
+			TotalSubImages:        lec.TotalSubImages,
+			ProcessedSubImages:    lec.ProcessedSubImages,
+			Stage:                 progress.Stage,
+			PercentComplete:       progress.Percent,
+			ProcessingStartedAt:   lec.ProcessingStartedAt,
+			CompletedAt:           lec.CompletedAt,
+			ElapsedSeconds:        int64(progress.Elapsed.Seconds()),
 			CreatedAt:             lec.CreatedAt,
 			UpdatedAt:             lec.UpdatedAt,
 			AccessedAt:            lec.AccessedAt,
@@ -548,18 +609,209 @@ func (h *LectureHandler) getSignedURL(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// getLectureProgress godoc
+// @Summary Get lecture processing progress
+// @Description Reports the processing stage, percentage complete and elapsed time of a lecture. While it is queued or processing, an ETA is estimated from recently completed lectures with a similar slide count.
+// @Tags lectures
+// @Produce json
+// @Param lectureId path string true "Lecture ID"
+// @Success 200 {object} dto.LectureProgressDTO
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "Lecture not found"
+// @Failure 500 {string} string "Failed to retrieve lecture progress"
+// @Router /lectures/{lectureId}/progress [get]
+func (h *LectureHandler) getLectureProgress(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	lectureID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/lectures/"), "/progress")
+	progress, err := h.lectureService.GetLectureProgress(r.Context(), lectureID, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrLectureNotFound) {
+			http.Error(w, "Lecture not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve lecture progress: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(toLectureProgressDTO(*progress)); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// listLectureProgress godoc
+// @Summary List in-flight lecture progress
+// @Description Reports the progress of every lecture the user has queued or processing, oldest first, so a dashboard can poll a single endpoint.
+// @Tags lectures
+// @Produce json
+// @Success 200 {object} dto.LectureProgressListResponseDTO
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 500 {string} string "Failed to retrieve lecture progress"
+// @Router /lectures/progress [get]
+func (h *LectureHandler) listLectureProgress(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	progress, err := h.lectureService.GetInFlightLectureProgress(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve lecture progress: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp := dto.LectureProgressListResponseDTO{Lectures: make([]dto.LectureProgressDTO, 0, len(progress))}
+	for _, p := range progress {
+		resp.Lectures = append(resp.Lectures, toLectureProgressDTO(p))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func toLectureProgressDTO(p service.LectureProgress) dto.LectureProgressDTO {
+	progress := dto.LectureProgressDTO{
+		LectureID:          p.LectureID,
+		Status:             p.Status,
+		Stage:              p.Stage,
+		PercentComplete:    p.Percent,
+		TotalSlides:        p.TotalSlides,
+		TotalSubImages:     p.TotalSubImages,
+		ProcessedSubImages: p.ProcessedSubImages,
+		EmbeddingsComplete: p.EmbeddingsComplete,
+		StartedAt:          p.StartedAt,
+		CompletedAt:        p.CompletedAt,
+		ElapsedSeconds:     int64(p.Elapsed.Seconds()),
+	}
+	if p.EstimatedRemaining != nil {
+		eta := int64(p.EstimatedRemaining.Seconds())
+		progress.EtaSeconds = &eta
+	}
+	return progress
+}
+
+// streamLectureEvents godoc
+// @Summary Stream lecture processing events
+// @Description Streams Server-Sent Events for a lecture. A "lecture" event carrying the current status and progress is sent on connect and whenever processing advances; a "chat-title" event is sent when one of the lecture's chats is renamed. Comment lines are sent periodically to keep the connection open.
+// @Tags lectures
+// @Produce text/event-stream
+// @Param lectureId path string true "Lecture ID"
+// @Success 200 {object} dto.LectureStatusEventDTO
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "Lecture not found"
+// @Failure 500 {string} string "Failed to retrieve lecture"
+// @Router /lectures/{lectureId}/events [get]
+func (h *LectureHandler) streamLectureEvents(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	lectureID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/lectures/"), "/events")
+
+	// Subscribe before reading the snapshot so no change in between is missed
+	events, unsubscribe := h.lectureEvents.Subscribe(lectureID)
+	defer unsubscribe()
+
+	lecture, err := h.lectureService.GetLectureByID(r.Context(), lectureID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve lecture: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if lecture == nil {
+		http.Error(w, "Lecture not found", http.StatusNotFound)
+		return
+	}
+	course, err := h.courseService.GetCourseByID(r.Context(), lecture.CourseID)
+	if err != nil || course == nil || course.UserID != userID {
+		http.Error(w, "Lecture not found", http.StatusNotFound)
+		return
+	}
+
+	sse, err := newSSEWriter(w)
+	if err != nil {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	// Processing can outlast the server's WriteTimeout, so lift it for this stream
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to clear write deadline for lecture events")
+	}
+
+	snapshot := dto.LectureStatusEventDTO{
+		LectureID:          lecture.ID,
+		Status:             lecture.Status,
+		TotalSlides:        lecture.TotalSlides,
+		TotalSubImages:     lecture.TotalSubImages,
+		ProcessedSubImages: lecture.ProcessedSubImages,
+		EmbeddingsComplete: lecture.EmbeddingsComplete,
+		UpdatedAt:          lecture.UpdatedAt,
+	}
+	if err := sse.WriteEvent("lecture", "", snapshot); err != nil {
+		h.logger.Debug().Err(err).Msg("Failed to write lecture snapshot: client disconnected")
+		return
+	}
+
+	heartbeat := time.NewTicker(lectureEventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if err := sse.WriteComment("ping"); err != nil {
+				return
+			}
+		case event := <-events:
+			if err := h.writeLectureEvent(sse, event); err != nil {
+				h.logger.Debug().Err(err).Str("lecture_id", lectureID).Msg("Failed to write lecture event: client disconnected")
+				return
+			}
+		}
+	}
+}
+
+func (h *LectureHandler) writeLectureEvent(sse *sseWriter, event model.LectureEvent) error {
+	switch event.Type {
+	case "lecture":
+		return sse.WriteEvent("lecture", "", dto.LectureStatusEventDTO{
+			LectureID:          event.LectureID,
+			Status:             event.Status,
+			TotalSlides:        event.TotalSlides,
+			TotalSubImages:     event.TotalSubImages,
+			ProcessedSubImages: event.ProcessedSubImages,
+			EmbeddingsComplete: event.EmbeddingsComplete,
+			UpdatedAt:          event.UpdatedAt,
+		})
+	case "chat_title":
+		return sse.WriteEvent("chat-title", "", dto.ChatTitleEventDTO{
+			LectureID: event.LectureID,
+			ChatID:    event.ChatID,
+			Title:     event.Title,
+			UpdatedAt: event.UpdatedAt,
+		})
+	default:
+		h.logger.Warn().Str("type", event.Type).Msg("Skipping unknown lecture event type")
+		return nil
+	}
+}
+
 // getBatchUploadURL godoc
 // @Summary Get upload URLs for lectures
-// @Description Initiates lecture uploads by creating lecture records and returning presigned URLs for direct S3 upload. Works for both single and multiple files.
+// @Description Initiates lecture uploads by creating lecture records and returning presigned URLs for direct S3 upload. Works for both single and multiple files. Supported formats are PDF, PPTX, DOCX, PNG and JPEG; each upload must be sent with the returned content type and exactly the declared size.
 // @Tags lectures
 // @Accept json
 // @Produce json
 // @Param request body dto.LectureUploadURLRequestDTO true "Upload URL request"
 // @Success 201 {object} dto.LectureBatchUploadURLResponseDTO
-// @Failure 400 {string} string "Invalid JSON payload or validation failed"
+// @Failure 400 {string} string "Invalid JSON payload, validation failed or unsupported file type"
 // @Failure 401 {string} string "Unauthorized: User ID not found in context"
 // @Failure 403 {string} string "Upload limit exceeded"
 // @Failure 404 {string} string "Course not found or access denied"
+// @Failure 413 {string} string "File exceeds the maximum upload size"
 // @Failure 500 {string} string "Failed to create upload URLs"
 // @Router /lectures/batch-upload-url [post]
 func (h *LectureHandler) getBatchUploadURL(w http.ResponseWriter, r *http.Request) {
@@ -595,8 +847,20 @@ func (h *LectureHandler) getBatchUploadURL(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Initiate batch upload
-	lectures, presignedURLs, err := h.lectureService.InitiateBatchUpload(r.Context(), req.CourseID, userID, req.Filenames)
+	files := make([]service.UploadFile, 0, len(req.Files))
+	for _, file := range req.Files {
+		files = append(files, service.UploadFile{Filename: file.Filename, FileType: file.FileType, SizeBytes: file.SizeBytes})
+	}
+	lectures, presignedURLs, err := h.lectureService.InitiateBatchUpload(r.Context(), req.CourseID, userID, files)
 	if err != nil {
+		if errors.Is(err, service.ErrUploadTooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if errors.Is(err, service.ErrUnsupportedFileType) || errors.Is(err, service.ErrUploadSizeRequired) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to create batch upload URLs: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -605,8 +869,10 @@ func (h *LectureHandler) getBatchUploadURL(w http.ResponseWriter, r *http.Reques
 	var uploads []dto.LectureUploadURLResponseDTO
 	for i, lecture := range lectures {
 		uploads = append(uploads, dto.LectureUploadURLResponseDTO{
-			LectureID: lecture.ID,
-			UploadURL: presignedURLs[i],
+			LectureID:   lecture.ID,
+			UploadURL:   presignedURLs[i],
+			FileType:    lecture.FileType,
+			ContentType: lecture.MimeType,
 		})
 	}
 
@@ -623,16 +889,18 @@ func (h *LectureHandler) getBatchUploadURL(w http.ResponseWriter, r *http.Reques
 
 // completeUpload godoc
 // @Summary Complete lecture upload
-// @Description Finalizes a lecture upload by verifying the file exists in storage and triggering processing.
+// @Description Finalizes a lecture upload by verifying the file in storage and triggering processing. For multipart uploads the parts are assembled first; the body may list the parts to use, otherwise every uploaded part is used. Files that are too large or do not match their declared format are rejected and the lecture is marked failed.
 // @Tags lectures
 // @Accept json
 // @Produce json
 // @Param lectureId path string true "Lecture ID"
 // @Param request body dto.LectureUploadCompleteRequestDTO true "Upload complete request"
 // @Success 200 {object} dto.LectureUploadCompleteResponseDTO
-// @Failure 400 {string} string "Invalid JSON payload"
+// @Failure 400 {string} string "Invalid JSON payload or invalid upload parts"
 // @Failure 401 {string} string "Unauthorized: User ID not found in context"
 // @Failure 404 {string} string "Lecture not found or access denied"
+// @Failure 409 {string} string "Multipart upload no longer exists, or the upload has already been completed"
+// @Failure 422 {string} string "Uploaded file failed validation"
 // @Failure 500 {string} string "Failed to complete upload"
 // @Router /lectures/{lectureId}/upload-complete [post]
 func (h *LectureHandler) completeUpload(w http.ResponseWriter, r *http.Request) {
@@ -664,9 +932,36 @@ func (h *LectureHandler) completeUpload(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Parse the optional body carrying multipart completion parts
+	var req dto.LectureUploadCompleteRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		http.Error(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var parts []service.UploadedPart
+	for _, part := range req.Parts {
+		parts = append(parts, service.UploadedPart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+
 	// Complete the upload
-	updatedLecture, err := h.lectureService.CompleteUpload(r.Context(), lectureID, userID)
+	updatedLecture, err := h.lectureService.CompleteUpload(r.Context(), lectureID, userID, parts)
 	if err != nil {
+		if errors.Is(err, service.ErrUploadRejected) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidUploadParts) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, service.ErrMultipartUploadNotFound) || errors.Is(err, service.ErrUploadAlreadyCompleted) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, "Failed to complete upload: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -683,3 +978,269 @@ func (h *LectureHandler) completeUpload(w http.ResponseWriter, r *http.Request)
 		h.logger.Error().Err(err).Msg("Failed to encode response")
 	}
 }
+
+func (h *LectureHandler) handleMultipartUpload(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/lectures/")
+	lectureID, sub, _ := strings.Cut(rest, "/multipart-upload")
+	switch {
+	case sub == "" && r.Method == http.MethodPost:
+		h.initiateMultipartUpload(w, r, lectureID)
+	case sub == "" && r.Method == http.MethodDelete:
+		h.abortMultipartUpload(w, r, lectureID)
+	case sub == "/parts" && r.Method == http.MethodPost:
+		h.presignUploadParts(w, r, lectureID)
+	case sub == "/parts" && r.Method == http.MethodGet:
+		h.listUploadedParts(w, r, lectureID)
+	case sub == "" || sub == "/parts":
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// initiateMultipartUpload godoc
+// @Summary Start a multipart lecture upload
+// @Description Starts a resumable multipart upload for a lecture that is still awaiting its file, replacing any multipart upload already in progress. The response tells the client how to split the file; part URLs are requested separately and the upload is finished through upload-complete.
+// @Tags lectures
+// @Accept json
+// @Produce json
+// @Param lectureId path string true "Lecture ID"
+// @Param request body dto.LectureMultipartUploadRequestDTO true "Multipart upload request"
+// @Success 201 {object} dto.LectureMultipartUploadResponseDTO
+// @Failure 400 {string} string "Invalid JSON payload or validation failed"
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "Lecture not found"
+// @Failure 409 {string} string "Lecture is not awaiting an upload"
+// @Failure 413 {string} string "File exceeds the maximum upload size"
+// @Failure 500 {string} string "Failed to start multipart upload"
+// @Router /lectures/{lectureId}/multipart-upload [post]
+func (h *LectureHandler) initiateMultipartUpload(w http.ResponseWriter, r *http.Request, lectureID string) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.LectureMultipartUploadRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		http.Error(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	upload, err := h.lectureService.InitiateMultipartUpload(r.Context(), lectureID, userID, req.SizeBytes)
+	if err != nil {
+		writeMultipartUploadError(w, err, "Failed to start multipart upload")
+		return
+	}
+
+	resp := dto.LectureMultipartUploadResponseDTO{
+		LectureID:     lectureID,
+		UploadID:      upload.UploadID,
+		PartSizeBytes: upload.PartSizeBytes,
+		PartCount:     upload.PartCount,
+		ContentType:   upload.ContentType,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// presignUploadParts godoc
+// @Summary Get upload URLs for multipart parts
+// @Description Returns presigned URLs for uploading the given parts of the lecture's multipart upload. Up to 100 parts can be requested at once, and URLs can be requested again when they expire.
+// @Tags lectures
+// @Accept json
+// @Produce json
+// @Param lectureId path string true "Lecture ID"
+// @Param request body dto.LectureUploadPartURLsRequestDTO true "Part numbers to presign"
+// @Success 200 {object} dto.LectureUploadPartURLsResponseDTO
+// @Failure 400 {string} string "Invalid JSON payload or validation failed"
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "Lecture not found"
+// @Failure 409 {string} string "Lecture has no multipart upload in progress"
+// @Failure 500 {string} string "Failed to presign upload parts"
+// @Router /lectures/{lectureId}/multipart-upload/parts [post]
+func (h *LectureHandler) presignUploadParts(w http.ResponseWriter, r *http.Request, lectureID string) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.LectureUploadPartURLsRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		http.Error(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	parts, err := h.lectureService.PresignUploadParts(r.Context(), lectureID, userID, req.PartNumbers)
+	if err != nil {
+		writeMultipartUploadError(w, err, "Failed to presign upload parts")
+		return
+	}
+
+	resp := dto.LectureUploadPartURLsResponseDTO{Parts: make([]dto.LectureUploadPartURLDTO, 0, len(parts))}
+	for _, part := range parts {
+		resp.Parts = append(resp.Parts, dto.LectureUploadPartURLDTO{
+			PartNumber: part.PartNumber,
+			UploadURL:  part.URL,
+			ExpiresAt:  part.ExpiresAt,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// listUploadedParts godoc
+// @Summary List uploaded multipart parts
+// @Description Lists the parts of the lecture's multipart upload that storage has already received, so an interrupted upload can be resumed.
+// @Tags lectures
+// @Produce json
+// @Param lectureId path string true "Lecture ID"
+// @Success 200 {object} dto.LectureUploadedPartsResponseDTO
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "Lecture not found"
+// @Failure 409 {string} string "Lecture has no multipart upload in progress"
+// @Failure 500 {string} string "Failed to list uploaded parts"
+// @Router /lectures/{lectureId}/multipart-upload/parts [get]
+func (h *LectureHandler) listUploadedParts(w http.ResponseWriter, r *http.Request, lectureID string) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	parts, err := h.lectureService.ListUploadedParts(r.Context(), lectureID, userID)
+	if err != nil {
+		writeMultipartUploadError(w, err, "Failed to list uploaded parts")
+		return
+	}
+
+	resp := dto.LectureUploadedPartsResponseDTO{Parts: make([]dto.LectureUploadedPartDTO, 0, len(parts))}
+	for _, part := range parts {
+		resp.Parts = append(resp.Parts, dto.LectureUploadedPartDTO{
+			PartNumber:   part.PartNumber,
+			ETag:         part.ETag,
+			SizeBytes:    part.SizeBytes,
+			LastModified: part.LastModified,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// abortMultipartUpload godoc
+// @Summary Abort a multipart lecture upload
+// @Description Cancels the lecture's multipart upload and discards its parts. The lecture keeps awaiting a file, so a new upload can be started.
+// @Tags lectures
+// @Param lectureId path string true "Lecture ID"
+// @Success 204 "No Content"
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "Lecture not found"
+// @Failure 409 {string} string "Lecture has no multipart upload in progress"
+// @Failure 500 {string} string "Failed to abort multipart upload"
+// @Router /lectures/{lectureId}/multipart-upload [delete]
+func (h *LectureHandler) abortMultipartUpload(w http.ResponseWriter, r *http.Request, lectureID string) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.lectureService.AbortMultipartUpload(r.Context(), lectureID, userID); err != nil {
+		writeMultipartUploadError(w, err, "Failed to abort multipart upload")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeMultipartUploadError maps multipart upload service errors to HTTP responses.
+func writeMultipartUploadError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrLectureNotFound):
+		http.Error(w, "Lecture not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrLectureNotUploading), errors.Is(err, service.ErrMultipartUploadNotFound):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidUploadParts):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrUploadTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	default:
+		http.Error(w, fallback+": "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// reprocessLecture godoc
+// @Summary Reprocess a lecture
+// @Description Re-queues a lecture for ingestion after a failure, or when it has been stuck without progress. The uploaded file must still exist in storage. Lectures that are already queued or processing are rejected.
+// @Tags lectures
+// @Produce json
+// @Param lectureId path string true "Lecture ID"
+// @Success 202 {object} dto.LectureReprocessResponseDTO
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "Lecture not found"
+// @Failure 409 {string} string "Lecture is already queued or processing, or its file is missing"
+// @Failure 500 {string} string "Failed to reprocess lecture"
+// @Router /lectures/{lectureId}/reprocess [post]
+func (h *LectureHandler) reprocessLecture(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	lectureID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/lectures/"), "/reprocess")
+	lecture, err := h.lectureService.GetLectureByID(r.Context(), lectureID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve lecture: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if lecture == nil {
+		http.Error(w, "Lecture not found", http.StatusNotFound)
+		return
+	}
+	course, err := h.courseService.GetCourseByID(r.Context(), lecture.CourseID)
+	if err != nil || course == nil || course.UserID != userID {
+		http.Error(w, "Lecture not found", http.StatusNotFound)
+		return
+	}
+
+	updatedLecture, err := h.lectureService.ReprocessLecture(r.Context(), lectureID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLectureNotFound):
+			http.Error(w, "Lecture not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrLectureNotReprocessable):
+			http.Error(w, "Lecture is already queued or processing", http.StatusConflict)
+		case errors.Is(err, service.ErrLectureFileMissing):
+			http.Error(w, "Lecture file not found in storage; please upload it again", http.StatusConflict)
+		default:
+			http.Error(w, "Failed to reprocess lecture: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	resp := dto.LectureReprocessResponseDTO{
+		LectureID: updatedLecture.ID,
+		Status:    updatedLecture.Status,
+		Message:   "Lecture has been queued for processing.",
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"app/internal/api/v1/dto"
+	"app/internal/middleware"
+	"app/internal/model"
+	"app/internal/service"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// searchMaxLimit caps the page size of search results.
+	searchMaxLimit = 100
+	// semanticSearchMaxLimit caps how many nearest chunks a semantic search returns.
+	semanticSearchMaxLimit = 50
+)
+
+// SearchHandler handles search endpoints
+type SearchHandler struct {
+	searchService service.SearchService
+	validate      *validator.Validate
+	logger        zerolog.Logger
+}
+
+// NewSearchHandler creates a new SearchHandler
+func NewSearchHandler(searchService service.SearchService, validate *validator.Validate, logger zerolog.Logger) *SearchHandler {
+	return &SearchHandler{
+		searchService: searchService,
+		validate:      validate,
+		logger:        logger,
+	}
+}
+
+// RegisterRoutes mounts search routes
+func (h *SearchHandler) RegisterRoutes(mux *http.ServeMux, authMw func(http.Handler) http.Handler) {
+	mux.Handle("/search", authMw(http.HandlerFunc(h.search)))
+	mux.Handle("/search/semantic", authMw(http.HandlerFunc(h.semanticSearch)))
+}
+
+// search godoc
+// @Summary Search the user's content
+// @Description Full-text search over the authenticated user's chat messages, notes, lecture titles and course titles, best matches first. Supports quoted phrases, OR and -exclusions. Snippets are HTML with the matched terms wrapped in <mark>.
+// @Tags search
+// @Produce json
+// @Param q query string true "Search query"
+// @Param course_id query string false "Only return results from this course"
+// @Param lecture_id query string false "Only return results from this lecture"
+// @Param limit query int false "Number of results to return (default 20, max 100)"
+// @Param offset query int false "Offset for pagination (default 0)"
+// @Success 200 {object} dto.SearchResponseDTO
+// @Failure 400 {string} string "Invalid search query or filter"
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 500 {string} string "Failed to search"
+// @Router /search [get]
+func (h *SearchHandler) search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	filter, ok := h.parseSearchFilter(w, r)
+	if !ok {
+		return
+	}
+	limit := 20
+	if l := q.Get("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = min(v, searchMaxLimit)
+		}
+	}
+	offset := 0
+	if o := q.Get("offset"); o != "" {
+		if v, err := strconv.Atoi(o); err == nil && v >= 0 {
+			offset = v
+		}
+	}
+
+	results, err := h.searchService.Search(r.Context(), userID, q.Get("q"), filter, limit, offset)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSearchQuery) {
+			http.Error(w, "Invalid search query: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to search: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.SearchResponseDTO{
+		Results: make([]dto.SearchResultDTO, len(results)),
+		Limit:   limit,
+		Offset:  offset,
+	}
+	for i, res := range results {
+		resp.Results[i] = dto.SearchResultDTO{
+			Kind:         res.Kind,
+			ID:           res.ID,
+			CourseID:     res.CourseID,
+			CourseTitle:  res.CourseTitle,
+			LectureID:    res.LectureID,
+			LectureTitle: res.LectureTitle,
+			ChatID:       res.ChatID,
+			ChatTitle:    res.ChatTitle,
+			Snippet:      res.Snippet,
+			Rank:         res.Rank,
+			CreatedAt:    res.CreatedAt,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// semanticSearch godoc
+// @Summary Semantic search over lecture content
+// @Description Finds the passages of the authenticated user's lectures closest in meaning to the query, using the lecture embeddings. Searches one lecture, one course, or all of the user's lectures when neither is given. The query is embedded with the user's OpenAI API key.
+// @Tags search
+// @Produce json
+// @Param q query string true "Search query"
+// @Param course_id query string false "Only search lectures of this course"
+// @Param lecture_id query string false "Only search this lecture"
+// @Param limit query int false "Number of results to return (default 10, max 50)"
+// @Success 200 {object} dto.SemanticSearchResponseDTO
+// @Failure 400 {string} string "Invalid search query or filter, or API key required"
+// @Failure 401 {string} string "Unauthorized: User ID not found in context"
+// @Failure 404 {string} string "Lecture or course not found"
+// @Failure 500 {string} string "Failed to search"
+// @Router /search/semantic [get]
+func (h *SearchHandler) semanticSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := r.Context().Value(middleware.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	filter, ok := h.parseSearchFilter(w, r)
+	if !ok {
+		return
+	}
+	limit := 10
+	if l := q.Get("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = min(v, semanticSearchMaxLimit)
+		}
+	}
+
+	matches, err := h.searchService.SemanticSearch(r.Context(), userID, q.Get("q"), filter, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidSearchQuery):
+			http.Error(w, "Invalid search query: "+err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrEmbeddingKeyRequired):
+			http.Error(w, "API key required: "+err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrLectureNotFound):
+			http.Error(w, "Lecture not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrCourseNotFound):
+			http.Error(w, "Course not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to search: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	resp := dto.SemanticSearchResponseDTO{Results: make([]dto.ChunkMatchDTO, len(matches))}
+	for i, m := range matches {
+		resp.Results[i] = dto.ChunkMatchDTO{
+			ChunkID:      m.ChunkID,
+			LectureID:    m.LectureID,
+			LectureTitle: m.LectureTitle,
+			CourseID:     m.CourseID,
+			SlideNumber:  m.SlideNumber,
+			Text:         m.Text,
+			Score:        m.Score,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// parseSearchFilter reads the course_id and lecture_id filters, replying with 400 if either is not
+// a UUID.
+func (h *SearchHandler) parseSearchFilter(w http.ResponseWriter, r *http.Request) (model.SearchFilter, bool) {
+	q := r.URL.Query()
+	filter := model.SearchFilter{
+		CourseID:  q.Get("course_id"),
+		LectureID: q.Get("lecture_id"),
+	}
+	if err := h.validate.Var(filter.CourseID, "omitempty,uuid"); err != nil {
+		http.Error(w, "Invalid course_id", http.StatusBadRequest)
+		return filter, false
+	}
+	if err := h.validate.Var(filter.LectureID, "omitempty,uuid"); err != nil {
+		http.Error(w, "Invalid lecture_id", http.StatusBadRequest)
+		return filter, false
+	}
+	return filter, true
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// sseWriter writes Server-Sent Events, flushing each one so it reaches the client immediately.
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// newSSEWriter sets the SSE response headers. It fails if the response cannot be streamed,
+// in which case nothing has been written yet.
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming not supported")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
+	return &sseWriter{w: w, flusher: flusher}, nil
+}
+
+// WriteData writes v as JSON in an unnamed event.
+func (s *sseWriter) WriteData(v any) error {
+	return s.WriteEvent("", "", v)
+}
+
+// WriteEvent writes v as JSON. The event name and ID are omitted when empty.
+func (s *sseWriter) WriteEvent(event, id string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshaling event data: %w", err)
+	}
+	var b strings.Builder
+	if id != "" {
+		fmt.Fprintf(&b, "id: %s\n", id)
+	}
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	fmt.Fprintf(&b, "data: %s\n\n", data)
+	return s.write(b.String())
+}
+
+// WriteRaw writes data verbatim in an unnamed event, e.g. the "[DONE]" marker.
+func (s *sseWriter) WriteRaw(data string) error {
+	return s.write("data: " + data + "\n\n")
+}
+
+// WriteComment writes a comment line, which clients ignore; used to keep idle streams open.
+func (s *sseWriter) WriteComment(text string) error {
+	return s.write(": " + text + "\n\n")
+}
+
+func (s *sseWriter) write(frame string) error {
+	if _, err := io.WriteString(s.w, frame); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
@@ -4,10 +4,14 @@ import (
 	"app/internal/api/v1/handler"
 	"app/internal/config"
 	"app/internal/middleware"
+	"app/internal/model"
 	"app/internal/pubsub"
 	"app/internal/repository"
 	"app/internal/service"
+	"app/internal/telemetry"
+	"app/internal/util"
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -16,14 +20,19 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	awsmiddleware "github.com/aws/smithy-go/middleware"
+	"github.com/exaring/otelpgx"
 	"github.com/go-playground/validator/v10"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/cors"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
-func New(cfg *config.Config, logger zerolog.Logger) (http.Handler, *pgxpool.Pool, error) {
+// New wires up the API and returns its handler, the DB pool and the background workers
+// that must run alongside the HTTP server.
+func New(cfg *config.Config, logger zerolog.Logger) (http.Handler, *pgxpool.Pool, []service.BackgroundWorker, error) {
 
 	// 2. Open DB connection (connection pooling)
 	dsn := cfg.DBConnectionString
@@ -43,31 +52,39 @@ func New(cfg *config.Config, logger zerolog.Logger) (http.Handler, *pgxpool.Pool
 			}
 			dsn += separator + "sslmode=disable"
 		}
-		pool, err = pgxpool.New(context.Background(), dsn)
+		dbConfig, parseErr := pgxpool.ParseConfig(dsn)
+		if parseErr != nil {
+			logger.Fatal().Err(parseErr).Msg("Failed to parse DB connection string for development")
+			return nil, nil, nil, parseErr
+		}
+		dbConfig.ConnConfig.Tracer = otelpgx.NewTracer()
+
+		pool, err = pgxpool.NewWithConfig(context.Background(), dbConfig)
 		if err != nil {
 			logger.Fatal().Err(err).Msg("Failed to create connection pool for development")
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 	} else {
 		// For staging/production, use the transaction pooler with prepared statements disabled.
 		dbConfig, parseErr := pgxpool.ParseConfig(dsn)
 		if parseErr != nil {
 			logger.Fatal().Err(parseErr).Msg("Failed to parse DB connection string for production")
-			return nil, nil, parseErr
+			return nil, nil, nil, parseErr
 		}
 		dbConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+		dbConfig.ConnConfig.Tracer = otelpgx.NewTracer()
 
 		pool, err = pgxpool.NewWithConfig(context.Background(), dbConfig)
 		if err != nil {
 			logger.Fatal().Err(err).Msg("Failed to create connection pool for production")
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 	}
 
 	// Ping the database to ensure connection is valid
 	if err := pool.Ping(context.Background()); err != nil {
 		logger.Fatal().Err(err).Msg("Failed to ping DB")
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// 3. Initialize S3 client
@@ -79,6 +96,7 @@ func New(cfg *config.Config, logger zerolog.Logger) (http.Handler, *pgxpool.Pool
 	if err != nil {
 		logger.Fatal().Msgf("Failed to load S3 config: %v", err)
 	}
+	otelaws.AppendMiddlewares(&s3Config.APIOptions)
 	s3Client := s3.NewFromConfig(s3Config, func(o *s3.Options) {
 		o.BaseEndpoint = aws.String(cfg.S3URL)
 		o.UsePathStyle = true
@@ -91,14 +109,14 @@ func New(cfg *config.Config, logger zerolog.Logger) (http.Handler, *pgxpool.Pool
 	pubSubPublisher, err := pubsub.NewPublisher(context.Background(), cfg)
 	if err != nil {
 		logger.Fatal().Msgf("Failed to create Pub/Sub publisher: %v", err)
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// 6. Initialize Secret Manager service
 	secretManagerSvc, err := service.NewSecretManagerService(context.Background(), cfg)
 	if err != nil {
 		logger.Fatal().Msgf("Failed to create Secret Manager service: %v", err)
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// 7. Initialize repositories & services & handlers
@@ -108,6 +126,21 @@ func New(cfg *config.Config, logger zerolog.Logger) (http.Handler, *pgxpool.Pool
 	noteRepo := repository.NewNoteRepository(pool)
 	chatRepo := repository.NewChatRepo(pool)
 	dlqRepo := repository.NewDLQRepository(pool)
+	searchRepo := repository.NewSearchRepo(pool)
+	outboxRepo := repository.NewOutboxRepository(pool)
+	roleRepo := repository.NewRoleRepo(pool)
+	listenDSN := cfg.DBListenConnectionString
+	if listenDSN == "" {
+		// Staging and production connect through the transaction pooler, which cannot LISTEN, so
+		// falling back there would leave lecture event streams silently empty.
+		if cfg.Environment != "development" {
+			err := errors.New("DB_LISTEN_CONNECTION_STRING is required outside development")
+			logger.Fatal().Err(err).Msg("Failed to configure lecture event listener")
+			return nil, nil, nil, err
+		}
+		listenDSN = dsn
+	}
+	lectureEventListener := repository.NewLectureEventListener(listenDSN)
 
 	openAIValidator := service.NewOpenAIValidator()
 	geminiValidator := service.NewGeminiValidator()
@@ -116,23 +149,58 @@ func New(cfg *config.Config, logger zerolog.Logger) (http.Handler, *pgxpool.Pool
 	deepseekValidator := service.NewDeepSeekValidator()
 	pythonClient := service.NewPythonClient(cfg.PythonServiceBaseURL, logger)
 
-	lectureSvc := service.NewLectureService(lectureRepo, userRepo, s3Client, cfg.S3Bucket, pubSubPublisher, cfg.PubSubIngestionTopic, logger)
+	lectureSvc := service.NewLectureService(lectureRepo, userRepo, s3Client, cfg.S3Bucket, cfg.PubSubIngestionTopic, cfg.UploadMaxSizeBytes, logger)
 	userSvc := service.NewUserService(userRepo, courseRepo, lectureRepo, lectureSvc, secretManagerSvc, openAIValidator, geminiValidator, anthropicValidator, xaiValidator, deepseekValidator, logger)
 	courseSvc := service.NewCourseService(courseRepo, lectureSvc, logger)
 	noteSvc := service.NewNoteService(noteRepo, logger)
-	chatSvc := service.NewChatService(chatRepo, lectureRepo, pythonClient, logger)
-	dlqSvc := service.NewDLQService(dlqRepo, logger)
+	chatSvc := service.NewChatService(chatRepo, lectureRepo, courseRepo, pythonClient, logger)
+	searchSvc := service.NewSearchService(searchRepo, lectureRepo, courseRepo, userRepo, pythonClient, logger)
+	adminSvc := service.NewAdminService(userRepo, lectureRepo, roleRepo, logger)
+	dlqSvc := service.NewDLQService(dlqRepo, lectureRepo, pubSubPublisher, cfg.PubSubIngestionTopic, service.IngestionRetryPolicy{
+		MaxAttempts: cfg.IngestionMaxAutoRetries,
+		BaseDelay:   cfg.IngestionRetryBaseDelay,
+	}, logger)
+	outboxDispatcher := service.NewOutboxDispatcher(outboxRepo, pubSubPublisher, service.OutboxDispatcherConfig{
+		PollInterval: cfg.OutboxPollInterval,
+		BatchSize:    cfg.OutboxBatchSize,
+		MaxAttempts:  cfg.OutboxMaxAttempts,
+	}, logger)
+	lectureEventHub := service.NewLectureEventHub(lectureEventListener, logger)
+	multipartSweeper := service.NewMultipartUploadSweeper(s3Client, cfg.S3Bucket, lectureRepo, cfg.MultipartUploadTTL, cfg.MultipartSweepInterval, logger)
+	uploadJanitor := service.NewUploadJanitor(lectureRepo, lectureSvc, service.UploadJanitorConfig{
+		TTL:      cfg.UploadJanitorTTL,
+		Interval: cfg.UploadJanitorInterval,
+		DryRun:   cfg.UploadJanitorDryRun,
+	}, logger)
 
 	userHandler := handler.NewUserHandler(userSvc, validate, logger)
-	courseHandler := handler.NewCourseHandler(courseSvc, validate, logger)
 	chatHandler := handler.NewChatHandler(chatSvc, validate, logger)
-	lectureHandler := handler.NewLectureHandler(lectureSvc, courseSvc, noteSvc, chatHandler, validate, cfg.S3URL, cfg.S3Bucket, logger)
-	dlqHandler := handler.NewDLQHandler(dlqSvc, logger)
+	courseHandler := handler.NewCourseHandler(courseSvc, chatHandler, validate, logger)
+	lectureHandler := handler.NewLectureHandler(lectureSvc, courseSvc, noteSvc, chatHandler, lectureEventHub, validate, cfg.S3URL, cfg.S3Bucket, logger)
+	dlqHandler := handler.NewDLQHandler(dlqSvc, validate, logger)
+	searchHandler := handler.NewSearchHandler(searchSvc, validate, logger)
+	adminHandler := handler.NewAdminHandler(adminSvc, validate, logger)
 
 	// 7. Initialize middleware
-	authMiddleware := middleware.AuthMiddleware(cfg.JWTSecret)
+	jwtVerifier, err := util.NewJWTVerifier(util.JWTVerifierConfig{
+		JWKSURL:  cfg.GetJWKSURL(),
+		Secret:   cfg.JWTSecret,
+		Issuer:   cfg.GetJWTIssuer(),
+		Audience: cfg.GetJWTAudience(),
+		Leeway:   cfg.JWTClockSkew,
+		CacheTTL: cfg.JWKSCacheTTL,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to create JWT verifier")
+		return nil, nil, nil, err
+	}
+	authMiddleware := middleware.AuthMiddleware(jwtVerifier)
 	isLocalDev := cfg.PubSubEmulatorHost != ""
 	pubsubAuthMiddleware := middleware.PubSubAuthMiddleware(isLocalDev, cfg.DLQEndpointURL, cfg.PubSubPushServiceAccountEmail, logger)
+	requireAdmin := middleware.RequireRole(model.RoleAdmin, roleRepo, logger)
+	adminMiddleware := func(next http.Handler) http.Handler {
+		return authMiddleware(requireAdmin(next))
+	}
 
 	// 8. Create ServeMux router
 	mux := http.NewServeMux()
@@ -143,7 +211,10 @@ func New(cfg *config.Config, logger zerolog.Logger) (http.Handler, *pgxpool.Pool
 	courseHandler.RegisterRoutes(apiV1Mux, authMiddleware)
 	lectureHandler.RegisterRoutes(apiV1Mux, authMiddleware)
 	chatHandler.RegisterRoutes(apiV1Mux, authMiddleware)
+	searchHandler.RegisterRoutes(apiV1Mux, authMiddleware)
 	dlqHandler.RegisterRoutes(apiV1Mux, pubsubAuthMiddleware)
+	dlqHandler.RegisterAdminRoutes(apiV1Mux, adminMiddleware)
+	adminHandler.RegisterRoutes(apiV1Mux, adminMiddleware)
 
 	// Mount the API v1 routes under /v1
 	mux.Handle("/v1/", http.StripPrefix("/v1", apiV1Mux))
@@ -176,10 +247,19 @@ func New(cfg *config.Config, logger zerolog.Logger) (http.Handler, *pgxpool.Pool
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
 		AllowCredentials: true,
+		ExposedHeaders:   []string{"X-Request-ID"},
 		Debug:            false, // Enable debug logging for CORS
 	})
 
-	return middleware.LoggerMiddleware(c.Handler(mux)), pool, nil
+	workers := []service.BackgroundWorker{outboxDispatcher, multipartSweeper, uploadJanitor, lectureEventHub}
+
+	accessLog := middleware.LoggerMiddleware(middleware.AccessLogConfig{
+		SampledPaths: cfg.AccessLogSampledPaths,
+		SampleRate:   cfg.AccessLogSampleRate,
+	})
+	// The server span wraps everything, so request logs can carry its trace ID
+	apiHandler := middleware.RequestIDMiddleware(logger)(accessLog(c.Handler(mux)))
+	return otelhttp.NewHandler(apiHandler, "http.server", otelhttp.WithSpanNameFormatter(telemetry.SpanName)), pool, workers, nil
 }
 
 // removeDisableGzip is a workaround for S3 signature errors with some S3-compatible services.
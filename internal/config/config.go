@@ -1,13 +1,17 @@
 package config
 
 import (
+	"strings"
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 )
 
 type Config struct {
 	// Local & Github Secrets (Fill up for local development)
 	DBConnectionString   string `envconfig:"DB_CONNECTION_STRING" required:"true"`
-	JWTSecret            string `envconfig:"SUPABASE_JWT_SECRET" required:"true"`
+	SupabaseURL          string `envconfig:"SUPABASE_URL" required:"true"`
+	JWTSecret            string `envconfig:"SUPABASE_JWT_SECRET"` // legacy HS256 secret, accepted while signing keys are migrated
 	S3URL                string `envconfig:"SUPABASE_S3_URL" required:"true"`
 	S3Bucket             string `envconfig:"SUPABASE_S3_BUCKET" required:"true"`
 	S3Region             string `envconfig:"SUPABASE_S3_REGION" required:"true"`
@@ -17,6 +21,48 @@ type Config struct {
 	PubSubIngestionTopic string `envconfig:"PUBSUB_INGESTION_TOPIC" default:"ingestion"`
 	PythonServiceBaseURL string `envconfig:"PYTHON_SERVICE_BASE_URL" required:"true"`
 
+	// Supabase Auth access token checks; the issuer defaults to SUPABASE_URL + "/auth/v1" and the
+	// audience to "authenticated", also when the deploy passes them through empty
+	JWTIssuer    string        `envconfig:"SUPABASE_JWT_ISSUER"`
+	JWTAudience  string        `envconfig:"SUPABASE_JWT_AUDIENCE"`
+	JWTClockSkew time.Duration `envconfig:"SUPABASE_JWT_CLOCK_SKEW" default:"30s"`
+	JWKSCacheTTL time.Duration `envconfig:"SUPABASE_JWKS_CACHE_TTL" default:"10m"`
+
+	// Automatic re-publishing of dead-lettered ingestion jobs
+	IngestionMaxAutoRetries int           `envconfig:"INGESTION_MAX_AUTO_RETRIES" default:"3"`
+	IngestionRetryBaseDelay time.Duration `envconfig:"INGESTION_RETRY_BASE_DELAY" default:"1m"`
+
+	// Transactional outbox dispatcher
+	OutboxPollInterval time.Duration `envconfig:"OUTBOX_POLL_INTERVAL" default:"2s"`
+	OutboxBatchSize    int           `envconfig:"OUTBOX_BATCH_SIZE" default:"20"`
+	OutboxMaxAttempts  int           `envconfig:"OUTBOX_MAX_ATTEMPTS" default:"10"`
+
+	// Largest lecture file accepted for upload, in bytes (default 256 MiB)
+	UploadMaxSizeBytes int64 `envconfig:"UPLOAD_MAX_SIZE_BYTES" default:"268435456"`
+
+	// Multipart uploads left unfinished longer than the TTL are aborted by a periodic sweep
+	MultipartUploadTTL     time.Duration `envconfig:"MULTIPART_UPLOAD_TTL" default:"24h"`
+	MultipartSweepInterval time.Duration `envconfig:"MULTIPART_SWEEP_INTERVAL" default:"1h"`
+
+	// Lectures left in 'uploading' longer than the TTL are deleted by a periodic janitor
+	UploadJanitorTTL      time.Duration `envconfig:"UPLOAD_JANITOR_TTL" default:"24h"`
+	UploadJanitorInterval time.Duration `envconfig:"UPLOAD_JANITOR_INTERVAL" default:"1h"`
+	UploadJanitorDryRun   bool          `envconfig:"UPLOAD_JANITOR_DRY_RUN" default:"false"`
+
+	// Successful requests to these path prefixes (e.g. "/v1/lectures/") are only access-logged
+	// at the sample rate; failed requests are always logged
+	AccessLogSampledPaths []string `envconfig:"ACCESS_LOG_SAMPLED_PATHS"`
+	AccessLogSampleRate   float64  `envconfig:"ACCESS_LOG_SAMPLE_RATE" default:"0.1"`
+
+	// OpenTelemetry tracing: "otlp" exports to OTEL_EXPORTER_OTLP_ENDPOINT, "stdout" prints spans
+	// for local runs and "none" turns recording off
+	TracingExporter    string  `envconfig:"TRACING_EXPORTER" default:"none"`
+	TracingSampleRatio float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1"`
+
+	// Session-mode connection used to LISTEN for lecture events. Transaction poolers do not support
+	// LISTEN, so it is required outside development, where it defaults to DB_CONNECTION_STRING.
+	DBListenConnectionString string `envconfig:"DB_LISTEN_CONNECTION_STRING"`
+
 	// Local Secrets (Fill up for local development)
 	Port                       string `envconfig:"PORT" default:"8080"`
 	PubSubEmulatorHost         string `envconfig:"PUBSUB_EMULATOR_HOST"`
@@ -51,6 +97,27 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
+// GetJWTIssuer returns the expected issuer of Supabase Auth access tokens.
+func (c *Config) GetJWTIssuer() string {
+	if c.JWTIssuer != "" {
+		return c.JWTIssuer
+	}
+	return strings.TrimSuffix(c.SupabaseURL, "/") + "/auth/v1"
+}
+
+// GetJWTAudience returns the expected audience of Supabase Auth access tokens.
+func (c *Config) GetJWTAudience() string {
+	if c.JWTAudience != "" {
+		return c.JWTAudience
+	}
+	return "authenticated"
+}
+
+// GetJWKSURL returns the URL of the project's public JWT signing keys.
+func (c *Config) GetJWKSURL() string {
+	return strings.TrimSuffix(c.SupabaseURL, "/") + "/auth/v1/.well-known/jwks.json"
+}
+
 // GetGCPProjectID returns the appropriate GCP project ID based on the environment.
 // Uses the same logic as Pub/Sub: local if emulator host is set, otherwise staging (preferred) or prod.
 func (c *Config) GetGCPProjectID() string {
@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// RequestIDHeader carries the request ID on incoming requests, their responses and calls to
+// the Python service.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the ID of the request ctx belongs to, or "" outside of a request.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext returns the request-scoped logger stored in ctx, or a new logger when there is none.
+func FromContext(ctx context.Context) zerolog.Logger {
+	if l := zerolog.Ctx(ctx); l.GetLevel() != zerolog.Disabled {
+		return *l
+	}
+	return New()
+}
+
+// ForRequest adds the request ID of ctx, if any, to a component logger, so that work done for a
+// request outside of its handler, such as a streamed chat reply, can be tied back to it.
+func ForRequest(ctx context.Context, l zerolog.Logger) zerolog.Logger {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return l.With().Str("request_id", id).Logger()
+	}
+	return l
+}
@@ -11,34 +11,48 @@ import (
 // Injected key type to avoid context collisions
 type contextKey string
 
-const UserContextKey = contextKey("user")
+const (
+	UserContextKey   = contextKey("user")
+	ClaimsContextKey = contextKey("claims")
+)
 
-func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
+// AuthMiddleware verifies the bearer token and puts the user ID under UserContextKey and the
+// full token claims under ClaimsContextKey. The request logger also gets the user ID.
+func AuthMiddleware(verifier *util.JWTVerifier) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			logger := logger.New()
+			log := logger.FromContext(r.Context())
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				logger.Error().Msg("Authorization header missing")
+				log.Error().Msg("Authorization header missing")
 				http.Error(w, "Authorization header missing", http.StatusUnauthorized)
 				return
 			}
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) != 2 || parts[0] != "Bearer" {
-				logger.Error().Msg("Invalid authorization header")
+				log.Error().Msg("Invalid authorization header")
 				http.Error(w, "Invalid authorization header", http.StatusUnauthorized)
 				return
 			}
 			tokenString := parts[1]
-			claims, err := util.ValidateJWT(tokenString, jwtSecret)
+			claims, err := verifier.Verify(r.Context(), tokenString)
 			if err != nil {
-				logger.Error().Msgf("Invalid token: %+v", err)
+				log.Error().Msgf("Invalid token: %+v", err)
 				http.Error(w, "Invalid token: "+err.Error(), http.StatusUnauthorized)
 				return
 			}
-			// Embed user ID (or entire claims) into request context
 			ctx := context.WithValue(r.Context(), UserContextKey, claims.Subject)
+			ctx = context.WithValue(ctx, ClaimsContextKey, claims)
+			log = log.With().Str("user_id", claims.Subject).Logger()
+			setAccessLogUser(ctx, claims.Subject)
+			ctx = log.WithContext(ctx)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// ClaimsFromContext returns the verified token claims of an authenticated request.
+func ClaimsFromContext(ctx context.Context) (*util.Claims, bool) {
+	claims, ok := ctx.Value(ClaimsContextKey).(*util.Claims)
+	return claims, ok && claims != nil
+}
@@ -1,19 +1,141 @@
 package middleware
 
 import (
-	"app/internal/logger"
+	"context"
+	"math/rand/v2"
 	"net/http"
+	"strings"
+	"time"
+
+	"app/internal/logger"
+
+	"github.com/rs/zerolog"
 )
 
-// LoggerMiddleware logs incoming HTTP requests.
-func LoggerMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+// AccessLogConfig configures the access log written by LoggerMiddleware.
+type AccessLogConfig struct {
+	// SampledPaths are path prefixes of noisy routes, such as polling endpoints, whose
+	// successful requests are only logged at SampleRate. Failed requests are always logged.
+	SampledPaths []string
+	// SampleRate is the fraction of sampled requests that are logged, from 0 to 1
+	SampleRate float64
+}
+
+// LoggerMiddleware writes one structured access log line per request once its handler returns:
+// at info level, or at warn and error level for 4xx and 5xx responses. Streamed responses, such
+// as SSE, are logged when the stream ends and are marked as streamed.
+func LoggerMiddleware(cfg AccessLogConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &responseRecorder{ResponseWriter: w}
+			entry := &accessLogEntry{}
+			ctx := context.WithValue(r.Context(), accessLogEntryKey{}, entry)
+
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status < http.StatusBadRequest && cfg.sampledOut(r.URL.Path) {
+				return
+			}
+
+			log := logger.FromContext(r.Context())
+			var event *zerolog.Event
+			switch {
+			case status >= http.StatusInternalServerError:
+				event = log.Error()
+			case status >= http.StatusBadRequest:
+				event = log.Warn()
+			default:
+				event = log.Info()
+			}
+			if entry.userID != "" {
+				event = event.Str("user_id", entry.userID)
+			}
+			event.
+				Str("method", r.Method).
+				Str("uri", r.URL.RequestURI()).
+				Int("status", status).
+				Int64("bytes", rec.bytes).
+				Dur("latency_ms", time.Since(start)).
+				Bool("streamed", rec.streamed).
+				Str("remote_ip", clientIP(r)).
+				Str("user_agent", r.UserAgent()).
+				Msgf("%s %s %d", r.Method, r.URL.Path, status)
+		})
+	}
+}
+
+// sampledOut reports whether a successful request to path is left out of the access log.
+func (c AccessLogConfig) sampledOut(path string) bool {
+	for _, prefix := range c.SampledPaths {
+		if prefix != "" && strings.HasPrefix(path, prefix) {
+			return rand.Float64() >= c.SampleRate
+		}
+	}
+	return false
+}
+
+type accessLogEntryKey struct{}
 
-		// Call the next handler in the chain
-		next.ServeHTTP(w, r)
+// accessLogEntry collects what inner middleware learns about a request, such as the
+// authenticated user, for the access log line written once the request is done.
+type accessLogEntry struct {
+	userID string
+}
+
+// setAccessLogUser records the authenticated user of the request in its access log line.
+func setAccessLogUser(ctx context.Context, userID string) {
+	if entry, ok := ctx.Value(accessLogEntryKey{}).(*accessLogEntry); ok {
+		entry.userID = userID
+	}
+}
+
+// clientIP returns the client address, preferring the first X-Forwarded-For hop set by the
+// load balancer.
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		ip, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(ip)
+	}
+	return r.RemoteAddr
+}
+
+// responseRecorder records the status and size of a response. It implements http.Flusher so
+// that SSE handlers can still stream through it, and Unwrap for http.ResponseController.
+type responseRecorder struct {
+	http.ResponseWriter
+	status   int
+	bytes    int64
+	streamed bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *responseRecorder) Flush() {
+	r.streamed = true
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
 
-		logger := logger.New()
-		// Log original message format with full request URI including query params
-		logger.Debug().Msgf("%s %s", r.Method, r.URL.RequestURI())
-	})
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
 }
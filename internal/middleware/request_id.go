@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"net/http"
+
+	"app/internal/logger"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxRequestIDLength bounds client-supplied request IDs.
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware tags each request with an ID, taken from the X-Request-ID header when the
+// client sent a well-formed one and generated otherwise. The ID is echoed in the response and put
+// in the request context, along with a logger that includes it, and the trace ID of traced
+// requests, in every line.
+func RequestIDMiddleware(base zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(logger.RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = rand.Text()
+			}
+			w.Header().Set(logger.RequestIDHeader, requestID)
+
+			logCtx := base.With().Str("request_id", requestID)
+			if span := trace.SpanFromContext(r.Context()); span.SpanContext().IsValid() {
+				span.SetAttributes(attribute.String("http.request.header.x-request-id", requestID))
+				logCtx = logCtx.Str("trace_id", span.SpanContext().TraceID().String())
+			}
+			log := logCtx.Logger()
+			ctx := logger.WithRequestID(r.Context(), requestID)
+			ctx = log.WithContext(ctx)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// validRequestID accepts IDs made of letters, digits and - _ . : so that they are safe to log and
+// forward as headers and Pub/Sub attributes.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
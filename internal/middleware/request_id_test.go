@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"app/internal/logger"
+
+	"github.com/rs/zerolog"
+)
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"01HZX3J4Q7K2", true},
+		{"a1b2c3d4-e5f6-7890-abcd-ef1234567890", true},
+		{"trace.span:1_2", true},
+		{strings.Repeat("a", maxRequestIDLength), true},
+		{"", false},
+		{strings.Repeat("a", maxRequestIDLength+1), false},
+		{"has space", false},
+		{"line\nbreak", false},
+		{"quote\"", false},
+		{"ünïcode", false},
+	}
+	for _, tt := range tests {
+		if got := validRequestID(tt.id); got != tt.want {
+			t.Errorf("validRequestID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var gotID string
+	h := RequestIDMiddleware(zerolog.Nop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = logger.RequestIDFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"keeps a well-formed ID", "client-id-123", true},
+		{"replaces a malformed ID", "bad id\r\n", false},
+		{"generates a missing ID", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(logger.RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			echoed := rec.Header().Get(logger.RequestIDHeader)
+			if echoed == "" || echoed != gotID {
+				t.Fatalf("response ID %q, context ID %q, want the same non-empty ID", echoed, gotID)
+			}
+			if tt.keep != (echoed == tt.incoming) {
+				t.Errorf("ID = %q for incoming %q, keep %v", echoed, tt.incoming, tt.keep)
+			}
+			if !validRequestID(echoed) {
+				t.Errorf("ID %q is not a valid request ID", echoed)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rs/zerolog"
+)
+
+// RoleChecker looks up roles granted outside the token, e.g. in the database.
+type RoleChecker interface {
+	HasRole(ctx context.Context, userID, role string) (bool, error)
+}
+
+// RequireRole restricts access to users with the role, granted either through their token's
+// app_metadata or by roles. It must run after AuthMiddleware so the claims are in the request
+// context.
+func RequireRole(role string, roles RoleChecker, logger zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok || claims.Subject == "" {
+				http.Error(w, "Unauthorized: User ID not found in context", http.StatusUnauthorized)
+				return
+			}
+			if claims.HasRole(role) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			granted, err := roles.HasRole(r.Context(), claims.Subject, role)
+			if err != nil {
+				logger.Error().Err(err).Str("user_id", claims.Subject).Str("role", role).Msg("Failed to look up user role")
+				http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+				return
+			}
+			if !granted {
+				logger.Warn().Str("user_id", claims.Subject).Str("role", role).Str("path", r.URL.Path).Msg("User without the required role attempted to access a restricted route")
+				http.Error(w, "Forbidden: "+role+" role required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -7,34 +7,82 @@ import (
 	"time"
 )
 
-// Chat represents a chat conversation for a lecture
+// Chat represents a chat conversation about a lecture, or about every lecture of a course.
+// Exactly one of LectureID and CourseID is set.
 type Chat struct {
 	ID        string    `db:"id" json:"id"`
-	LectureID string    `db:"lecture_id" json:"lecture_id"`
+	LectureID *string   `db:"lecture_id" json:"lecture_id"`
+	CourseID  *string   `db:"course_id" json:"course_id"`
 	UserID    string    `db:"user_id" json:"user_id"`
 	Title     string    `db:"title" json:"title"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 }
 
-// Message represents a message in a chat (V2 format)
+// ChatScope is what a chat is about: a single lecture, or all lectures of a course. Exactly one
+// of the IDs is set.
+type ChatScope struct {
+	LectureID string
+	CourseID  string
+}
+
+// Scope returns what the chat is about.
+func (c *Chat) Scope() ChatScope {
+	if c.CourseID != nil {
+		return ChatScope{CourseID: *c.CourseID}
+	}
+	if c.LectureID != nil {
+		return ChatScope{LectureID: *c.LectureID}
+	}
+	return ChatScope{}
+}
+
+// Message represents a message in a chat (V2 format).
+// Messages form a tree: editing or regenerating a message adds a sibling under the same parent,
+// and the chat tracks which branch is active.
 type Message struct {
-	ID        string       `db:"id" json:"id"`
-	ChatID    string       `db:"chat_id" json:"chat_id"`
-	Role      string       `db:"role" json:"role"` // 'user' or 'assistant'
-	Parts     MessageParts `db:"parts" json:"parts"`
-	CreatedAt time.Time    `db:"created_at" json:"created_at"`
+	ID       string                 `db:"id" json:"id"`
+	ChatID   string                 `db:"chat_id" json:"chat_id"`
+	ParentID *string                `db:"parent_id" json:"parent_id"`
+	Role     string                 `db:"role" json:"role"` // 'user' or 'assistant'
+	Parts    MessageParts           `db:"parts" json:"parts"`
+	Metadata map[string]interface{} `db:"metadata" json:"metadata"`
+	// SiblingIDs lists the alternatives at this point of the conversation, including the message
+	// itself, oldest first. It is only populated when listing the active branch.
+	SiblingIDs []string  `db:"sibling_ids" json:"sibling_ids,omitempty"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
 }
 
+// Assistant replies record how their stream ended in the "status" metadata key, with the
+// cause under "error" when it did not complete.
+const (
+	MessageStatusComplete = "complete"
+	MessageStatusStopped  = "stopped" // the user stopped generation
+	MessageStatusAborted  = "aborted" // generation was canceled, e.g. by a shutdown
+	MessageStatusError    = "error"   // the Python service failed mid-stream
+)
+
 // MessageParts is an array of message parts (JSONB)
 type MessageParts []MessagePart
 
 // MessagePart represents a single part of a message
 type MessagePart struct {
-	Type      string         `json:"type"` // 'text' or 'data-reference'
+	Type      string         `json:"type"` // 'text', 'reasoning', 'data-reference', 'source-document', 'source-url' or 'dynamic-tool'
 	Text      string         `json:"text,omitempty"`
 	Reference *Reference     `json:"reference,omitempty"`
 	Data      *ReferencePart `json:"data,omitempty"`
+	// Source citations
+	SourceID  string `json:"sourceId,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Title     string `json:"title,omitempty"`
+	MediaType string `json:"mediaType,omitempty"`
+	Filename  string `json:"filename,omitempty"`
+	// Tool calls; State is 'input-available' until the output arrives, then 'output-available'
+	ToolCallID string      `json:"toolCallId,omitempty"`
+	ToolName   string      `json:"toolName,omitempty"`
+	State      string      `json:"state,omitempty"`
+	Input      interface{} `json:"input,omitempty"`
+	Output     interface{} `json:"output,omitempty"`
 }
 
 type ReferencePart struct {
@@ -43,6 +91,11 @@ type ReferencePart struct {
 	Reference *Reference `json:"reference,omitempty"`
 }
 
+// ReferenceTypeSlide references a slide by its slide number, within the lecture named by the
+// "lecture_id" metadata key, which defaults to the chat's lecture. Citations in assistant replies
+// always carry the lecture, and list the chunks retrieved from the slide under "chunk_ids".
+const ReferenceTypeSlide = "slide"
+
 // Reference represents a contextual reference in a message part
 type Reference struct {
 	Type     string         `json:"type"` // e.g., 'slide'
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// Chat export formats
+const (
+	ChatExportMarkdown = "md"
+	ChatExportHTML     = "html"
+	ChatExportJSON     = "json"
+)
+
+// ChatExport is a chat's active branch, ready to be rendered for download. It is also the
+// document served by JSON exports.
+type ChatExport struct {
+	ID           string          `json:"id"`
+	Title        string          `json:"title"`
+	LectureID    *string         `json:"lecture_id"`
+	LectureTitle *string         `json:"lecture_title"`
+	CourseID     *string         `json:"course_id"`
+	CourseTitle  *string         `json:"course_title"`
+	CreatedAt    time.Time       `json:"created_at"`
+	ExportedAt   time.Time       `json:"exported_at"`
+	Messages     []ExportMessage `json:"messages"`
+}
+
+// ExportMessage is a message of an export. Content is its text with slide references written
+// as readable citations, such as "[Slide 12]"; Parts keeps the original structure.
+type ExportMessage struct {
+	ID        string       `json:"id"`
+	Role      string       `json:"role"`
+	Model     string       `json:"model,omitempty"`
+	Status    string       `json:"status,omitempty"` // set on assistant replies
+	Content   string       `json:"content"`
+	Parts     MessageParts `json:"parts"`
+	CreatedAt time.Time    `json:"created_at"`
+}
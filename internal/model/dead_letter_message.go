@@ -4,12 +4,15 @@ import "time"
 
 // DeadLetterMessage represents a message from the dead-letter queue persisted in the database.
 type DeadLetterMessage struct {
-	ID               string    `db:"id"`
-	SubscriptionName string    `db:"subscription_name"`
-	MessageID        string    `db:"message_id"`
-	Payload          []byte    `db:"payload"`    // Should be a JSON byte slice
-	Attributes       []byte    `db:"attributes"` // Can be null, should be a JSON byte slice
-	Status           string    `db:"status"`
-	CreatedAt        time.Time `db:"created_at"`
-	UpdatedAt        time.Time `db:"updated_at"`
+	ID               string     `db:"id"`
+	SubscriptionName string     `db:"subscription_name"`
+	MessageID        string     `db:"message_id"`
+	Payload          []byte     `db:"payload"`    // Should be a JSON byte slice
+	Attributes       []byte     `db:"attributes"` // Can be null, should be a JSON byte slice
+	Status           string     `db:"status"`
+	ReplayCount      int        `db:"replay_count"`
+	LastReplayedAt   *time.Time `db:"last_replayed_at"` // Null until the message is replayed
+	LastReplayedBy   *string    `db:"last_replayed_by"` // User ID of the operator who last replayed the message
+	CreatedAt        time.Time  `db:"created_at"`
+	UpdatedAt        time.Time  `db:"updated_at"`
 }
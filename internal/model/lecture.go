@@ -7,20 +7,27 @@ import (
 	"time"
 )
 
-// Lecture represents the metadata for an uploaded PDF lecture.
+// Lecture represents the metadata for an uploaded lecture document (PDF, PPTX, DOCX or image).
 type Lecture struct {
 	ID                    string                `db:"id" json:"id"`           // UUID or unique string
 	UserID                string                `db:"user_id" json:"user_id"` // Supabase Auth user UUID
 	CourseID              string                `db:"course_id" json:"course_id"`
 	Title                 string                `db:"title" json:"title"`
 	StoragePath           string                `db:"storage_path" json:"storage_path"`
+	FileType              string                `db:"file_type" json:"file_type"` // Original format, e.g. "pdf", "pptx"
+	MimeType              string                `db:"mime_type" json:"mime_type"`
+	MultipartUploadID     *string               `db:"multipart_upload_id" json:"multipart_upload_id"`
 	Status                string                `db:"status" json:"status"` // e.g., "uploaded", "parsed", "explained"
 	EmbeddingErrorDetails EmbeddingErrorDetails `db:"embedding_error_details" json:"embedding_error_details"`
 	TotalSlides           int                   `db:"total_slides" json:"total_slides"`
+	TotalSubImages        int                   `db:"total_sub_images" json:"total_sub_images"`
+	ProcessedSubImages    int                   `db:"processed_sub_images" json:"processed_sub_images"`
 	EmbeddingsComplete    bool                  `db:"embeddings_complete" json:"embeddings_complete"`
 	CreatedAt             time.Time             `db:"created_at" json:"created_at"`
 	UpdatedAt             time.Time             `db:"updated_at" json:"updated_at"`
 	AccessedAt            time.Time             `db:"accessed_at" json:"accessed_at"`
+	ProcessingStartedAt   *time.Time            `db:"processing_started_at" json:"processing_started_at"`
+	CompletedAt           *time.Time            `db:"completed_at" json:"completed_at"`
 }
 
 // EmbeddingErrorDetails is a map for storing error details (JSONB)
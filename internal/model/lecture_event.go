@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// LectureEvent is a change notification published by the database triggers on the
+// 'lecture_events' channel. Type is "lecture" for processing updates, which carry the
+// lecture's current progress, or "chat_title" when one of its chats is renamed.
+type LectureEvent struct {
+	Type               string    `json:"type"`
+	LectureID          string    `json:"lecture_id"`
+	Status             string    `json:"status"`
+	TotalSlides        int       `json:"total_slides"`
+	TotalSubImages     int       `json:"total_sub_images"`
+	ProcessedSubImages int       `json:"processed_sub_images"`
+	EmbeddingsComplete bool      `json:"embeddings_complete"`
+	ChatID             string    `json:"chat_id"`
+	Title              string    `json:"title"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
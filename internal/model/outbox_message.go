@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// OutboxMessage is a Pub/Sub message recorded in the same transaction as the state change
+// that produced it, and published asynchronously by the outbox dispatcher.
+type OutboxMessage struct {
+	ID                 string            `db:"id"`
+	Topic              string            `db:"topic"`
+	Payload            []byte            `db:"payload"`    // JSON byte slice
+	Attributes         map[string]string `db:"attributes"` // Optional Pub/Sub message attributes
+	LectureID          *string           `db:"lecture_id"` // Lecture the message starts the ingestion of, if any
+	Status             string            `db:"status"`     // "pending", "published" or "failed"
+	Attempts           int               `db:"attempts"`
+	LastError          *string           `db:"last_error"`
+	AvailableAt        time.Time         `db:"available_at"` // Earliest time the next publish attempt may run
+	PublishedAt        *time.Time        `db:"published_at"`
+	PublishedMessageID *string           `db:"published_message_id"`
+	CreatedAt          time.Time         `db:"created_at"`
+	UpdatedAt          time.Time         `db:"updated_at"`
+}
@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// Kinds of search results
+const (
+	SearchResultCourse  = "course"
+	SearchResultLecture = "lecture"
+	SearchResultNote    = "note"
+	SearchResultMessage = "message"
+)
+
+// Search snippets mark matched terms with these private-use characters, which cannot clash with
+// markup in the user's own text.
+const (
+	SearchHighlightStart = "\uE000"
+	SearchHighlightStop  = "\uE001"
+)
+
+// SearchFilter narrows a search to one course or lecture. Empty fields do not filter.
+type SearchFilter struct {
+	CourseID  string
+	LectureID string
+}
+
+// SearchResult is a course, lecture, note or chat message matching a search, with the lecture and
+// chat it belongs to.
+type SearchResult struct {
+	Kind         string    `db:"kind" json:"kind"`
+	ID           string    `db:"id" json:"id"`
+	CourseID     string    `db:"course_id" json:"course_id"`
+	CourseTitle  string    `db:"course_title" json:"course_title"`
+	LectureID    *string   `db:"lecture_id" json:"lecture_id"`
+	LectureTitle *string   `db:"lecture_title" json:"lecture_title"`
+	ChatID       *string   `db:"chat_id" json:"chat_id"`
+	ChatTitle    *string   `db:"chat_title" json:"chat_title"`
+	Snippet      string    `db:"snippet" json:"snippet"`
+	Rank         float64   `db:"rank" json:"rank"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// ChunkMatch is a lecture chunk found by semantic search. Score is the cosine similarity between
+// the chunk and the query, from -1 to 1, higher being closer.
+type ChunkMatch struct {
+	ChunkID      string  `db:"chunk_id" json:"chunk_id"`
+	LectureID    string  `db:"lecture_id" json:"lecture_id"`
+	LectureTitle string  `db:"lecture_title" json:"lecture_title"`
+	CourseID     string  `db:"course_id" json:"course_id"`
+	SlideNumber  int     `db:"slide_number" json:"slide_number"`
+	Text         string  `db:"text" json:"text"`
+	Score        float64 `db:"score" json:"score"`
+}
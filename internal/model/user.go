@@ -19,6 +19,9 @@ type User struct {
 	UpdatedAt        time.Time        `db:"updated_at" json:"updated_at"`
 }
 
+// RoleAdmin lets a user operate the service through the /admin routes.
+const RoleAdmin = "admin"
+
 // APIKeysProvided is a map of provider names to boolean flags
 type APIKeysProvided map[string]bool
 
@@ -3,16 +3,60 @@ package pubsub
 import (
 	"context"
 	"fmt"
+	"maps"
 
 	"app/internal/config"
+	"app/internal/logger"
 
 	"cloud.google.com/go/pubsub"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/api/option"
 )
 
+// RequestIDAttribute carries the ID of the API request that produced a message, so that
+// consumers such as the ingestion worker can log it.
+const RequestIDAttribute = "request_id"
+
+// WithRequestID returns the attributes with the request ID of ctx added, unless there is none or
+// they already carry one. The given map is not modified.
+func WithRequestID(ctx context.Context, attributes map[string]string) map[string]string {
+	requestID := logger.RequestIDFromContext(ctx)
+	if requestID == "" || attributes[RequestIDAttribute] != "" {
+		return attributes
+	}
+	withID := copyAttributes(attributes)
+	withID[RequestIDAttribute] = requestID
+	return withID
+}
+
+// traceParentAttribute is the W3C trace context attribute set by the propagator.
+const traceParentAttribute = "traceparent"
+
+// WithTraceContext returns the attributes with the W3C trace context of ctx added, unless ctx
+// has no span or they already carry one. Messages published later, such as from the outbox,
+// then stay in the trace of the request that produced them. The given map is not modified.
+func WithTraceContext(ctx context.Context, attributes map[string]string) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsValid() || attributes[traceParentAttribute] != "" {
+		return attributes
+	}
+	withTrace := copyAttributes(attributes)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(withTrace))
+	return withTrace
+}
+
+func copyAttributes(attributes map[string]string) map[string]string {
+	copied := make(map[string]string, len(attributes)+2)
+	maps.Copy(copied, attributes)
+	return copied
+}
+
 // Publisher defines an interface for publishing messages.
 type Publisher interface {
-	Publish(ctx context.Context, topic string, payload []byte) (string, error)
+	Publish(ctx context.Context, topic string, payload []byte, attributes map[string]string) (string, error)
 }
 
 // PubSubPublisher is an implementation of Publisher using Google Pub/Sub.
@@ -54,12 +98,35 @@ func NewPublisher(ctx context.Context, cfg *config.Config) (*PubSubPublisher, er
 }
 
 // Publish sends the payload to the given Pub/Sub topic and returns the message ID.
-func (p *PubSubPublisher) Publish(ctx context.Context, topic string, payload []byte) (string, error) {
+// Attributes are optional and are carried over by Pub/Sub when the message is dead-lettered.
+// The request ID of ctx is added to them if they do not carry one yet, and the trace context
+// of the publish span replaces any they carry, so that consumers continue the trace.
+func (p *PubSubPublisher) Publish(ctx context.Context, topic string, payload []byte, attributes map[string]string) (string, error) {
+	// Messages from the outbox are published without a request, under the trace they were produced in
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(attributes))
+	}
+	ctx, span := otel.Tracer("app/internal/pubsub").Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemGCPPubsub,
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingDestinationName(topic),
+		),
+	)
+	defer span.End()
+
+	attributes = copyAttributes(WithRequestID(ctx, attributes))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(attributes))
+
 	t := p.client.Topic(topic)
-	result := t.Publish(ctx, &pubsub.Message{Data: payload})
+	result := t.Publish(ctx, &pubsub.Message{Data: payload, Attributes: attributes})
 	id, err := result.Get(ctx)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "publish failed")
 		return "", fmt.Errorf("failed to publish message to topic %s: %w", topic, err)
 	}
+	span.SetAttributes(semconv.MessagingMessageID(id))
 	return id, nil
 }
@@ -14,13 +14,22 @@ import (
 
 type ChatRepository interface {
 	CreateChat(ctx context.Context, lectureID, userID, title string) (*model.Chat, error)
+	CreateCourseChat(ctx context.Context, courseID, userID, title string) (*model.Chat, error)
 	GetChat(ctx context.Context, chatID, userID string) (*model.Chat, error)
 	ListChats(ctx context.Context, lectureID, userID string, limit, offset int) ([]model.Chat, error)
+	ListCourseChats(ctx context.Context, courseID, userID string, limit, offset int) ([]model.Chat, error)
 	UpdateChat(ctx context.Context, chatID, userID, title string) (*model.Chat, error)
 	DeleteChat(ctx context.Context, chatID, userID string) error
 	CreateMessage(ctx context.Context, chatID, role string, parts model.MessageParts, metadata map[string]interface{}) (*model.Message, error)
+	CreateBranchMessage(ctx context.Context, chatID string, parentID *string, role string, parts model.MessageParts, metadata map[string]interface{}) (*model.Message, error)
+	GetMessage(ctx context.Context, chatID, messageID string) (*model.Message, error)
 	ListMessages(ctx context.Context, chatID, userID string, limit int) ([]model.Message, error)
+	ListMessagePath(ctx context.Context, chatID, leafID string, limit int) ([]model.Message, error)
+	SetActiveBranch(ctx context.Context, chatID, messageID string) (bool, error)
 	GetMessageCount(ctx context.Context, chatID, userID string) (int, error)
+	// CountCompleteReplies counts the chat's assistant messages, on any branch, that finished
+	// streaming. Replies saved before statuses were recorded count as complete.
+	CountCompleteReplies(ctx context.Context, chatID string) (int, error)
 }
 
 type chatRepo struct {
@@ -35,12 +44,13 @@ func (r *chatRepo) CreateChat(ctx context.Context, lectureID, userID, title stri
 	query := `
 		INSERT INTO chats (lecture_id, user_id, title)
 		VALUES ($1, $2, $3)
-		RETURNING id, lecture_id, user_id, title, created_at, updated_at
+		RETURNING id, lecture_id, course_id, user_id, title, created_at, updated_at
 	`
 	var chat model.Chat
 	err := r.pool.QueryRow(ctx, query, lectureID, userID, title).Scan(
 		&chat.ID,
 		&chat.LectureID,
+		&chat.CourseID,
 		&chat.UserID,
 		&chat.Title,
 		&chat.CreatedAt,
@@ -52,9 +62,31 @@ func (r *chatRepo) CreateChat(ctx context.Context, lectureID, userID, title stri
 	return &chat, nil
 }
 
+func (r *chatRepo) CreateCourseChat(ctx context.Context, courseID, userID, title string) (*model.Chat, error) {
+	query := `
+		INSERT INTO chats (course_id, user_id, title)
+		VALUES ($1, $2, $3)
+		RETURNING id, lecture_id, course_id, user_id, title, created_at, updated_at
+	`
+	var chat model.Chat
+	err := r.pool.QueryRow(ctx, query, courseID, userID, title).Scan(
+		&chat.ID,
+		&chat.LectureID,
+		&chat.CourseID,
+		&chat.UserID,
+		&chat.Title,
+		&chat.CreatedAt,
+		&chat.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating course chat: %w", err)
+	}
+	return &chat, nil
+}
+
 func (r *chatRepo) GetChat(ctx context.Context, chatID, userID string) (*model.Chat, error) {
 	query := `
-		SELECT id, lecture_id, user_id, title, created_at, updated_at
+		SELECT id, lecture_id, course_id, user_id, title, created_at, updated_at
 		FROM chats
 		WHERE id = $1 AND user_id = $2
 	`
@@ -62,6 +94,7 @@ func (r *chatRepo) GetChat(ctx context.Context, chatID, userID string) (*model.C
 	err := r.pool.QueryRow(ctx, query, chatID, userID).Scan(
 		&chat.ID,
 		&chat.LectureID,
+		&chat.CourseID,
 		&chat.UserID,
 		&chat.Title,
 		&chat.CreatedAt,
@@ -78,7 +111,7 @@ func (r *chatRepo) GetChat(ctx context.Context, chatID, userID string) (*model.C
 
 func (r *chatRepo) ListChats(ctx context.Context, lectureID, userID string, limit, offset int) ([]model.Chat, error) {
 	query := fmt.Sprintf(`
-		SELECT id, lecture_id, user_id, title, created_at, updated_at
+		SELECT id, lecture_id, course_id, user_id, title, created_at, updated_at
 		FROM chats
 		WHERE lecture_id = $1 AND user_id = $2
 		ORDER BY updated_at DESC
@@ -97,6 +130,46 @@ func (r *chatRepo) ListChats(ctx context.Context, lectureID, userID string, limi
 		if err := rows.Scan(
 			&chat.ID,
 			&chat.LectureID,
+			&chat.CourseID,
+			&chat.UserID,
+			&chat.Title,
+			&chat.CreatedAt,
+			&chat.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning chat row: %w", err)
+		}
+		chats = append(chats, chat)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating chat rows: %w", err)
+	}
+
+	return chats, nil
+}
+
+func (r *chatRepo) ListCourseChats(ctx context.Context, courseID, userID string, limit, offset int) ([]model.Chat, error) {
+	query := fmt.Sprintf(`
+		SELECT id, lecture_id, course_id, user_id, title, created_at, updated_at
+		FROM chats
+		WHERE course_id = $1 AND user_id = $2
+		ORDER BY updated_at DESC
+		LIMIT %d OFFSET %d
+	`, limit, offset)
+
+	rows, err := r.pool.Query(ctx, query, courseID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying course chats: %w", err)
+	}
+	defer rows.Close()
+
+	var chats []model.Chat
+	for rows.Next() {
+		var chat model.Chat
+		if err := rows.Scan(
+			&chat.ID,
+			&chat.LectureID,
+			&chat.CourseID,
 			&chat.UserID,
 			&chat.Title,
 			&chat.CreatedAt,
@@ -119,12 +192,13 @@ func (r *chatRepo) UpdateChat(ctx context.Context, chatID, userID, title string)
 		UPDATE chats
 		SET title = $1, updated_at = NOW()
 		WHERE id = $2 AND user_id = $3
-		RETURNING id, lecture_id, user_id, title, created_at, updated_at
+		RETURNING id, lecture_id, course_id, user_id, title, created_at, updated_at
 	`
 	var chat model.Chat
 	err := r.pool.QueryRow(ctx, query, title, chatID, userID).Scan(
 		&chat.ID,
 		&chat.LectureID,
+		&chat.CourseID,
 		&chat.UserID,
 		&chat.Title,
 		&chat.CreatedAt,
@@ -154,47 +228,66 @@ func (r *chatRepo) DeleteChat(ctx context.Context, chatID, userID string) error
 	return nil
 }
 
+// CreateMessage appends a message to the end of the chat's active branch.
 func (r *chatRepo) CreateMessage(ctx context.Context, chatID, role string, parts model.MessageParts, metadata map[string]interface{}) (*model.Message, error) {
-	if parts == nil {
-		parts = make(model.MessageParts, 0)
-	}
-	partsJSON, err := json.Marshal(parts)
+	var message *model.Message
+	err := r.withActiveBranch(ctx, chatID, func(tx pgx.Tx, leafID *string) error {
+		var err error
+		message, err = insertMessage(ctx, tx, chatID, leafID, role, parts, metadata)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("marshaling message parts: %w", err)
+		return nil, err
 	}
+	return message, nil
+}
 
-	if metadata == nil {
-		metadata = make(map[string]interface{})
-	}
-	metadataJSON, err := json.Marshal(metadata)
+// CreateBranchMessage adds a message under parentID, or as a new first message when parentID is nil,
+// and makes it the end of the active branch. Any existing children of parentID are kept as siblings.
+func (r *chatRepo) CreateBranchMessage(ctx context.Context, chatID string, parentID *string, role string, parts model.MessageParts, metadata map[string]interface{}) (*model.Message, error) {
+	var message *model.Message
+	err := r.withActiveBranch(ctx, chatID, func(tx pgx.Tx, _ *string) error {
+		var err error
+		message, err = insertMessage(ctx, tx, chatID, parentID, role, parts, metadata)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("marshaling message metadata: %w", err)
+		return nil, err
 	}
+	return message, nil
+}
 
+func (r *chatRepo) GetMessage(ctx context.Context, chatID, messageID string) (*model.Message, error) {
 	query := `
-		INSERT INTO messages (chat_id, role, parts, metadata)
-		VALUES ($1, $2, $3::jsonb, $4::jsonb)
-		RETURNING id, chat_id, role, parts, created_at
+		SELECT id, chat_id, parent_id, role, parts, metadata, created_at
+		FROM messages
+		WHERE id = $1 AND chat_id = $2
 	`
 	var message model.Message
-	err = r.pool.QueryRow(ctx, query, chatID, role, string(partsJSON), string(metadataJSON)).Scan(
+	err := r.pool.QueryRow(ctx, query, messageID, chatID).Scan(
 		&message.ID,
 		&message.ChatID,
+		&message.ParentID,
 		&message.Role,
 		&message.Parts,
+		&message.Metadata,
 		&message.CreatedAt,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("creating message: %w", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting message %s: %w", messageID, err)
 	}
 	return &message, nil
 }
 
+// ListMessages returns the last limit messages of the chat's active branch, oldest first.
 func (r *chatRepo) ListMessages(ctx context.Context, chatID, userID string, limit int) ([]model.Message, error) {
 	// Verify chat ownership first
-	chatQuery := `SELECT id FROM chats WHERE id = $1 AND user_id = $2`
-	var chatIDCheck string
-	err := r.pool.QueryRow(ctx, chatQuery, chatID, userID).Scan(&chatIDCheck)
+	chatQuery := `SELECT active_message_id FROM chats WHERE id = $1 AND user_id = $2`
+	var leafID *string
+	err := r.pool.QueryRow(ctx, chatQuery, chatID, userID).Scan(&leafID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, fmt.Errorf("chat not found or access denied")
@@ -202,9 +295,77 @@ func (r *chatRepo) ListMessages(ctx context.Context, chatID, userID string, limi
 		return nil, fmt.Errorf("verifying chat ownership: %w", err)
 	}
 
+	if leafID == nil {
+		return r.listLegacyMessages(ctx, chatID, limit)
+	}
+	return r.ListMessagePath(ctx, chatID, *leafID, limit)
+}
+
+// ListMessagePath returns the last limit messages on the branch ending at leafID, oldest first.
+func (r *chatRepo) ListMessagePath(ctx context.Context, chatID, leafID string, limit int) ([]model.Message, error) {
+	query := fmt.Sprintf(`
+		WITH RECURSIVE path AS (
+			SELECT id, chat_id, parent_id, role, parts, metadata, created_at, 0 AS depth
+			FROM messages
+			WHERE id = $1 AND chat_id = $2
+			UNION ALL
+			SELECT m.id, m.chat_id, m.parent_id, m.role, m.parts, m.metadata, m.created_at, p.depth + 1
+			FROM messages m
+			JOIN path p ON m.id = p.parent_id
+		)
+		SELECT p.id, p.chat_id, p.parent_id, p.role, p.parts, p.metadata, p.created_at,
+			ARRAY(
+				SELECT s.id::text
+				FROM messages s
+				WHERE s.chat_id = p.chat_id AND s.parent_id IS NOT DISTINCT FROM p.parent_id
+				ORDER BY s.created_at, s.id
+			) AS sibling_ids
+		FROM path p
+		ORDER BY p.depth
+		LIMIT %d
+	`, limit)
+
+	rows, err := r.pool.Query(ctx, query, leafID, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("querying message path: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []model.Message
+	for rows.Next() {
+		var message model.Message
+		if err := rows.Scan(
+			&message.ID,
+			&message.ChatID,
+			&message.ParentID,
+			&message.Role,
+			&message.Parts,
+			&message.Metadata,
+			&message.CreatedAt,
+			&message.SiblingIDs,
+		); err != nil {
+			return nil, fmt.Errorf("scanning message path row: %w", err)
+		}
+		messages = append(messages, message)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating message path rows: %w", err)
+	}
+
+	// The path is walked from the leaf up, so reverse it to get chronological order
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, nil
+}
+
+// listLegacyMessages lists a chat that has never been branched, whose messages are linear by creation time.
+func (r *chatRepo) listLegacyMessages(ctx context.Context, chatID string, limit int) ([]model.Message, error) {
 	// Fetch the latest messages (ordered DESC, then reverse to get oldest first)
 	query := fmt.Sprintf(`
-		SELECT id, chat_id, role, parts, created_at
+		SELECT id, chat_id, parent_id, role, parts, metadata, created_at
 		FROM messages
 		WHERE chat_id = $1
 		ORDER BY created_at DESC
@@ -223,8 +384,10 @@ func (r *chatRepo) ListMessages(ctx context.Context, chatID, userID string, limi
 		if err := rows.Scan(
 			&message.ID,
 			&message.ChatID,
+			&message.ParentID,
 			&message.Role,
 			&message.Parts,
+			&message.Metadata,
 			&message.CreatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("scanning message row: %w", err)
@@ -244,6 +407,154 @@ func (r *chatRepo) ListMessages(ctx context.Context, chatID, userID string, limi
 	return messages, nil
 }
 
+// SetActiveBranch switches the chat to the branch containing messageID, continuing down to its
+// most recent descendant. It reports false when the message does not belong to the chat.
+func (r *chatRepo) SetActiveBranch(ctx context.Context, chatID, messageID string) (bool, error) {
+	var found bool
+	err := r.withActiveBranch(ctx, chatID, func(tx pgx.Tx, _ *string) error {
+		query := `
+			WITH RECURSIVE descendants AS (
+				SELECT id, created_at
+				FROM messages
+				WHERE id = $1 AND chat_id = $2
+				UNION ALL
+				SELECT m.id, m.created_at
+				FROM messages m
+				JOIN descendants d ON m.parent_id = d.id
+			)
+			SELECT id::text
+			FROM descendants
+			ORDER BY created_at DESC, id DESC
+			LIMIT 1
+		`
+		var leafID string
+		if err := tx.QueryRow(ctx, query, messageID, chatID).Scan(&leafID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return fmt.Errorf("finding latest message under %s: %w", messageID, err)
+		}
+		found = true
+		return setActiveMessage(ctx, tx, chatID, leafID)
+	})
+	if err != nil {
+		return false, err
+	}
+	return found, nil
+}
+
+// withActiveBranch runs fn in a transaction holding a lock on the chat, passing the end of its
+// active branch. Whatever message fn inserts last becomes the new end of the branch.
+// Chats that predate branching are linked into a single branch first.
+func (r *chatRepo) withActiveBranch(ctx context.Context, chatID string, fn func(tx pgx.Tx, leafID *string) error) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning chat transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var leafID *string
+	if err := tx.QueryRow(ctx, `SELECT active_message_id FROM chats WHERE id = $1 FOR UPDATE`, chatID).Scan(&leafID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("chat not found")
+		}
+		return fmt.Errorf("locking chat %s: %w", chatID, err)
+	}
+	if leafID == nil {
+		if leafID, err = linkLegacyMessages(ctx, tx, chatID); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(tx, leafID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing chat transaction: %w", err)
+	}
+	return nil
+}
+
+// linkLegacyMessages chains a chat's unbranched messages by creation time, makes the newest one
+// the active branch, and returns it. It returns nil for a chat with no messages.
+func linkLegacyMessages(ctx context.Context, tx pgx.Tx, chatID string) (*string, error) {
+	query := `
+		UPDATE messages m
+		SET parent_id = ordered.previous_id
+		FROM (
+			SELECT id, LAG(id) OVER (ORDER BY created_at, id) AS previous_id
+			FROM messages
+			WHERE chat_id = $1
+		) ordered
+		WHERE m.id = ordered.id AND m.parent_id IS NULL AND ordered.previous_id IS NOT NULL
+	`
+	if _, err := tx.Exec(ctx, query, chatID); err != nil {
+		return nil, fmt.Errorf("linking messages of chat %s: %w", chatID, err)
+	}
+
+	var leafID string
+	err := tx.QueryRow(ctx, `SELECT id::text FROM messages WHERE chat_id = $1 ORDER BY created_at DESC, id DESC LIMIT 1`, chatID).Scan(&leafID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("finding latest message of chat %s: %w", chatID, err)
+	}
+	if err := setActiveMessage(ctx, tx, chatID, leafID); err != nil {
+		return nil, err
+	}
+	return &leafID, nil
+}
+
+func setActiveMessage(ctx context.Context, q querier, chatID, messageID string) error {
+	if _, err := q.Exec(ctx, `UPDATE chats SET active_message_id = $1 WHERE id = $2`, messageID, chatID); err != nil {
+		return fmt.Errorf("setting active message of chat %s: %w", chatID, err)
+	}
+	return nil
+}
+
+// insertMessage adds a message under parentID and makes it the end of the chat's active branch.
+func insertMessage(ctx context.Context, q querier, chatID string, parentID *string, role string, parts model.MessageParts, metadata map[string]interface{}) (*model.Message, error) {
+	if parts == nil {
+		parts = make(model.MessageParts, 0)
+	}
+	partsJSON, err := json.Marshal(parts)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling message parts: %w", err)
+	}
+
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling message metadata: %w", err)
+	}
+
+	query := `
+		INSERT INTO messages (chat_id, parent_id, role, parts, metadata)
+		VALUES ($1, $2, $3, $4::jsonb, $5::jsonb)
+		RETURNING id, chat_id, parent_id, role, parts, metadata, created_at
+	`
+	var message model.Message
+	err = q.QueryRow(ctx, query, chatID, parentID, role, string(partsJSON), string(metadataJSON)).Scan(
+		&message.ID,
+		&message.ChatID,
+		&message.ParentID,
+		&message.Role,
+		&message.Parts,
+		&message.Metadata,
+		&message.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating message: %w", err)
+	}
+	if err := setActiveMessage(ctx, q, chatID, message.ID); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
 func (r *chatRepo) GetMessageCount(ctx context.Context, chatID, userID string) (int, error) {
 	// Verify chat ownership first
 	chatQuery := `SELECT id FROM chats WHERE id = $1 AND user_id = $2`
@@ -265,3 +576,16 @@ func (r *chatRepo) GetMessageCount(ctx context.Context, chatID, userID string) (
 	}
 	return count, nil
 }
+
+func (r *chatRepo) CountCompleteReplies(ctx context.Context, chatID string) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM messages
+		WHERE chat_id = $1 AND role = 'assistant' AND COALESCE(metadata->>'status', $2) = $2
+	`
+	var count int
+	if err := r.pool.QueryRow(ctx, query, chatID, model.MessageStatusComplete).Scan(&count); err != nil {
+		return 0, fmt.Errorf("counting complete replies of chat %s: %w", chatID, err)
+	}
+	return count, nil
+}
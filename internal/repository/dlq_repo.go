@@ -2,15 +2,34 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"app/internal/model"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// DLQListFilter narrows down the dead-letter messages returned by List.
+// Zero values are ignored.
+type DLQListFilter struct {
+	SubscriptionName string
+	Status           string
+	CreatedFrom      *time.Time
+	CreatedTo        *time.Time
+}
+
 type DLQRepository interface {
-	Create(ctx context.Context, message *model.DeadLetterMessage) error
+	// Create saves the message and reports whether it was new. A message the subscription has
+	// already delivered is not saved again, and Create reports false.
+	Create(ctx context.Context, message *model.DeadLetterMessage) (bool, error)
+	List(ctx context.Context, filter DLQListFilter, limit, offset int) ([]model.DeadLetterMessage, error)
+	GetByID(ctx context.Context, id string) (*model.DeadLetterMessage, error)
+	UpdateStatus(ctx context.Context, id, status string) (*model.DeadLetterMessage, error)
+	RecordReplay(ctx context.Context, id, replayedBy string) (*model.DeadLetterMessage, error)
 }
 
 type dlqRepository struct {
@@ -21,12 +40,16 @@ func NewDLQRepository(pool *pgxpool.Pool) DLQRepository {
 	return &dlqRepository{pool: pool}
 }
 
-func (r *dlqRepository) Create(ctx context.Context, message *model.DeadLetterMessage) error {
+const dlqColumns = `id, subscription_name, message_id, payload, attributes, status, replay_count, last_replayed_at, last_replayed_by, created_at, updated_at`
+
+func (r *dlqRepository) Create(ctx context.Context, message *model.DeadLetterMessage) (bool, error) {
 	query := `
         INSERT INTO dead_letter_messages (subscription_name, message_id, payload, attributes, status)
         VALUES ($1, $2, $3::jsonb, $4::jsonb, $5)
+        ON CONFLICT (subscription_name, message_id) DO NOTHING
+        RETURNING id, created_at, updated_at
     `
-	_, err := r.pool.Exec(
+	err := r.pool.QueryRow(
 		ctx,
 		query,
 		message.SubscriptionName,
@@ -34,9 +57,139 @@ func (r *dlqRepository) Create(ctx context.Context, message *model.DeadLetterMes
 		string(message.Payload),
 		string(message.Attributes),
 		message.Status,
-	)
+	).Scan(&message.ID, &message.CreatedAt, &message.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("creating dead letter message for subscription %s: %w", message.SubscriptionName, err)
+	}
+	return true, nil
+}
+
+func (r *dlqRepository) List(ctx context.Context, filter DLQListFilter, limit, offset int) ([]model.DeadLetterMessage, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.SubscriptionName != "" {
+		args = append(args, filter.SubscriptionName)
+		conditions = append(conditions, fmt.Sprintf("subscription_name = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d::dlq_message_status", len(args)))
+	}
+	if filter.CreatedFrom != nil {
+		args = append(args, *filter.CreatedFrom)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.CreatedTo != nil {
+		args = append(args, *filter.CreatedTo)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM dead_letter_messages
+		%s
+		ORDER BY created_at DESC
+		LIMIT %d OFFSET %d
+	`, dlqColumns, where, limit, offset)
+
+	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("creating dead letter message for subscription %s: %w", message.SubscriptionName, err)
+		return nil, fmt.Errorf("querying dead letter messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []model.DeadLetterMessage
+	for rows.Next() {
+		message, err := scanDeadLetterMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning dead letter message row: %w", err)
+		}
+		messages = append(messages, *message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating dead letter message rows: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (r *dlqRepository) GetByID(ctx context.Context, id string) (*model.DeadLetterMessage, error) {
+	query := fmt.Sprintf(`SELECT %s FROM dead_letter_messages WHERE id = $1`, dlqColumns)
+	message, err := scanDeadLetterMessage(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting dead letter message %s: %w", id, err)
+	}
+	return message, nil
+}
+
+func (r *dlqRepository) UpdateStatus(ctx context.Context, id, status string) (*model.DeadLetterMessage, error) {
+	query := fmt.Sprintf(`
+		UPDATE dead_letter_messages
+		SET status = $1::dlq_message_status, updated_at = NOW()
+		WHERE id = $2
+		RETURNING %s
+	`, dlqColumns)
+	message, err := scanDeadLetterMessage(r.pool.QueryRow(ctx, query, status, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("updating status of dead letter message %s: %w", id, err)
+	}
+	return message, nil
+}
+
+// RecordReplay stamps the replay audit columns and marks the message as processed,
+// since its payload has been handed back to the source topic.
+func (r *dlqRepository) RecordReplay(ctx context.Context, id, replayedBy string) (*model.DeadLetterMessage, error) {
+	query := fmt.Sprintf(`
+		UPDATE dead_letter_messages
+		SET status = 'processed',
+			replay_count = replay_count + 1,
+			last_replayed_at = NOW(),
+			last_replayed_by = $1,
+			updated_at = NOW()
+		WHERE id = $2
+		RETURNING %s
+	`, dlqColumns)
+	message, err := scanDeadLetterMessage(r.pool.QueryRow(ctx, query, replayedBy, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("recording replay of dead letter message %s: %w", id, err)
+	}
+	return message, nil
+}
+
+func scanDeadLetterMessage(row pgx.Row) (*model.DeadLetterMessage, error) {
+	var message model.DeadLetterMessage
+	if err := row.Scan(
+		&message.ID,
+		&message.SubscriptionName,
+		&message.MessageID,
+		&message.Payload,
+		&message.Attributes,
+		&message.Status,
+		&message.ReplayCount,
+		&message.LastReplayedAt,
+		&message.LastReplayedBy,
+		&message.CreatedAt,
+		&message.UpdatedAt,
+	); err != nil {
+		return nil, err
 	}
-	return nil
+	return &message, nil
 }
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// lectureEventChannel is the NOTIFY channel the lecture and chat triggers publish to.
+const lectureEventChannel = "lecture_events"
+
+type LectureEventListener interface {
+	// Listen blocks, passing the payload of every lecture event notification to onNotification,
+	// until ctx is cancelled or the connection fails.
+	Listen(ctx context.Context, onNotification func(payload string)) error
+}
+
+// lectureEventListener holds its own connection rather than using the pool, because LISTEN
+// needs a long-lived session, which transaction poolers do not provide.
+type lectureEventListener struct {
+	connString string
+}
+
+func NewLectureEventListener(connString string) LectureEventListener {
+	return &lectureEventListener{connString: connString}
+}
+
+func (l *lectureEventListener) Listen(ctx context.Context, onNotification func(payload string)) error {
+	conn, err := pgx.Connect(ctx, l.connString)
+	if err != nil {
+		return fmt.Errorf("connecting to listen for lecture events: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+lectureEventChannel); err != nil {
+		return fmt.Errorf("listening on %s: %w", lectureEventChannel, err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("waiting for lecture events: %w", err)
+		}
+		onNotification(notification.Payload)
+	}
+}
@@ -2,8 +2,11 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"app/internal/model"
 
@@ -11,14 +14,46 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// LectureListFilter narrows down the lectures returned by ListLectures.
+// Zero values are ignored.
+type LectureListFilter struct {
+	UserID string
+	Status string
+}
+
 type LectureRepository interface {
 	GetLecturesByUserID(ctx context.Context, userID string, limit, offset int) ([]model.Lecture, error)
 	GetLecturesByCourseID(ctx context.Context, courseID string, limit, offset int) ([]model.Lecture, error)
 	GetLectureByID(ctx context.Context, lectureID string) (*model.Lecture, error)
 	DeleteLecture(ctx context.Context, lectureID string) error
 	UpdateLecture(ctx context.Context, l *model.Lecture) error
+	UpdateLectureStatus(ctx context.Context, lectureID, status string, errorDetails model.EmbeddingErrorDetails) error
+	UpdateLectureStatusWithOutbox(ctx context.Context, lectureID, status string, errorDetails model.EmbeddingErrorDetails, message *model.OutboxMessage) error
+	ClaimLectureForReprocessing(ctx context.Context, lectureID string, staleBefore time.Time, message *model.OutboxMessage) (bool, error)
+	ClaimUploadedLecture(ctx context.Context, lectureID string, message *model.OutboxMessage) (bool, error)
+	SetMultipartUploadID(ctx context.Context, lectureID string, uploadID *string) error
+	ClearMultipartUploadID(ctx context.Context, uploadID string) error
+	GetStaleUploadingLectures(ctx context.Context, updatedBefore time.Time, limit int) ([]model.Lecture, error)
+	// DeleteStaleUploadingLecture deletes the lecture only while it is still uploading and has not
+	// changed since updatedBefore. It returns the storage path and multipart upload of the deleted
+	// lecture, or nil when the lecture no longer qualifies and nothing was deleted.
+	DeleteStaleUploadingLecture(ctx context.Context, lectureID string, updatedBefore time.Time) (*model.Lecture, error)
+	// TouchUploadingLecture bumps updated_at of a lecture that is still uploading, marking the upload as active.
+	TouchUploadingLecture(ctx context.Context, lectureID string) error
+	GetInFlightLecturesByUserID(ctx context.Context, userID string) ([]model.Lecture, error)
+	// GetLectureIDsByCourseID returns the IDs of every lecture in the course, oldest first.
+	GetLectureIDsByCourseID(ctx context.Context, courseID string) ([]string, error)
+	// GetAverageProcessingDuration averages the processing time of the sampleSize most recently
+	// completed lectures with between minSlides and maxSlides slides. It also returns how many
+	// lectures the average was taken over, which is zero when there is no history.
+	GetAverageProcessingDuration(ctx context.Context, minSlides, maxSlides, sampleSize int) (time.Duration, int, error)
+	// SlideHasChunks reports whether the lecture has the slide and every one of chunkIDs belongs to it.
+	SlideHasChunks(ctx context.Context, lectureID string, slideNumber int, chunkIDs []string) (bool, error)
 	CreateLecture(ctx context.Context, lecture *model.Lecture) (*model.Lecture, error)
 	CountLecturesByUserID(ctx context.Context, userID string) (int, error)
+	// ListLectures returns lectures of any user matching the filter with their error details,
+	// most recently updated first. It is meant for operators.
+	ListLectures(ctx context.Context, filter LectureListFilter, limit, offset int) ([]model.Lecture, error)
 }
 
 type lectureRepository struct {
@@ -31,7 +66,7 @@ func NewLectureRepository(pool *pgxpool.Pool) LectureRepository {
 
 func (r *lectureRepository) GetLecturesByUserID(ctx context.Context, userID string, limit, offset int) ([]model.Lecture, error) {
 	query := fmt.Sprintf(`
-		SELECT id, user_id, course_id, title, storage_path, status, total_slides, embeddings_complete, created_at, updated_at, accessed_at
+		SELECT id, user_id, course_id, title, storage_path, file_type, mime_type, status, total_slides, total_sub_images, processed_sub_images, embeddings_complete, created_at, updated_at, accessed_at, processing_started_at, completed_at
 		FROM lectures
 		WHERE user_id = $1
 		ORDER BY accessed_at DESC
@@ -53,12 +88,18 @@ func (r *lectureRepository) GetLecturesByUserID(ctx context.Context, userID stri
 			&lecture.CourseID,
 			&lecture.Title,
 			&lecture.StoragePath,
+			&lecture.FileType,
+			&lecture.MimeType,
 			&lecture.Status,
 			&lecture.TotalSlides,
+			&lecture.TotalSubImages,
+			&lecture.ProcessedSubImages,
 			&lecture.EmbeddingsComplete,
 			&lecture.CreatedAt,
 			&lecture.UpdatedAt,
 			&lecture.AccessedAt,
+			&lecture.ProcessingStartedAt,
+			&lecture.CompletedAt,
 		); err != nil {
 			return nil, fmt.Errorf("scanning lecture row: %w", err)
 		}
@@ -74,7 +115,7 @@ func (r *lectureRepository) GetLecturesByUserID(ctx context.Context, userID stri
 
 func (r *lectureRepository) GetLecturesByCourseID(ctx context.Context, courseID string, limit, offset int) ([]model.Lecture, error) {
 	query := fmt.Sprintf(`
-		SELECT id, user_id, course_id, title, storage_path, status, total_slides, embeddings_complete, created_at, updated_at, accessed_at
+		SELECT id, user_id, course_id, title, storage_path, file_type, mime_type, status, total_slides, total_sub_images, processed_sub_images, embeddings_complete, created_at, updated_at, accessed_at, processing_started_at, completed_at
 		FROM lectures
 		WHERE course_id = $1
 		ORDER BY accessed_at DESC
@@ -96,12 +137,18 @@ func (r *lectureRepository) GetLecturesByCourseID(ctx context.Context, courseID
 			&lecture.CourseID,
 			&lecture.Title,
 			&lecture.StoragePath,
+			&lecture.FileType,
+			&lecture.MimeType,
 			&lecture.Status,
 			&lecture.TotalSlides,
+			&lecture.TotalSubImages,
+			&lecture.ProcessedSubImages,
 			&lecture.EmbeddingsComplete,
 			&lecture.CreatedAt,
 			&lecture.UpdatedAt,
 			&lecture.AccessedAt,
+			&lecture.ProcessingStartedAt,
+			&lecture.CompletedAt,
 		); err != nil {
 			return nil, fmt.Errorf("scanning lecture row for course: %w", err)
 		}
@@ -117,7 +164,7 @@ func (r *lectureRepository) GetLecturesByCourseID(ctx context.Context, courseID
 
 func (r *lectureRepository) GetLectureByID(ctx context.Context, lectureID string) (*model.Lecture, error) {
 	query := `
-		SELECT id, user_id, course_id, title, storage_path, status, embedding_error_details, total_slides, embeddings_complete, created_at, updated_at, accessed_at
+		SELECT id, user_id, course_id, title, storage_path, file_type, mime_type, multipart_upload_id, status, embedding_error_details, total_slides, total_sub_images, processed_sub_images, embeddings_complete, created_at, updated_at, accessed_at, processing_started_at, completed_at
 		FROM lectures
 		WHERE id = $1
 	`
@@ -128,13 +175,20 @@ func (r *lectureRepository) GetLectureByID(ctx context.Context, lectureID string
 		&lecture.CourseID,
 		&lecture.Title,
 		&lecture.StoragePath,
+		&lecture.FileType,
+		&lecture.MimeType,
+		&lecture.MultipartUploadID,
 		&lecture.Status,
 		&lecture.EmbeddingErrorDetails,
 		&lecture.TotalSlides,
+		&lecture.TotalSubImages,
+		&lecture.ProcessedSubImages,
 		&lecture.EmbeddingsComplete,
 		&lecture.CreatedAt,
 		&lecture.UpdatedAt,
 		&lecture.AccessedAt,
+		&lecture.ProcessingStartedAt,
+		&lecture.CompletedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -158,7 +212,7 @@ func (r *lectureRepository) UpdateLecture(ctx context.Context, l *model.Lecture)
 		UPDATE lectures
 		SET title = $1, accessed_at = $2, storage_path = $3, status = $4, course_id = $5, embeddings_complete = $6, updated_at = NOW()
 		WHERE id = $7
-		RETURNING user_id, course_id, title, storage_path, status, total_slides, embeddings_complete, created_at, updated_at, accessed_at
+		RETURNING user_id, course_id, title, storage_path, file_type, mime_type, status, total_slides, embeddings_complete, created_at, updated_at, accessed_at
 	`
 	err := r.pool.QueryRow(ctx, query,
 		l.Title, l.AccessedAt, l.StoragePath, l.Status, l.CourseID, l.EmbeddingsComplete, l.ID,
@@ -167,6 +221,8 @@ func (r *lectureRepository) UpdateLecture(ctx context.Context, l *model.Lecture)
 		&l.CourseID,
 		&l.Title,
 		&l.StoragePath,
+		&l.FileType,
+		&l.MimeType,
 		&l.Status,
 		&l.TotalSlides,
 		&l.EmbeddingsComplete,
@@ -180,9 +236,329 @@ func (r *lectureRepository) UpdateLecture(ctx context.Context, l *model.Lecture)
 	return nil
 }
 
+// UpdateLectureStatus sets the lecture status and replaces its error details.
+// Passing nil errorDetails clears any previously recorded error.
+func (r *lectureRepository) UpdateLectureStatus(ctx context.Context, lectureID, status string, errorDetails model.EmbeddingErrorDetails) error {
+	return updateLectureStatus(ctx, r.pool, lectureID, status, errorDetails)
+}
+
+// UpdateLectureStatusWithOutbox updates the lecture status and enqueues the outbox message in a
+// single transaction, so the message is published if and only if the status change is committed.
+func (r *lectureRepository) UpdateLectureStatusWithOutbox(ctx context.Context, lectureID, status string, errorDetails model.EmbeddingErrorDetails, message *model.OutboxMessage) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction for lecture %s: %w", lectureID, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := updateLectureStatus(ctx, tx, lectureID, status, errorDetails); err != nil {
+		return err
+	}
+	if err := insertOutboxMessage(ctx, tx, message); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing status update of lecture %s: %w", lectureID, err)
+	}
+	return nil
+}
+
+// ClaimLectureForReprocessing atomically moves a lecture back to pending_processing, clears
+// its error details and enqueues the outbox message. Only failed lectures, or in-flight lectures
+// that have not progressed since staleBefore, can be claimed; it reports false when the lecture
+// is not eligible, in which case nothing is enqueued. Progress is judged by updated_at, which
+// the schema's triggers keep current while the pipeline works on the lecture.
+func (r *lectureRepository) ClaimLectureForReprocessing(ctx context.Context, lectureID string, staleBefore time.Time, message *model.OutboxMessage) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("beginning transaction for lecture %s: %w", lectureID, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE lectures
+		SET status = 'pending_processing', embedding_error_details = NULL, updated_at = NOW()
+		WHERE id = $1
+		  AND (
+			status = 'failed'
+			OR (status IN ('pending_processing', 'parsing', 'processing') AND updated_at < $2)
+		  )
+	`
+	result, err := tx.Exec(ctx, query, lectureID, staleBefore)
+	if err != nil {
+		return false, fmt.Errorf("claiming lecture %s for reprocessing: %w", lectureID, err)
+	}
+	if result.RowsAffected() != 1 {
+		return false, nil
+	}
+	if err := insertOutboxMessage(ctx, tx, message); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("committing reprocessing claim for lecture %s: %w", lectureID, err)
+	}
+	return true, nil
+}
+
+// ClaimUploadedLecture atomically moves a lecture that is still uploading to pending_processing
+// and enqueues the outbox message. It reports false when the lecture is past the upload, in which
+// case nothing is enqueued, so a repeated upload completion cannot queue a second ingestion job.
+func (r *lectureRepository) ClaimUploadedLecture(ctx context.Context, lectureID string, message *model.OutboxMessage) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("beginning transaction for lecture %s: %w", lectureID, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE lectures
+		SET status = 'pending_processing', embedding_error_details = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'uploading'
+	`
+	result, err := tx.Exec(ctx, query, lectureID)
+	if err != nil {
+		return false, fmt.Errorf("claiming uploaded lecture %s: %w", lectureID, err)
+	}
+	if result.RowsAffected() != 1 {
+		return false, nil
+	}
+	if err := insertOutboxMessage(ctx, tx, message); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("committing upload completion of lecture %s: %w", lectureID, err)
+	}
+	return true, nil
+}
+
+// SetMultipartUploadID records (or, with a nil uploadID, clears) the lecture's active multipart upload.
+func (r *lectureRepository) SetMultipartUploadID(ctx context.Context, lectureID string, uploadID *string) error {
+	query := `UPDATE lectures SET multipart_upload_id = $1, updated_at = NOW() WHERE id = $2`
+	if _, err := r.pool.Exec(ctx, query, uploadID, lectureID); err != nil {
+		return fmt.Errorf("setting multipart upload of lecture %s: %w", lectureID, err)
+	}
+	return nil
+}
+
+func (r *lectureRepository) TouchUploadingLecture(ctx context.Context, lectureID string) error {
+	query := `UPDATE lectures SET updated_at = NOW() WHERE id = $1 AND status = 'uploading'`
+	if _, err := r.pool.Exec(ctx, query, lectureID); err != nil {
+		return fmt.Errorf("touching uploading lecture %s: %w", lectureID, err)
+	}
+	return nil
+}
+
+// ClearMultipartUploadID detaches an aborted multipart upload from whichever lecture references it.
+func (r *lectureRepository) ClearMultipartUploadID(ctx context.Context, uploadID string) error {
+	query := `UPDATE lectures SET multipart_upload_id = NULL, updated_at = NOW() WHERE multipart_upload_id = $1`
+	if _, err := r.pool.Exec(ctx, query, uploadID); err != nil {
+		return fmt.Errorf("clearing multipart upload %s: %w", uploadID, err)
+	}
+	return nil
+}
+
+func updateLectureStatus(ctx context.Context, q querier, lectureID, status string, errorDetails model.EmbeddingErrorDetails) error {
+	var detailsJSON *string
+	if errorDetails != nil {
+		b, err := json.Marshal(errorDetails)
+		if err != nil {
+			return fmt.Errorf("marshaling error details for lecture %s: %w", lectureID, err)
+		}
+		str := string(b)
+		detailsJSON = &str
+	}
+	query := `
+		UPDATE lectures
+		SET status = $1, embedding_error_details = $2::jsonb, updated_at = NOW()
+		WHERE id = $3
+	`
+	if _, err := q.Exec(ctx, query, status, detailsJSON, lectureID); err != nil {
+		return fmt.Errorf("updating status of lecture %s: %w", lectureID, err)
+	}
+	return nil
+}
+
+// GetStaleUploadingLectures returns lectures still in 'uploading' that have not changed since
+// updatedBefore, oldest first.
+func (r *lectureRepository) GetStaleUploadingLectures(ctx context.Context, updatedBefore time.Time, limit int) ([]model.Lecture, error) {
+	query := fmt.Sprintf(`
+		SELECT id, user_id, course_id, title, storage_path, file_type, mime_type, status, total_slides, embeddings_complete, created_at, updated_at, accessed_at
+		FROM lectures
+		WHERE status = 'uploading' AND updated_at < $1
+		ORDER BY updated_at
+		LIMIT %d
+	`, limit)
+
+	rows, err := r.pool.Query(ctx, query, updatedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("querying stale uploading lectures: %w", err)
+	}
+	defer rows.Close()
+
+	var lectures []model.Lecture
+	for rows.Next() {
+		var lecture model.Lecture
+		if err := rows.Scan(
+			&lecture.ID,
+			&lecture.UserID,
+			&lecture.CourseID,
+			&lecture.Title,
+			&lecture.StoragePath,
+			&lecture.FileType,
+			&lecture.MimeType,
+			&lecture.Status,
+			&lecture.TotalSlides,
+			&lecture.EmbeddingsComplete,
+			&lecture.CreatedAt,
+			&lecture.UpdatedAt,
+			&lecture.AccessedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning stale uploading lecture row: %w", err)
+		}
+		lectures = append(lectures, lecture)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating stale uploading lecture rows: %w", err)
+	}
+
+	return lectures, nil
+}
+
+func (r *lectureRepository) DeleteStaleUploadingLecture(ctx context.Context, lectureID string, updatedBefore time.Time) (*model.Lecture, error) {
+	query := `
+		DELETE FROM lectures
+		WHERE id = $1 AND status = 'uploading' AND updated_at < $2
+		RETURNING id, user_id, storage_path, multipart_upload_id
+	`
+	var lecture model.Lecture
+	err := r.pool.QueryRow(ctx, query, lectureID, updatedBefore).Scan(
+		&lecture.ID,
+		&lecture.UserID,
+		&lecture.StoragePath,
+		&lecture.MultipartUploadID,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("deleting stale uploading lecture %s: %w", lectureID, err)
+	}
+	return &lecture, nil
+}
+
+// GetInFlightLecturesByUserID returns the user's lectures that are queued or being processed,
+// oldest first.
+func (r *lectureRepository) GetInFlightLecturesByUserID(ctx context.Context, userID string) ([]model.Lecture, error) {
+	query := `
+		SELECT id, user_id, course_id, title, storage_path, file_type, mime_type, status, total_slides, total_sub_images, processed_sub_images, embeddings_complete, created_at, updated_at, accessed_at, processing_started_at, completed_at
+		FROM lectures
+		WHERE user_id = $1 AND status IN ('pending_processing', 'parsing', 'processing')
+		ORDER BY created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying in-flight lectures for user %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	var lectures []model.Lecture
+	for rows.Next() {
+		var lecture model.Lecture
+		if err := rows.Scan(
+			&lecture.ID,
+			&lecture.UserID,
+			&lecture.CourseID,
+			&lecture.Title,
+			&lecture.StoragePath,
+			&lecture.FileType,
+			&lecture.MimeType,
+			&lecture.Status,
+			&lecture.TotalSlides,
+			&lecture.TotalSubImages,
+			&lecture.ProcessedSubImages,
+			&lecture.EmbeddingsComplete,
+			&lecture.CreatedAt,
+			&lecture.UpdatedAt,
+			&lecture.AccessedAt,
+			&lecture.ProcessingStartedAt,
+			&lecture.CompletedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning in-flight lecture row: %w", err)
+		}
+		lectures = append(lectures, lecture)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating in-flight lecture rows: %w", err)
+	}
+
+	return lectures, nil
+}
+
+func (r *lectureRepository) GetLectureIDsByCourseID(ctx context.Context, courseID string) ([]string, error) {
+	query := `SELECT id FROM lectures WHERE course_id = $1 ORDER BY created_at`
+	rows, err := r.pool.Query(ctx, query, courseID)
+	if err != nil {
+		return nil, fmt.Errorf("querying lecture IDs for course %s: %w", courseID, err)
+	}
+	defer rows.Close()
+
+	var lectureIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning lecture ID row: %w", err)
+		}
+		lectureIDs = append(lectureIDs, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating lecture ID rows: %w", err)
+	}
+	return lectureIDs, nil
+}
+
+func (r *lectureRepository) GetAverageProcessingDuration(ctx context.Context, minSlides, maxSlides, sampleSize int) (time.Duration, int, error) {
+	query := fmt.Sprintf(`
+		SELECT COALESCE(EXTRACT(EPOCH FROM AVG(completed_at - processing_started_at)), 0)::float8, COUNT(*)
+		FROM (
+			SELECT processing_started_at, completed_at
+			FROM lectures
+			WHERE status = 'complete'
+				AND processing_started_at IS NOT NULL
+				AND completed_at IS NOT NULL
+				AND total_slides BETWEEN $1 AND $2
+			ORDER BY completed_at DESC
+			LIMIT %d
+		) recent
+	`, sampleSize)
+
+	var seconds float64
+	var count int
+	if err := r.pool.QueryRow(ctx, query, minSlides, maxSlides).Scan(&seconds, &count); err != nil {
+		return 0, 0, fmt.Errorf("averaging processing duration for %d-%d slides: %w", minSlides, maxSlides, err)
+	}
+	return time.Duration(seconds * float64(time.Second)), count, nil
+}
+
+func (r *lectureRepository) SlideHasChunks(ctx context.Context, lectureID string, slideNumber int, chunkIDs []string) (bool, error) {
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM slides WHERE lecture_id = $1 AND slide_number = $2),
+			(SELECT COUNT(DISTINCT id) FROM chunks WHERE lecture_id = $1 AND slide_number = $2 AND id::text = ANY($3))
+	`
+	var slideFound bool
+	var chunksFound int
+	if err := r.pool.QueryRow(ctx, query, lectureID, slideNumber, chunkIDs).Scan(&slideFound, &chunksFound); err != nil {
+		return false, fmt.Errorf("checking slide %d of lecture %s: %w", slideNumber, lectureID, err)
+	}
+	return slideFound && chunksFound == len(chunkIDs), nil
+}
+
 func (r *lectureRepository) CreateLecture(ctx context.Context, lecture *model.Lecture) (*model.Lecture, error) {
-	query := `INSERT INTO lectures (course_id, user_id, title, status, storage_path, embeddings_complete) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, total_slides, embeddings_complete, created_at, updated_at, accessed_at`
-	err := r.pool.QueryRow(ctx, query, lecture.CourseID, lecture.UserID, lecture.Title, lecture.Status, lecture.StoragePath, lecture.EmbeddingsComplete).Scan(&lecture.ID, &lecture.TotalSlides, &lecture.EmbeddingsComplete, &lecture.CreatedAt, &lecture.UpdatedAt, &lecture.AccessedAt)
+	query := `INSERT INTO lectures (course_id, user_id, title, status, storage_path, file_type, mime_type, embeddings_complete) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, total_slides, embeddings_complete, created_at, updated_at, accessed_at`
+	err := r.pool.QueryRow(ctx, query, lecture.CourseID, lecture.UserID, lecture.Title, lecture.Status, lecture.StoragePath, lecture.FileType, lecture.MimeType, lecture.EmbeddingsComplete).Scan(&lecture.ID, &lecture.TotalSlides, &lecture.EmbeddingsComplete, &lecture.CreatedAt, &lecture.UpdatedAt, &lecture.AccessedAt)
 	if err != nil {
 		return nil, fmt.Errorf("creating lecture: %w", err)
 	}
@@ -198,3 +574,69 @@ func (r *lectureRepository) CountLecturesByUserID(ctx context.Context, userID st
 	}
 	return count, nil
 }
+
+func (r *lectureRepository) ListLectures(ctx context.Context, filter LectureListFilter, limit, offset int) ([]model.Lecture, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.UserID != "" {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d::lecture_status", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, user_id, course_id, title, storage_path, file_type, mime_type, status, embedding_error_details, total_slides, total_sub_images, processed_sub_images, embeddings_complete, created_at, updated_at, accessed_at, processing_started_at, completed_at
+		FROM lectures
+		%s
+		ORDER BY updated_at DESC
+		LIMIT %d OFFSET %d
+	`, where, limit, offset)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying lectures: %w", err)
+	}
+	defer rows.Close()
+
+	var lectures []model.Lecture
+	for rows.Next() {
+		var lecture model.Lecture
+		if err := rows.Scan(
+			&lecture.ID,
+			&lecture.UserID,
+			&lecture.CourseID,
+			&lecture.Title,
+			&lecture.StoragePath,
+			&lecture.FileType,
+			&lecture.MimeType,
+			&lecture.Status,
+			&lecture.EmbeddingErrorDetails,
+			&lecture.TotalSlides,
+			&lecture.TotalSubImages,
+			&lecture.ProcessedSubImages,
+			&lecture.EmbeddingsComplete,
+			&lecture.CreatedAt,
+			&lecture.UpdatedAt,
+			&lecture.AccessedAt,
+			&lecture.ProcessingStartedAt,
+			&lecture.CompletedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning lecture row: %w", err)
+		}
+		lectures = append(lectures, lecture)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating lecture rows: %w", err)
+	}
+
+	return lectures, nil
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"app/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// querier is satisfied by both *pgxpool.Pool and pgx.Tx, so statements can be shared
+// between standalone calls and multi-statement transactions.
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type OutboxRepository interface {
+	// Enqueue records a message for the dispatcher to publish.
+	Enqueue(ctx context.Context, message *model.OutboxMessage) error
+	// ClaimDue leases up to limit pending messages that are due for a publish attempt.
+	// Leased messages are hidden from other dispatchers until leaseUntil and have their
+	// attempt count incremented.
+	ClaimDue(ctx context.Context, limit int, leaseUntil time.Time) ([]model.OutboxMessage, error)
+	MarkPublished(ctx context.Context, id, publishedMessageID string) error
+	// MarkAttemptFailed records a failed publish. The message is retried at nextAttemptAt.
+	MarkAttemptFailed(ctx context.Context, id, lastError string, nextAttemptAt time.Time) error
+	// MarkFailed records the last failed publish and moves the message to failed. In the same
+	// transaction, the message's lecture, if it is still waiting for the message, is marked
+	// failed with the given error details.
+	MarkFailed(ctx context.Context, id, lastError string, lectureErrorDetails model.EmbeddingErrorDetails) error
+}
+
+type outboxRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewOutboxRepository(pool *pgxpool.Pool) OutboxRepository {
+	return &outboxRepository{pool: pool}
+}
+
+const outboxColumns = `id, topic, payload, attributes, lecture_id, status, attempts, last_error, available_at, published_at, published_message_id, created_at, updated_at`
+
+func (r *outboxRepository) Enqueue(ctx context.Context, message *model.OutboxMessage) error {
+	return insertOutboxMessage(ctx, r.pool, message)
+}
+
+func (r *outboxRepository) ClaimDue(ctx context.Context, limit int, leaseUntil time.Time) ([]model.OutboxMessage, error) {
+	query := fmt.Sprintf(`
+		UPDATE outbox_messages
+		SET available_at = $1, attempts = attempts + 1, updated_at = NOW()
+		WHERE id IN (
+			SELECT id
+			FROM outbox_messages
+			WHERE status = 'pending' AND available_at <= NOW()
+			ORDER BY available_at
+			LIMIT %d
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING %s
+	`, limit, outboxColumns)
+
+	rows, err := r.pool.Query(ctx, query, leaseUntil)
+	if err != nil {
+		return nil, fmt.Errorf("claiming due outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []model.OutboxMessage
+	for rows.Next() {
+		message, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning outbox message row: %w", err)
+		}
+		messages = append(messages, *message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating outbox message rows: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (r *outboxRepository) MarkPublished(ctx context.Context, id, publishedMessageID string) error {
+	query := `
+		UPDATE outbox_messages
+		SET status = 'published', published_at = NOW(), published_message_id = $1, last_error = NULL, updated_at = NOW()
+		WHERE id = $2
+	`
+	if _, err := r.pool.Exec(ctx, query, publishedMessageID, id); err != nil {
+		return fmt.Errorf("marking outbox message %s as published: %w", id, err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) MarkAttemptFailed(ctx context.Context, id, lastError string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE outbox_messages
+		SET last_error = $1, available_at = $2, updated_at = NOW()
+		WHERE id = $3
+	`
+	if _, err := r.pool.Exec(ctx, query, lastError, nextAttemptAt, id); err != nil {
+		return fmt.Errorf("recording failed publish of outbox message %s: %w", id, err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id, lastError string, lectureErrorDetails model.EmbeddingErrorDetails) error {
+	detailsJSON, err := json.Marshal(lectureErrorDetails)
+	if err != nil {
+		return fmt.Errorf("marshaling lecture error details for outbox message %s: %w", id, err)
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction for outbox message %s: %w", id, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var lectureID *string
+	query := `
+		UPDATE outbox_messages
+		SET status = 'failed', last_error = $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING lecture_id
+	`
+	if err := tx.QueryRow(ctx, query, lastError, id).Scan(&lectureID); err != nil {
+		return fmt.Errorf("marking outbox message %s as failed: %w", id, err)
+	}
+	if lectureID != nil {
+		// A lecture that has moved on, e.g. through a reprocess with a newer message, is left alone
+		query = `
+			UPDATE lectures
+			SET status = 'failed', embedding_error_details = $1::jsonb, updated_at = NOW()
+			WHERE id = $2 AND status = 'pending_processing'
+		`
+		if _, err := tx.Exec(ctx, query, string(detailsJSON), *lectureID); err != nil {
+			return fmt.Errorf("marking lecture %s of outbox message %s as failed: %w", *lectureID, id, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing failure of outbox message %s: %w", id, err)
+	}
+	return nil
+}
+
+// insertOutboxMessage writes a pending outbox message through q, which may be a transaction
+// so the message is only recorded together with the state change that produced it.
+// A zero AvailableAt makes the message due immediately.
+func insertOutboxMessage(ctx context.Context, q querier, message *model.OutboxMessage) error {
+	var attributesJSON *string
+	if len(message.Attributes) > 0 {
+		b, err := json.Marshal(message.Attributes)
+		if err != nil {
+			return fmt.Errorf("marshaling outbox message attributes: %w", err)
+		}
+		str := string(b)
+		attributesJSON = &str
+	}
+	var availableAt *time.Time
+	if !message.AvailableAt.IsZero() {
+		availableAt = &message.AvailableAt
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO outbox_messages (topic, payload, attributes, lecture_id, available_at)
+		VALUES ($1, $2::jsonb, $3::jsonb, $4, COALESCE($5, NOW()))
+		RETURNING %s
+	`, outboxColumns)
+	inserted, err := scanOutboxMessage(q.QueryRow(ctx, query, message.Topic, string(message.Payload), attributesJSON, message.LectureID, availableAt))
+	if err != nil {
+		return fmt.Errorf("creating outbox message for topic %s: %w", message.Topic, err)
+	}
+	*message = *inserted
+	return nil
+}
+
+func scanOutboxMessage(row pgx.Row) (*model.OutboxMessage, error) {
+	var message model.OutboxMessage
+	var attributesJSON []byte
+	if err := row.Scan(
+		&message.ID,
+		&message.Topic,
+		&message.Payload,
+		&attributesJSON,
+		&message.LectureID,
+		&message.Status,
+		&message.Attempts,
+		&message.LastError,
+		&message.AvailableAt,
+		&message.PublishedAt,
+		&message.PublishedMessageID,
+		&message.CreatedAt,
+		&message.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if len(attributesJSON) > 0 {
+		if err := json.Unmarshal(attributesJSON, &message.Attributes); err != nil {
+			return nil, fmt.Errorf("unmarshaling outbox message attributes: %w", err)
+		}
+	}
+	return &message, nil
+}
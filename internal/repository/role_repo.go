@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RoleRepository reads the operator roles granted in the user_roles table.
+type RoleRepository interface {
+	HasRole(ctx context.Context, userID, role string) (bool, error)
+	ListRoles(ctx context.Context, userID string) ([]string, error)
+}
+
+type roleRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewRoleRepo creates a new RoleRepository
+func NewRoleRepo(pool *pgxpool.Pool) RoleRepository {
+	return &roleRepo{pool: pool}
+}
+
+func (r *roleRepo) HasRole(ctx context.Context, userID, role string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM user_roles WHERE user_id = $1 AND role = $2)`
+	var ok bool
+	if err := r.pool.QueryRow(ctx, query, userID, role).Scan(&ok); err != nil {
+		return false, fmt.Errorf("checking role %s of user %s: %w", role, userID, err)
+	}
+	return ok, nil
+}
+
+func (r *roleRepo) ListRoles(ctx context.Context, userID string) ([]string, error) {
+	query := `SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role`
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying roles of user %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("scanning role row: %w", err)
+		}
+		roles = append(roles, role)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating role rows: %w", err)
+	}
+
+	return roles, nil
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"app/internal/model"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// searchHeadlineOptions configures ts_headline for result snippets.
+var searchHeadlineOptions = fmt.Sprintf(
+	"StartSel=%s, StopSel=%s, MaxWords=30, MinWords=10, MaxFragments=2",
+	model.SearchHighlightStart, model.SearchHighlightStop,
+)
+
+// SearchRepository runs full-text and semantic searches over a user's content.
+type SearchRepository interface {
+	// Search matches query, in websearch syntax, against the user's course and lecture titles,
+	// notes and chat messages, best matches first.
+	Search(ctx context.Context, userID, query string, filter model.SearchFilter, limit, offset int) ([]model.SearchResult, error)
+	// SearchChunks returns the chunks of the user's lectures nearest to the embedding, closest first.
+	SearchChunks(ctx context.Context, userID string, embedding []float32, filter model.SearchFilter, limit int) ([]model.ChunkMatch, error)
+}
+
+type searchRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewSearchRepo creates a new SearchRepository
+func NewSearchRepo(pool *pgxpool.Pool) SearchRepository {
+	return &searchRepo{pool: pool}
+}
+
+func (r *searchRepo) Search(ctx context.Context, userID, query string, filter model.SearchFilter, limit, offset int) ([]model.SearchResult, error) {
+	// Snippets are only computed for the page being returned, as ts_headline is expensive
+	sql := fmt.Sprintf(`
+		WITH search AS (
+			SELECT websearch_to_tsquery('english', $2) AS q
+		),
+		hits AS (
+			SELECT 'course' AS kind, c.id, c.id AS course_id, c.title AS course_title,
+				NULL::uuid AS lecture_id, NULL::text AS lecture_title, NULL::uuid AS chat_id, NULL::text AS chat_title,
+				c.title AS body, ts_rank(c.search_vector, search.q) AS rank, c.created_at
+			FROM courses c, search
+			WHERE c.user_id = $1 AND c.search_vector @@ search.q
+				AND ($3 = '' OR c.id::text = $3) AND $4 = ''
+
+			UNION ALL
+
+			SELECT 'lecture', l.id, c.id, c.title, l.id, l.title, NULL, NULL,
+				l.title, ts_rank(l.search_vector, search.q), l.created_at
+			FROM lectures l
+			JOIN courses c ON c.id = l.course_id, search
+			WHERE l.user_id = $1 AND l.search_vector @@ search.q
+				AND ($3 = '' OR l.course_id::text = $3) AND ($4 = '' OR l.id::text = $4)
+
+			UNION ALL
+
+			SELECT 'note', n.id, c.id, c.title, l.id, l.title, NULL, NULL,
+				n.content, ts_rank(n.search_vector, search.q), n.created_at
+			FROM notes n
+			JOIN lectures l ON l.id = n.lecture_id
+			JOIN courses c ON c.id = l.course_id, search
+			WHERE n.user_id = $1 AND n.search_vector @@ search.q
+				AND ($3 = '' OR l.course_id::text = $3) AND ($4 = '' OR l.id::text = $4)
+
+			UNION ALL
+
+			SELECT 'message', m.id, c.id, c.title, l.id, l.title, ch.id, ch.title,
+				message_plain_text(m.parts), ts_rank(m.search_vector, search.q), m.created_at
+			FROM messages m
+			JOIN chats ch ON ch.id = m.chat_id
+			LEFT JOIN lectures l ON l.id = ch.lecture_id -- course chats have no lecture
+			JOIN courses c ON c.id = COALESCE(ch.course_id, l.course_id), search
+			WHERE ch.user_id = $1 AND m.search_vector @@ search.q
+				AND ($3 = '' OR c.id::text = $3) AND ($4 = '' OR l.id::text = $4)
+
+			ORDER BY rank DESC, created_at DESC
+			LIMIT %d OFFSET %d
+		)
+		SELECT h.kind, h.id, h.course_id, h.course_title, h.lecture_id, h.lecture_title, h.chat_id, h.chat_title,
+			ts_headline('english', h.body, search.q, $5), h.rank::float8, h.created_at
+		FROM hits h, search
+		ORDER BY h.rank DESC, h.created_at DESC
+	`, limit, offset)
+
+	rows, err := r.pool.Query(ctx, sql, userID, query, filter.CourseID, filter.LectureID, searchHeadlineOptions)
+	if err != nil {
+		return nil, fmt.Errorf("searching for %q: %w", query, err)
+	}
+	defer rows.Close()
+
+	var results []model.SearchResult
+	for rows.Next() {
+		var res model.SearchResult
+		if err := rows.Scan(
+			&res.Kind,
+			&res.ID,
+			&res.CourseID,
+			&res.CourseTitle,
+			&res.LectureID,
+			&res.LectureTitle,
+			&res.ChatID,
+			&res.ChatTitle,
+			&res.Snippet,
+			&res.Rank,
+			&res.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning search result row: %w", err)
+		}
+		results = append(results, res)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating search result rows: %w", err)
+	}
+
+	return results, nil
+}
+
+func (r *searchRepo) SearchChunks(ctx context.Context, userID string, embedding []float32, filter model.SearchFilter, limit int) ([]model.ChunkMatch, error) {
+	// Rank the user's embeddings exactly instead of through the ivfflat index: the index is built
+	// over every user's rows, so an approximate scan filtered afterwards would usually probe lists
+	// holding none of this user's chunks and return few or no matches. The materialized CTE keeps
+	// the planner from pushing the ORDER BY back onto the index; the lecture_id index narrows the
+	// scan to the user's lectures.
+	query := fmt.Sprintf(`
+		WITH owned AS (
+			SELECT l.id, l.title, l.course_id
+			FROM lectures l
+			JOIN courses c ON c.id = l.course_id
+			WHERE c.user_id = $2
+				AND ($3 = '' OR l.course_id::text = $3) AND ($4 = '' OR l.id::text = $4)
+		), candidates AS MATERIALIZED (
+			SELECT e.chunk_id, e.lecture_id, e.slide_number, e.vector <=> $1::vector AS distance
+			FROM embeddings e
+			JOIN owned o ON o.id = e.lecture_id
+		)
+		SELECT ch.id, o.id, o.title, o.course_id, cd.slide_number, ch.text, 1 - cd.distance AS score
+		FROM candidates cd
+		JOIN chunks ch ON ch.id = cd.chunk_id
+		JOIN owned o ON o.id = cd.lecture_id
+		ORDER BY cd.distance
+		LIMIT %d
+	`, limit)
+
+	rows, err := r.pool.Query(ctx, query, vectorLiteral(embedding), userID, filter.CourseID, filter.LectureID)
+	if err != nil {
+		return nil, fmt.Errorf("searching chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []model.ChunkMatch
+	for rows.Next() {
+		var m model.ChunkMatch
+		if err := rows.Scan(
+			&m.ChunkID,
+			&m.LectureID,
+			&m.LectureTitle,
+			&m.CourseID,
+			&m.SlideNumber,
+			&m.Text,
+			&m.Score,
+		); err != nil {
+			return nil, fmt.Errorf("scanning chunk match row: %w", err)
+		}
+		matches = append(matches, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating chunk match rows: %w", err)
+	}
+
+	return matches, nil
+}
+
+// vectorLiteral formats an embedding in pgvector's text format, e.g. [0.1,0.2].
+func vectorLiteral(embedding []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, v := range embedding {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
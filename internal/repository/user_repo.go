@@ -14,6 +14,7 @@ import (
 type UserRepository interface {
 	CreateUser(ctx context.Context, u *model.User) error
 	GetUserByID(ctx context.Context, id string) (*model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
 	UpdateAPIKeyFlag(ctx context.Context, userID string, provider string, hasKey bool) error
 	UpdateModelPreference(ctx context.Context, userID string, provider string, model string, enabled bool) error
 	UpdateAPIKeyFlagAndInitializeModels(ctx context.Context, userID string, provider string, hasKey bool, defaultModels []string) error
@@ -66,6 +67,20 @@ func (r *userRepo) GetUserByID(ctx context.Context, id string) (*model.User, err
 	return &u, nil
 }
 
+// GetUserByEmail looks a user up by email, ignoring case.
+func (r *userRepo) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	var u model.User
+	query := `SELECT user_id, email, name, avatar_url, api_keys_provided, model_preferences, created_at, updated_at FROM user_profiles WHERE lower(email)=lower($1)`
+	err := r.pool.QueryRow(ctx, query, email).Scan(&u.UserID, &u.Email, &u.Name, &u.AvatarURL, &u.APIKeysProvided, &u.ModelPreferences, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting user by email: %w", err)
+	}
+	return &u, nil
+}
+
 func (r *userRepo) UpdateAPIKeyFlag(ctx context.Context, userID string, provider string, hasKey bool) error {
 	// Marshal the boolean value to JSON bytes (same pattern as CreateUser)
 	boolJSON, err := json.Marshal(hasKey)
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"app/internal/model"
+	"app/internal/repository"
+
+	"github.com/rs/zerolog"
+)
+
+// lectureStatuses are the values of the lecture_status enum.
+var lectureStatuses = []string{"uploading", "pending_processing", "parsing", "processing", "complete", "failed"}
+
+var ErrInvalidLectureStatus = errors.New("invalid lecture status")
+
+// AdminUser is a user as support sees it.
+type AdminUser struct {
+	User         model.User
+	Roles        []string // roles granted in the database; token roles are not visible here
+	LectureCount int
+}
+
+// AdminService backs the operator endpoints. Callers must already be authorized as admins;
+// nothing here is scoped to the caller.
+type AdminService interface {
+	GetUser(ctx context.Context, userID string) (*AdminUser, error)
+	// FindUserByEmail looks a user up by email, ignoring case.
+	FindUserByEmail(ctx context.Context, email string) (*AdminUser, error)
+	// ListUserLectures returns the user's lectures, optionally with the given status, most
+	// recently updated first.
+	ListUserLectures(ctx context.Context, userID, status string, limit, offset int) ([]model.Lecture, error)
+	// ListFailedIngestions returns lectures whose processing failed, with their error details,
+	// most recent first, for every user or just the given one.
+	ListFailedIngestions(ctx context.Context, userID string, limit, offset int) ([]model.Lecture, error)
+}
+
+type adminService struct {
+	userRepo    repository.UserRepository
+	lectureRepo repository.LectureRepository
+	roleRepo    repository.RoleRepository
+	adminLogger zerolog.Logger
+}
+
+// NewAdminService creates a new AdminService.
+func NewAdminService(
+	userRepo repository.UserRepository,
+	lectureRepo repository.LectureRepository,
+	roleRepo repository.RoleRepository,
+	logger zerolog.Logger,
+) AdminService {
+	return &adminService{
+		userRepo:    userRepo,
+		lectureRepo: lectureRepo,
+		roleRepo:    roleRepo,
+		adminLogger: logger.With().Str("service", "AdminService").Logger(),
+	}
+}
+
+func (s *adminService) GetUser(ctx context.Context, userID string) (*AdminUser, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		s.adminLogger.Error().Err(err).Str("user_id", userID).Msg("Failed to get user")
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return s.describeUser(ctx, user)
+}
+
+func (s *adminService) FindUserByEmail(ctx context.Context, email string) (*AdminUser, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		s.adminLogger.Error().Err(err).Msg("Failed to find user by email")
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return s.describeUser(ctx, user)
+}
+
+func (s *adminService) describeUser(ctx context.Context, user *model.User) (*AdminUser, error) {
+	roles, err := s.roleRepo.ListRoles(ctx, user.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("listing roles: %w", err)
+	}
+	count, err := s.lectureRepo.CountLecturesByUserID(ctx, user.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("counting lectures: %w", err)
+	}
+	return &AdminUser{User: *user, Roles: roles, LectureCount: count}, nil
+}
+
+func (s *adminService) ListUserLectures(ctx context.Context, userID, status string, limit, offset int) ([]model.Lecture, error) {
+	if status != "" && !slices.Contains(lectureStatuses, status) {
+		return nil, ErrInvalidLectureStatus
+	}
+	lectures, err := s.lectureRepo.ListLectures(ctx, repository.LectureListFilter{UserID: userID, Status: status}, limit, offset)
+	if err != nil {
+		s.adminLogger.Error().Err(err).Str("user_id", userID).Msg("Failed to list user lectures")
+		return nil, err
+	}
+	return lectures, nil
+}
+
+func (s *adminService) ListFailedIngestions(ctx context.Context, userID string, limit, offset int) ([]model.Lecture, error) {
+	lectures, err := s.lectureRepo.ListLectures(ctx, repository.LectureListFilter{UserID: userID, Status: "failed"}, limit, offset)
+	if err != nil {
+		s.adminLogger.Error().Err(err).Msg("Failed to list failed ingestions")
+		return nil, err
+	}
+	return lectures, nil
+}
@@ -0,0 +1,9 @@
+package service
+
+import "context"
+
+// BackgroundWorker is a long-running task that runs alongside the HTTP server in the API process.
+type BackgroundWorker interface {
+	// Run blocks until ctx is cancelled.
+	Run(ctx context.Context)
+}
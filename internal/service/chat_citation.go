@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"app/internal/model"
+)
+
+var errInvalidCitation = errors.New("invalid slide citation")
+
+// validateCitation checks that a slide citation from the model points at a slide of a lecture
+// the chat covers and at chunks of that slide, so the viewer never jumps to a slide that does not
+// exist. Citations in course chats must name their lecture under the "lecture_id" metadata key;
+// lecture chats fill it in, so every saved citation says which lecture its slide came from.
+// Other references are not checked.
+func (s *chatService) validateCitation(ctx context.Context, scope model.ChatScope, ref *model.ReferencePart) error {
+	if ref == nil || ref.Reference == nil || ref.Reference.Type != model.ReferenceTypeSlide {
+		return nil
+	}
+
+	slideNumber, err := strconv.Atoi(ref.Reference.ID)
+	if err != nil || slideNumber < 1 {
+		return fmt.Errorf("%w: bad slide number %q", errInvalidCitation, ref.Reference.ID)
+	}
+	chunkIDs, err := citationChunkIDs(ref.Reference.Metadata)
+	if err != nil {
+		return err
+	}
+
+	lectureID, _ := ref.Reference.Metadata["lecture_id"].(string)
+	switch {
+	case scope.LectureID != "":
+		if lectureID == "" {
+			lectureID = scope.LectureID
+		} else if lectureID != scope.LectureID {
+			return fmt.Errorf("%w: lecture %s is not the chat's lecture", errInvalidCitation, lectureID)
+		}
+	case lectureID == "":
+		return fmt.Errorf("%w: missing lecture_id", errInvalidCitation)
+	default:
+		lecture, err := s.lectureRepo.GetLectureByID(ctx, lectureID)
+		if err != nil {
+			return err
+		}
+		if lecture == nil || lecture.CourseID != scope.CourseID {
+			return fmt.Errorf("%w: lecture %s is not part of course %s", errInvalidCitation, lectureID, scope.CourseID)
+		}
+	}
+
+	ok, err := s.lectureRepo.SlideHasChunks(ctx, lectureID, slideNumber, chunkIDs)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: slide %d or its chunks are not part of lecture %s", errInvalidCitation, slideNumber, lectureID)
+	}
+
+	if ref.Reference.Metadata == nil {
+		ref.Reference.Metadata = map[string]any{}
+	}
+	ref.Reference.Metadata["lecture_id"] = lectureID
+	return nil
+}
+
+// citationChunkIDs reads the distinct chunk IDs of a slide citation, which are optional.
+func citationChunkIDs(metadata map[string]any) ([]string, error) {
+	raw, ok := metadata["chunk_ids"]
+	if !ok || raw == nil {
+		return []string{}, nil
+	}
+	values, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: chunk_ids must be an array", errInvalidCitation)
+	}
+
+	chunkIDs := make([]string, 0, len(values))
+	for _, v := range values {
+		id, ok := v.(string)
+		if !ok || id == "" {
+			return nil, fmt.Errorf("%w: chunk_ids must contain chunk IDs", errInvalidCitation)
+		}
+		if !slices.Contains(chunkIDs, id) {
+			chunkIDs = append(chunkIDs, id)
+		}
+	}
+	return chunkIDs, nil
+}
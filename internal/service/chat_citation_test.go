@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"app/internal/model"
+	"app/internal/repository"
+)
+
+// citationLectureRepo serves the lectures and slide chunks validateCitation looks up.
+type citationLectureRepo struct {
+	repository.LectureRepository
+	lectures map[string]*model.Lecture
+	// chunks lists the chunk IDs of each slide, by lecture ID and slide number
+	chunks map[string]map[int][]string
+}
+
+func (r *citationLectureRepo) GetLectureByID(_ context.Context, lectureID string) (*model.Lecture, error) {
+	return r.lectures[lectureID], nil
+}
+
+func (r *citationLectureRepo) SlideHasChunks(_ context.Context, lectureID string, slideNumber int, chunkIDs []string) (bool, error) {
+	slide, ok := r.chunks[lectureID][slideNumber]
+	if !ok {
+		return false, nil
+	}
+	for _, id := range chunkIDs {
+		if !slices.Contains(slide, id) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func TestCitationChunkIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]any
+		want     []string
+		wantErr  bool
+	}{
+		{"no metadata", nil, []string{}, false},
+		{"no chunk ids", map[string]any{"lecture_id": "l1"}, []string{}, false},
+		{"null chunk ids", map[string]any{"chunk_ids": nil}, []string{}, false},
+		{"chunk ids", map[string]any{"chunk_ids": []any{"c1", "c2"}}, []string{"c1", "c2"}, false},
+		{"duplicates", map[string]any{"chunk_ids": []any{"c1", "c2", "c1"}}, []string{"c1", "c2"}, false},
+		{"not an array", map[string]any{"chunk_ids": "c1"}, nil, true},
+		{"not strings", map[string]any{"chunk_ids": []any{"c1", 2.0}}, nil, true},
+		{"empty id", map[string]any{"chunk_ids": []any{""}}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := citationChunkIDs(tt.metadata)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: citationChunkIDs error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil && !errors.Is(err, errInvalidCitation) {
+			t.Errorf("%s: citationChunkIDs error = %v, want errInvalidCitation", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: citationChunkIDs = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateCitation(t *testing.T) {
+	s := &chatService{lectureRepo: &citationLectureRepo{
+		lectures: map[string]*model.Lecture{
+			"l1": {ID: "l1", CourseID: "c1"},
+			"l2": {ID: "l2", CourseID: "c1"},
+			"l3": {ID: "l3", CourseID: "c2"},
+		},
+		chunks: map[string]map[int][]string{
+			"l1": {1: {"k1", "k2"}, 2: {}},
+			"l2": {4: {"k4"}},
+			"l3": {1: {"k5"}},
+		},
+	}}
+	lectureChat := model.ChatScope{LectureID: "l1"}
+	courseChat := model.ChatScope{CourseID: "c1"}
+
+	slide := func(id string, metadata map[string]any) *model.ReferencePart {
+		return &model.ReferencePart{Type: "reference", Reference: &model.Reference{Type: model.ReferenceTypeSlide, ID: id, Metadata: metadata}}
+	}
+
+	tests := []struct {
+		name  string
+		scope model.ChatScope
+		ref   *model.ReferencePart
+		// wantLecture is the lecture a valid citation is saved with
+		wantLecture string
+		wantErr     bool
+	}{
+		{"lecture chat fills in its lecture", lectureChat, slide("1", nil), "l1", false},
+		{"lecture chat with chunks", lectureChat, slide("1", map[string]any{"chunk_ids": []any{"k2", "k1"}}), "l1", false},
+		{"lecture chat naming its lecture", lectureChat, slide("2", map[string]any{"lecture_id": "l1"}), "l1", false},
+		{"lecture chat naming another lecture", lectureChat, slide("4", map[string]any{"lecture_id": "l2"}), "", true},
+		{"slide the lecture lacks", lectureChat, slide("3", nil), "", true},
+		{"chunk of another slide", lectureChat, slide("2", map[string]any{"chunk_ids": []any{"k1"}}), "", true},
+		{"chunk of another lecture", lectureChat, slide("1", map[string]any{"chunk_ids": []any{"k5"}}), "", true},
+		{"slide zero", lectureChat, slide("0", nil), "", true},
+		{"slide not a number", lectureChat, slide("first", nil), "", true},
+		{"course chat lecture", courseChat, slide("4", map[string]any{"lecture_id": "l2"}), "l2", false},
+		{"course chat without lecture", courseChat, slide("1", nil), "", true},
+		{"course chat lecture of another course", courseChat, slide("1", map[string]any{"lecture_id": "l3"}), "", true},
+		{"course chat missing lecture", courseChat, slide("1", map[string]any{"lecture_id": "l9"}), "", true},
+	}
+	for _, tt := range tests {
+		err := s.validateCitation(context.Background(), tt.scope, tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateCitation error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, errInvalidCitation) {
+				t.Errorf("%s: validateCitation error = %v, want errInvalidCitation", tt.name, err)
+			}
+			continue
+		}
+		if got := tt.ref.Reference.Metadata["lecture_id"]; got != tt.wantLecture {
+			t.Errorf("%s: lecture_id = %v, want %s", tt.name, got, tt.wantLecture)
+		}
+	}
+
+	// Other references pass through untouched
+	for _, ref := range []*model.ReferencePart{nil, {Type: "reference"}, {Reference: &model.Reference{Type: "page", ID: "x"}}} {
+		if err := s.validateCitation(context.Background(), lectureChat, ref); err != nil {
+			t.Errorf("validateCitation(%+v) = %v, want nil", ref, err)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"app/internal/model"
+)
+
+// chatExportMessageLimit bounds the messages of an export; it is far above any real conversation.
+const chatExportMessageLimit = 10000
+
+var ErrInvalidExportFormat = errors.New("export format must be md, html or json")
+
+// ChatExportFile is a rendered chat export.
+type ChatExportFile struct {
+	Filename    string
+	ContentType string
+	Body        []byte
+}
+
+func (s *chatService) ExportChat(ctx context.Context, scope model.ChatScope, chatID, userID, format string) (*ChatExportFile, error) {
+	if format != model.ChatExportMarkdown && format != model.ChatExportHTML && format != model.ChatExportJSON {
+		return nil, ErrInvalidExportFormat
+	}
+
+	chat, err := s.verifyChatAccess(ctx, scope, chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := s.chatRepo.ListMessages(ctx, chatID, userID, chatExportMessageLimit)
+	if err != nil {
+		s.logger.Error().Err(err).Str("chat_id", chatID).Msg("Failed to list messages for export")
+		return nil, fmt.Errorf("listing messages: %w", err)
+	}
+
+	export := &model.ChatExport{
+		ID:         chat.ID,
+		Title:      chat.Title,
+		LectureID:  chat.LectureID,
+		CourseID:   chat.CourseID,
+		CreatedAt:  chat.CreatedAt,
+		ExportedAt: time.Now().UTC(),
+		Messages:   make([]model.ExportMessage, 0, len(messages)),
+	}
+	if scope.CourseID != "" {
+		course, err := s.courseRepo.GetCourseByID(ctx, scope.CourseID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve course: %w", err)
+		}
+		if course != nil {
+			export.CourseTitle = &course.Title
+		}
+	} else {
+		lecture, err := s.lectureRepo.GetLectureByID(ctx, scope.LectureID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve lecture: %w", err)
+		}
+		if lecture != nil {
+			export.LectureTitle = &lecture.Title
+		}
+	}
+
+	cite := s.newCitationWriter(ctx, scope)
+	for _, msg := range messages {
+		modelName, _ := msg.Metadata["model"].(string)
+		status, _ := msg.Metadata["status"].(string)
+		export.Messages = append(export.Messages, model.ExportMessage{
+			ID:        msg.ID,
+			Role:      msg.Role,
+			Model:     modelName,
+			Status:    status,
+			Content:   cite.content(msg.Parts),
+			Parts:     msg.Parts,
+			CreatedAt: msg.CreatedAt,
+		})
+	}
+
+	file := &ChatExportFile{Filename: exportFilename(chat.Title, format)}
+	switch format {
+	case model.ChatExportMarkdown:
+		file.ContentType = "text/markdown; charset=utf-8"
+		file.Body = renderChatMarkdown(export)
+	case model.ChatExportHTML:
+		file.ContentType = "text/html; charset=utf-8"
+		file.Body, err = renderChatHTML(export)
+	case model.ChatExportJSON:
+		file.ContentType = "application/json"
+		file.Body, err = json.MarshalIndent(export, "", "  ")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rendering %s export: %w", format, err)
+	}
+	return file, nil
+}
+
+// citationWriter flattens message parts into text with readable citations. In course chats,
+// slide citations also name their lecture, whose titles are looked up once each.
+type citationWriter struct {
+	s             *chatService
+	ctx           context.Context
+	scope         model.ChatScope
+	lectureTitles map[string]string
+}
+
+func (s *chatService) newCitationWriter(ctx context.Context, scope model.ChatScope) *citationWriter {
+	return &citationWriter{s: s, ctx: ctx, scope: scope, lectureTitles: map[string]string{}}
+}
+
+// content returns the text of the parts. Reasoning and tool calls are left out.
+func (c *citationWriter) content(parts model.MessageParts) string {
+	var b strings.Builder
+	for _, part := range parts {
+		var piece string
+		switch {
+		case part.Type == "text":
+			b.WriteString(part.Text)
+			continue
+		case part.Data != nil && part.Data.Reference != nil:
+			piece = c.citation(part.Data.Reference)
+		case part.Reference != nil:
+			piece = c.citation(part.Reference)
+		case part.Type == "source-url":
+			piece = "[Source: " + firstNonEmpty(part.Title, part.URL) + "]"
+		case part.Type == "source-document":
+			piece = "[Source: " + firstNonEmpty(part.Title, part.Filename, part.SourceID) + "]"
+		}
+		if piece == "" {
+			continue
+		}
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), " ") && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteByte(' ')
+		}
+		b.WriteString(piece)
+	}
+	return b.String()
+}
+
+// citation renders a slide reference as "[Slide 12]", or "[Slide 12, Lecture title]" in course
+// chats. Other references are not cited.
+func (c *citationWriter) citation(ref *model.Reference) string {
+	if ref.Type != model.ReferenceTypeSlide {
+		return ""
+	}
+	lectureID, _ := ref.Metadata["lecture_id"].(string)
+	if c.scope.CourseID == "" || lectureID == "" {
+		return "[Slide " + ref.ID + "]"
+	}
+
+	title, ok := c.lectureTitles[lectureID]
+	if !ok {
+		lecture, err := c.s.lectureRepo.GetLectureByID(c.ctx, lectureID)
+		if err != nil {
+			c.s.logger.Warn().Err(err).Str("lecture_id", lectureID).Msg("Failed to get lecture for export citation")
+		} else if lecture != nil {
+			title = lecture.Title
+		}
+		c.lectureTitles[lectureID] = title
+	}
+	if title == "" {
+		return "[Slide " + ref.ID + "]"
+	}
+	return "[Slide " + ref.ID + ", " + title + "]"
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// exportFilename derives a download name from the chat title, e.g. "week-3-recap.md".
+func exportFilename(title, format string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+		if b.Len() >= 60 {
+			break
+		}
+	}
+	name := b.String()
+	if name == "" {
+		name = "chat"
+	}
+	return name + "." + format
+}
+
+const exportTimeLayout = "2006-01-02 15:04 UTC"
+
+func exportRoleLabel(role string) string {
+	if role == "user" {
+		return "You"
+	}
+	return "Assistant"
+}
+
+// exportMessageHeading is the label shown above a message, e.g. "Assistant · gpt-4o · 2025-01-02 15:04 UTC".
+func exportMessageHeading(msg model.ExportMessage) string {
+	heading := exportRoleLabel(msg.Role)
+	if msg.Model != "" {
+		heading += " · " + msg.Model
+	}
+	heading += " · " + msg.CreatedAt.UTC().Format(exportTimeLayout)
+	if msg.Status != "" && msg.Status != model.MessageStatusComplete {
+		heading += " (" + msg.Status + ")"
+	}
+	return heading
+}
+
+func renderChatMarkdown(export *model.ChatExport) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n", export.Title)
+	if export.LectureTitle != nil {
+		fmt.Fprintf(&b, "- Lecture: %s\n", *export.LectureTitle)
+	}
+	if export.CourseTitle != nil {
+		fmt.Fprintf(&b, "- Course: %s\n", *export.CourseTitle)
+	}
+	fmt.Fprintf(&b, "- Created: %s\n", export.CreatedAt.UTC().Format(exportTimeLayout))
+	fmt.Fprintf(&b, "- Exported: %s\n", export.ExportedAt.UTC().Format(exportTimeLayout))
+
+	for _, msg := range export.Messages {
+		fmt.Fprintf(&b, "\n---\n\n### %s\n\n%s\n", exportMessageHeading(msg), strings.TrimSpace(msg.Content))
+	}
+	return b.Bytes()
+}
+
+// chatExportHTML is a standalone page meant to be printed to PDF from a browser: no external
+// assets, message headings kept with their text, and page margins set for print.
+var chatExportHTML = template.Must(template.New("chat").Funcs(template.FuncMap{
+	"heading": exportMessageHeading,
+	"time":    func(t time.Time) string { return t.UTC().Format(exportTimeLayout) },
+	"trim":    strings.TrimSpace,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+  @page { margin: 2cm; }
+  body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; line-height: 1.5; color: #111; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; }
+  h1 { font-size: 1.6rem; margin-bottom: 0.5rem; }
+  .details { color: #555; font-size: 0.9rem; margin: 0; padding: 0; list-style: none; }
+  .message { border-top: 1px solid #ddd; padding: 1rem 0; }
+  .message h2 { font-size: 0.85rem; font-weight: 600; color: #555; margin: 0 0 0.5rem; break-after: avoid; }
+  .message.user .content { background: #f4f4f5; border-radius: 6px; padding: 0.5rem 0.75rem; }
+  .content { white-space: pre-wrap; overflow-wrap: anywhere; orphans: 3; widows: 3; }
+  @media print {
+    body { margin: 0; max-width: none; padding: 0; }
+    .message.user .content { background: none; border-left: 3px solid #ccc; border-radius: 0; }
+  }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<ul class="details">
+{{- if .LectureTitle}}
+  <li>Lecture: {{.LectureTitle}}</li>
+{{- end}}
+{{- if .CourseTitle}}
+  <li>Course: {{.CourseTitle}}</li>
+{{- end}}
+  <li>Created: {{time .CreatedAt}}</li>
+  <li>Exported: {{time .ExportedAt}}</li>
+</ul>
+{{- range .Messages}}
+<section class="message {{.Role}}">
+  <h2>{{heading .}}</h2>
+  <div class="content">{{trim .Content}}</div>
+</section>
+{{- end}}
+</body>
+</html>
+`))
+
+func renderChatHTML(export *model.ChatExport) ([]byte, error) {
+	var b bytes.Buffer
+	if err := chatExportHTML.Execute(&b, export); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
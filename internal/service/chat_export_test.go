@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"app/internal/model"
+)
+
+func TestExportFilename(t *testing.T) {
+	tests := []struct {
+		title  string
+		format string
+		want   string
+	}{
+		{"Week 3 Recap", "md", "week-3-recap.md"},
+		{"  Week 3: Recap!  ", "html", "week-3-recap.html"},
+		{"Q&A -- entropy/enthalpy", "json", "q-a-entropy-enthalpy.json"},
+		{"../../etc/passwd", "md", "etc-passwd.md"},
+		{"", "md", "chat.md"},
+		{"!!!", "md", "chat.md"},
+		{"日本語", "html", "chat.html"},
+		{strings.Repeat("a", 80), "md", strings.Repeat("a", 60) + ".md"},
+	}
+	for _, tt := range tests {
+		if got := exportFilename(tt.title, tt.format); got != tt.want {
+			t.Errorf("exportFilename(%q, %q) = %q, want %q", tt.title, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestCitationWriterContent(t *testing.T) {
+	s := &chatService{lectureRepo: &citationLectureRepo{
+		lectures: map[string]*model.Lecture{"l2": {ID: "l2", CourseID: "c1", Title: "Thermodynamics"}},
+	}}
+	slide := func(id, lectureID string) *model.Reference {
+		return &model.Reference{Type: model.ReferenceTypeSlide, ID: id, Metadata: map[string]any{"lecture_id": lectureID}}
+	}
+	parts := model.MessageParts{
+		{Type: "reasoning", Text: "hidden"},
+		{Type: "text", Text: "Entropy grows"},
+		{Type: "data-reference", Data: &model.ReferencePart{Type: "reference", Reference: slide("3", "l2")}},
+		{Type: "text", Text: " and "},
+		{Type: "data-reference", Data: &model.ReferencePart{Type: "reference", Reference: slide("4", "l9")}},
+		{Type: "dynamic-tool", ToolCallID: "c1", ToolName: "search"},
+		{Type: "source-url", URL: "https://example.com"},
+		{Type: "source-document", SourceID: "s1", Title: "Notes"},
+		{Type: "data-reference", Data: &model.ReferencePart{Type: "reference", Reference: &model.Reference{Type: "page", ID: "1"}}},
+	}
+
+	tests := []struct {
+		scope model.ChatScope
+		want  string
+	}{
+		{model.ChatScope{LectureID: "l2"}, "Entropy grows [Slide 3] and [Slide 4] [Source: https://example.com] [Source: Notes]"},
+		{model.ChatScope{CourseID: "c1"}, "Entropy grows [Slide 3, Thermodynamics] and [Slide 4] [Source: https://example.com] [Source: Notes]"},
+	}
+	for _, tt := range tests {
+		if got := s.newCitationWriter(context.Background(), tt.scope).content(parts); got != tt.want {
+			t.Errorf("content in %+v = %q, want %q", tt.scope, got, tt.want)
+		}
+	}
+}
+
+func testChatExport() *model.ChatExport {
+	lectureTitle := "Lecture <1>"
+	created := time.Date(2025, 1, 2, 15, 4, 0, 0, time.UTC)
+	return &model.ChatExport{
+		ID:           "chat",
+		Title:        "Recap <script>alert(1)</script>",
+		LectureTitle: &lectureTitle,
+		CreatedAt:    created,
+		ExportedAt:   created.Add(time.Hour),
+		Messages: []model.ExportMessage{
+			{Role: "user", Content: "  What is <b>entropy</b>?\n", CreatedAt: created},
+			{Role: "assistant", Model: "gpt-4o", Status: model.MessageStatusComplete, Content: "Disorder [Slide 3]", CreatedAt: created.Add(time.Minute)},
+			{Role: "assistant", Model: "gpt-4o", Status: model.MessageStatusStopped, Content: "Partial", CreatedAt: created.Add(2 * time.Minute)},
+		},
+	}
+}
+
+func TestRenderChatMarkdown(t *testing.T) {
+	want := `# Recap <script>alert(1)</script>
+
+- Lecture: Lecture <1>
+- Created: 2025-01-02 15:04 UTC
+- Exported: 2025-01-02 16:04 UTC
+
+---
+
+### You · 2025-01-02 15:04 UTC
+
+What is <b>entropy</b>?
+
+---
+
+### Assistant · gpt-4o · 2025-01-02 15:05 UTC
+
+Disorder [Slide 3]
+
+---
+
+### Assistant · gpt-4o · 2025-01-02 15:06 UTC (stopped)
+
+Partial
+`
+	if got := string(renderChatMarkdown(testChatExport())); got != want {
+		t.Errorf("renderChatMarkdown =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderChatHTML(t *testing.T) {
+	body, err := renderChatHTML(testChatExport())
+	if err != nil {
+		t.Fatalf("renderChatHTML error = %v", err)
+	}
+	got := string(body)
+
+	for _, want := range []string{
+		"<title>Recap &lt;script&gt;alert(1)&lt;/script&gt;</title>",
+		"<h1>Recap &lt;script&gt;alert(1)&lt;/script&gt;</h1>",
+		"<li>Lecture: Lecture &lt;1&gt;</li>",
+		"<li>Created: 2025-01-02 15:04 UTC</li>",
+		`<section class="message user">`,
+		`<div class="content">What is &lt;b&gt;entropy&lt;/b&gt;?</div>`,
+		"<h2>Assistant · gpt-4o · 2025-01-02 15:06 UTC (stopped)</h2>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("renderChatHTML output lacks %q", want)
+		}
+	}
+	for _, unwanted := range []string{"<script>", "<b>", "Course:"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("renderChatHTML output contains %q", unwanted)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"app/internal/model"
+)
+
+// Tool part states, as in the AI SDK
+const (
+	toolStateInputAvailable  = "input-available"
+	toolStateOutputAvailable = "output-available"
+)
+
+// replyWriter turns Python stream chunks into AI SDK UI stream parts on the stream, and collects
+// the message parts to persist in the order they arrived. Consecutive text or reasoning chunks
+// form one block, which is closed when a chunk of another type arrives.
+type replyWriter struct {
+	stream   *ChatStream
+	idPrefix string
+	seq      int
+	parts    model.MessageParts
+
+	// The text or reasoning block being streamed, if any
+	blockType string
+	blockID   string
+	block     strings.Builder
+}
+
+func newReplyWriter(stream *ChatStream, idPrefix string) *replyWriter {
+	return &replyWriter{stream: stream, idPrefix: idPrefix}
+}
+
+func (w *replyWriter) nextID() string {
+	w.seq++
+	return fmt.Sprintf("%s_%d", w.idPrefix, w.seq)
+}
+
+// write forwards a chunk. Chunks missing the fields their type requires, or of an unknown type,
+// are rejected and nothing is sent.
+func (w *replyWriter) write(chunk *ChatStreamChunk) error {
+	switch chunk.Type {
+	case ChatChunkText, ChatChunkReasoning:
+		if chunk.Content != "" {
+			w.delta(chunk.Type, chunk.Content)
+		}
+	case ChatChunkReference:
+		if chunk.Reference == nil {
+			return fmt.Errorf("reference chunk without reference")
+		}
+		w.reference(chunk.Reference)
+	case ChatChunkSource:
+		if chunk.Source == nil || chunk.Source.ID == "" {
+			return fmt.Errorf("source chunk without source id")
+		}
+		w.source(chunk.Source)
+	case ChatChunkToolCall:
+		if chunk.Tool == nil || chunk.Tool.CallID == "" {
+			return fmt.Errorf("tool-call chunk without call id")
+		}
+		w.toolCall(chunk.Tool)
+	case ChatChunkToolResult:
+		if chunk.Tool == nil || chunk.Tool.CallID == "" {
+			return fmt.Errorf("tool-result chunk without call id")
+		}
+		w.toolResult(chunk.Tool)
+	default:
+		return fmt.Errorf("unknown chunk type %q", chunk.Type)
+	}
+	return nil
+}
+
+// delta appends to the open text or reasoning block, starting a new one if the type changed.
+func (w *replyWriter) delta(blockType, content string) {
+	if w.blockType != blockType {
+		w.closeBlock()
+		w.blockType = blockType
+		w.blockID = w.nextID()
+		w.stream.append(map[string]interface{}{
+			"type": blockType + "-start",
+			"id":   w.blockID,
+		})
+	}
+	w.block.WriteString(content)
+	w.stream.append(map[string]interface{}{
+		"type":  blockType + "-delta",
+		"id":    w.blockID,
+		"delta": content,
+	})
+}
+
+func (w *replyWriter) closeBlock() {
+	if w.blockType == "" {
+		return
+	}
+	w.stream.append(map[string]interface{}{
+		"type": w.blockType + "-end",
+		"id":   w.blockID,
+	})
+	w.parts = append(w.parts, model.MessagePart{Type: w.blockType, Text: w.block.String()})
+	w.blockType, w.blockID = "", ""
+	w.block.Reset()
+}
+
+func (w *replyWriter) reference(ref *model.ReferencePart) {
+	w.closeBlock()
+	w.stream.append(map[string]interface{}{
+		"type": "data-reference",
+		"id":   w.nextID(),
+		"data": ref,
+	})
+	w.parts = append(w.parts, model.MessagePart{Type: "data-reference", Data: ref})
+}
+
+func (w *replyWriter) source(src *ChatSourceChunk) {
+	w.closeBlock()
+	if src.URL != "" {
+		w.stream.append(map[string]interface{}{
+			"type":     "source-url",
+			"sourceId": src.ID,
+			"url":      src.URL,
+			"title":    src.Title,
+		})
+		w.parts = append(w.parts, model.MessagePart{Type: "source-url", SourceID: src.ID, URL: src.URL, Title: src.Title})
+		return
+	}
+
+	mediaType := src.MediaType
+	if mediaType == "" {
+		mediaType = "application/pdf"
+	}
+	w.stream.append(map[string]interface{}{
+		"type":      "source-document",
+		"sourceId":  src.ID,
+		"mediaType": mediaType,
+		"title":     src.Title,
+		"filename":  src.Filename,
+	})
+	w.parts = append(w.parts, model.MessagePart{
+		Type:      "source-document",
+		SourceID:  src.ID,
+		MediaType: mediaType,
+		Title:     src.Title,
+		Filename:  src.Filename,
+	})
+}
+
+func (w *replyWriter) toolCall(tool *ChatToolChunk) {
+	w.closeBlock()
+	w.stream.append(map[string]interface{}{
+		"type":       "tool-input-available",
+		"toolCallId": tool.CallID,
+		"toolName":   tool.Name,
+		"input":      tool.Input,
+		"dynamic":    true,
+	})
+	w.parts = append(w.parts, model.MessagePart{
+		Type:       "dynamic-tool",
+		ToolCallID: tool.CallID,
+		ToolName:   tool.Name,
+		State:      toolStateInputAvailable,
+		Input:      tool.Input,
+	})
+}
+
+// toolResult completes the persisted part of the call it answers, so a call and its output are
+// saved as one part like the AI SDK keeps them.
+func (w *replyWriter) toolResult(tool *ChatToolChunk) {
+	w.closeBlock()
+	w.stream.append(map[string]interface{}{
+		"type":       "tool-output-available",
+		"toolCallId": tool.CallID,
+		"output":     tool.Output,
+		"dynamic":    true,
+	})
+	for i := range w.parts {
+		if w.parts[i].Type == "dynamic-tool" && w.parts[i].ToolCallID == tool.CallID {
+			w.parts[i].State = toolStateOutputAvailable
+			w.parts[i].Output = tool.Output
+			return
+		}
+	}
+	w.parts = append(w.parts, model.MessagePart{
+		Type:       "dynamic-tool",
+		ToolCallID: tool.CallID,
+		ToolName:   tool.Name,
+		State:      toolStateOutputAvailable,
+		Output:     tool.Output,
+	})
+}
+
+// close ends any open block and returns the parts to persist.
+func (w *replyWriter) close() model.MessageParts {
+	w.closeBlock()
+	return w.parts
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"app/internal/model"
+)
+
+// writeReply feeds chunks to a replyWriter and returns the stream parts it sent, as JSON, and
+// the message parts it would persist.
+func writeReply(t *testing.T, chunks ...*ChatStreamChunk) ([]string, model.MessageParts) {
+	t.Helper()
+	stream := newChatStream(model.ChatScope{}, "chat", "user", func() {})
+	w := newReplyWriter(stream, "p")
+	for _, chunk := range chunks {
+		if err := w.write(chunk); err != nil {
+			t.Fatalf("write(%+v) error = %v", chunk, err)
+		}
+	}
+	parts := w.close()
+	events, _, _ := stream.Next(0)
+	sent := make([]string, len(events))
+	for i, event := range events {
+		sent[i] = string(event.Data)
+	}
+	return sent, parts
+}
+
+func TestReplyWriterBlocks(t *testing.T) {
+	sent, parts := writeReply(t,
+		&ChatStreamChunk{Type: ChatChunkReasoning, Content: "Thinking"},
+		&ChatStreamChunk{Type: ChatChunkText, Content: "Hel"},
+		&ChatStreamChunk{Type: ChatChunkText, Content: ""},
+		&ChatStreamChunk{Type: ChatChunkText, Content: "lo"},
+		&ChatStreamChunk{Type: ChatChunkReasoning, Content: "More"},
+		&ChatStreamChunk{Type: ChatChunkText, Content: "!"},
+	)
+
+	wantSent := []string{
+		`{"id":"p_1","type":"reasoning-start"}`,
+		`{"delta":"Thinking","id":"p_1","type":"reasoning-delta"}`,
+		`{"id":"p_1","type":"reasoning-end"}`,
+		`{"id":"p_2","type":"text-start"}`,
+		`{"delta":"Hel","id":"p_2","type":"text-delta"}`,
+		`{"delta":"lo","id":"p_2","type":"text-delta"}`,
+		`{"id":"p_2","type":"text-end"}`,
+		`{"id":"p_3","type":"reasoning-start"}`,
+		`{"delta":"More","id":"p_3","type":"reasoning-delta"}`,
+		`{"id":"p_3","type":"reasoning-end"}`,
+		`{"id":"p_4","type":"text-start"}`,
+		`{"delta":"!","id":"p_4","type":"text-delta"}`,
+		`{"id":"p_4","type":"text-end"}`,
+	}
+	if !reflect.DeepEqual(sent, wantSent) {
+		t.Errorf("sent parts =\n%v\nwant\n%v", sent, wantSent)
+	}
+
+	wantParts := model.MessageParts{
+		{Type: "reasoning", Text: "Thinking"},
+		{Type: "text", Text: "Hello"},
+		{Type: "reasoning", Text: "More"},
+		{Type: "text", Text: "!"},
+	}
+	if !reflect.DeepEqual(parts, wantParts) {
+		t.Errorf("parts = %+v, want %+v", parts, wantParts)
+	}
+}
+
+func TestReplyWriterSources(t *testing.T) {
+	sent, parts := writeReply(t,
+		&ChatStreamChunk{Type: ChatChunkText, Content: "See"},
+		&ChatStreamChunk{Type: ChatChunkSource, Source: &ChatSourceChunk{ID: "s1", URL: "https://example.com", Title: "Example"}},
+		&ChatStreamChunk{Type: ChatChunkSource, Source: &ChatSourceChunk{ID: "s2", Title: "Notes", Filename: "notes.pdf"}},
+		&ChatStreamChunk{Type: ChatChunkSource, Source: &ChatSourceChunk{ID: "s3", MediaType: "image/png", Filename: "figure.png"}},
+	)
+
+	wantSent := []string{
+		`{"id":"p_1","type":"text-start"}`,
+		`{"delta":"See","id":"p_1","type":"text-delta"}`,
+		`{"id":"p_1","type":"text-end"}`,
+		`{"sourceId":"s1","title":"Example","type":"source-url","url":"https://example.com"}`,
+		`{"filename":"notes.pdf","mediaType":"application/pdf","sourceId":"s2","title":"Notes","type":"source-document"}`,
+		`{"filename":"figure.png","mediaType":"image/png","sourceId":"s3","title":"","type":"source-document"}`,
+	}
+	if !reflect.DeepEqual(sent, wantSent) {
+		t.Errorf("sent parts =\n%v\nwant\n%v", sent, wantSent)
+	}
+
+	wantParts := model.MessageParts{
+		{Type: "text", Text: "See"},
+		{Type: "source-url", SourceID: "s1", URL: "https://example.com", Title: "Example"},
+		{Type: "source-document", SourceID: "s2", MediaType: "application/pdf", Title: "Notes", Filename: "notes.pdf"},
+		{Type: "source-document", SourceID: "s3", MediaType: "image/png", Filename: "figure.png"},
+	}
+	if !reflect.DeepEqual(parts, wantParts) {
+		t.Errorf("parts = %+v, want %+v", parts, wantParts)
+	}
+}
+
+func TestReplyWriterReference(t *testing.T) {
+	ref := &model.ReferencePart{Type: model.ReferenceTypeSlide, Text: "Slide 3"}
+	sent, parts := writeReply(t,
+		&ChatStreamChunk{Type: ChatChunkText, Content: "As shown"},
+		&ChatStreamChunk{Type: ChatChunkReference, Reference: ref},
+	)
+
+	wantSent := []string{
+		`{"id":"p_1","type":"text-start"}`,
+		`{"delta":"As shown","id":"p_1","type":"text-delta"}`,
+		`{"id":"p_1","type":"text-end"}`,
+		`{"data":{"type":"slide","text":"Slide 3"},"id":"p_2","type":"data-reference"}`,
+	}
+	if !reflect.DeepEqual(sent, wantSent) {
+		t.Errorf("sent parts =\n%v\nwant\n%v", sent, wantSent)
+	}
+
+	wantParts := model.MessageParts{
+		{Type: "text", Text: "As shown"},
+		{Type: "data-reference", Data: ref},
+	}
+	if !reflect.DeepEqual(parts, wantParts) {
+		t.Errorf("parts = %+v, want %+v", parts, wantParts)
+	}
+}
+
+func TestReplyWriterTools(t *testing.T) {
+	sent, parts := writeReply(t,
+		&ChatStreamChunk{Type: ChatChunkToolCall, Tool: &ChatToolChunk{CallID: "c1", Name: "search", Input: map[string]interface{}{"q": "entropy"}}},
+		&ChatStreamChunk{Type: ChatChunkToolCall, Tool: &ChatToolChunk{CallID: "c2", Name: "search", Input: "b"}},
+		&ChatStreamChunk{Type: ChatChunkToolResult, Tool: &ChatToolChunk{CallID: "c1", Output: "found"}},
+		// A result whose call was never sent is still kept
+		&ChatStreamChunk{Type: ChatChunkToolResult, Tool: &ChatToolChunk{CallID: "c3", Name: "lookup", Output: "orphan"}},
+	)
+
+	wantSent := []string{
+		`{"dynamic":true,"input":{"q":"entropy"},"toolCallId":"c1","toolName":"search","type":"tool-input-available"}`,
+		`{"dynamic":true,"input":"b","toolCallId":"c2","toolName":"search","type":"tool-input-available"}`,
+		`{"dynamic":true,"output":"found","toolCallId":"c1","type":"tool-output-available"}`,
+		`{"dynamic":true,"output":"orphan","toolCallId":"c3","type":"tool-output-available"}`,
+	}
+	if !reflect.DeepEqual(sent, wantSent) {
+		t.Errorf("sent parts =\n%v\nwant\n%v", sent, wantSent)
+	}
+
+	wantParts := model.MessageParts{
+		{Type: "dynamic-tool", ToolCallID: "c1", ToolName: "search", State: toolStateOutputAvailable, Input: map[string]interface{}{"q": "entropy"}, Output: "found"},
+		{Type: "dynamic-tool", ToolCallID: "c2", ToolName: "search", State: toolStateInputAvailable, Input: "b"},
+		{Type: "dynamic-tool", ToolCallID: "c3", ToolName: "lookup", State: toolStateOutputAvailable, Output: "orphan"},
+	}
+	if !reflect.DeepEqual(parts, wantParts) {
+		t.Errorf("parts = %+v, want %+v", parts, wantParts)
+	}
+}
+
+func TestReplyWriterRejectsInvalidChunks(t *testing.T) {
+	tests := []*ChatStreamChunk{
+		{Type: ChatChunkReference},
+		{Type: ChatChunkSource},
+		{Type: ChatChunkSource, Source: &ChatSourceChunk{URL: "https://example.com"}},
+		{Type: ChatChunkToolCall},
+		{Type: ChatChunkToolCall, Tool: &ChatToolChunk{Name: "search"}},
+		{Type: ChatChunkToolResult, Tool: &ChatToolChunk{Output: "x"}},
+		{Type: "image", Content: "data"},
+	}
+	for _, chunk := range tests {
+		stream := newChatStream(model.ChatScope{}, "chat", "user", func() {})
+		w := newReplyWriter(stream, "p")
+		if err := w.write(chunk); err == nil {
+			t.Errorf("write(%+v) error = nil, want an error", chunk)
+		}
+		if events, _, _ := stream.Next(0); len(events) != 0 {
+			t.Errorf("write(%+v) sent %d parts, want none", chunk, len(events))
+		}
+		if parts := w.close(); len(parts) != 0 {
+			t.Errorf("write(%+v) kept %d parts, want none", chunk, len(parts))
+		}
+	}
+}
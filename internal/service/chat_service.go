@@ -5,61 +5,91 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 
 	"app/internal/model"
 	"app/internal/repository"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog"
 )
 
 var (
-	ErrChatNotFound    = errors.New("chat not found")
-	ErrLectureNotFound = errors.New("lecture not found")
-	ErrUnauthorized    = errors.New("unauthorized access")
+	ErrChatNotFound        = errors.New("chat not found")
+	ErrLectureNotFound     = errors.New("lecture not found")
+	ErrCourseNotFound      = errors.New("course not found")
+	ErrUnauthorized        = errors.New("unauthorized access")
+	ErrMessageNotFound     = errors.New("message not found")
+	ErrMessageNotEditable  = errors.New("only user messages can be edited")
+	ErrNothingToRegenerate = errors.New("no assistant reply to regenerate")
 )
 
+// chatHistoryLimit caps how many earlier messages of the active branch are sent as context.
+const chatHistoryLimit = 100
+
 type ChatService interface {
 	CreateChat(ctx context.Context, lectureID, userID, title string) (*model.Chat, error)
-	GetChat(ctx context.Context, chatID, userID string) (*model.Chat, error)
+	// CreateCourseChat creates a chat that answers from every lecture of the course.
+	CreateCourseChat(ctx context.Context, courseID, userID, title string) (*model.Chat, error)
+	// The methods below that take a scope only act on chats about it, which is the lecture or course
+	// the request was made under. Chats about anything else are reported as ErrChatNotFound.
+	GetChat(ctx context.Context, scope model.ChatScope, chatID, userID string) (*model.Chat, error)
 	ListChats(ctx context.Context, lectureID, userID string, limit, offset int) ([]model.Chat, error)
-	UpdateChat(ctx context.Context, chatID, userID, title string) (*model.Chat, error)
-	DeleteChat(ctx context.Context, chatID, userID string) error
-	CreateMessage(ctx context.Context, chatID, userID, role string, parts model.MessageParts, metadata map[string]interface{}) (*model.Message, error)
-	ListMessages(ctx context.Context, chatID, userID string, limit int) ([]model.Message, error)
+	ListCourseChats(ctx context.Context, courseID, userID string, limit, offset int) ([]model.Chat, error)
+	UpdateChat(ctx context.Context, scope model.ChatScope, chatID, userID, title string) (*model.Chat, error)
+	DeleteChat(ctx context.Context, scope model.ChatScope, chatID, userID string) error
+	CreateMessage(ctx context.Context, scope model.ChatScope, chatID, userID, role string, parts model.MessageParts, metadata map[string]interface{}) (*model.Message, error)
+	CreateReply(ctx context.Context, scope model.ChatScope, chatID, userID, parentID string, parts model.MessageParts, metadata map[string]interface{}) (*model.Message, error)
+	EditMessage(ctx context.Context, scope model.ChatScope, chatID, userID, messageID string, parts model.MessageParts, metadata map[string]interface{}) (*model.Message, error)
+	GetRegenerationTarget(ctx context.Context, scope model.ChatScope, chatID, userID, messageID string) (*model.Message, error)
+	SwitchBranch(ctx context.Context, scope model.ChatScope, chatID, userID, messageID string) error
+	ListMessages(ctx context.Context, scope model.ChatScope, chatID, userID string, limit int) ([]model.Message, error)
 	GetMessageCount(ctx context.Context, chatID, userID string) (int, error)
-	StreamChatResponse(ctx context.Context, lectureID, chatID, userID string, messageParts model.MessageParts, model string) (io.ReadCloser, error)
-	GenerateAndUpdateTitle(ctx context.Context, lectureID, chatID, userID string, userMessageParts model.MessageParts, assistantMessageParts model.MessageParts)
+	// ExportChat renders the chat's active branch as a downloadable file in the given format,
+	// one of model.ChatExportMarkdown, model.ChatExportHTML or model.ChatExportJSON.
+	ExportChat(ctx context.Context, scope model.ChatScope, chatID, userID, format string) (*ChatExportFile, error)
+	StreamChatResponse(ctx context.Context, scope model.ChatScope, chatID, userID string, userMessage *model.Message, modelName string) (io.ReadCloser, error)
+	StartReply(ctx context.Context, scope model.ChatScope, chatID, userID string, userMessage *model.Message, modelName string) (*ChatStream, error)
+	GetChatStream(scope model.ChatScope, streamID, chatID, userID string) (*ChatStream, error)
+	StopReply(ctx context.Context, scope model.ChatScope, chatID, userID string) error
+	GenerateAndUpdateTitle(ctx context.Context, scope model.ChatScope, chatID, userID string, userMessageParts model.MessageParts, assistantMessageParts model.MessageParts)
 }
 
 type chatService struct {
 	chatRepo     repository.ChatRepository
 	lectureRepo  repository.LectureRepository
+	courseRepo   repository.CourseRepository
 	pythonClient PythonClient
 	logger       zerolog.Logger
+
+	streamsMu sync.Mutex
+	streams   map[string]*ChatStream // by stream ID, kept briefly after finishing for resumption
+	// activeStreams holds the reply being generated for each chat, by chat ID, so it can be stopped.
+	activeStreams map[string]*ChatStream
 }
 
 func NewChatService(
 	chatRepo repository.ChatRepository,
 	lectureRepo repository.LectureRepository,
+	courseRepo repository.CourseRepository,
 	pythonClient PythonClient,
 	logger zerolog.Logger,
 ) ChatService {
 	return &chatService{
-		chatRepo:     chatRepo,
-		lectureRepo:  lectureRepo,
-		pythonClient: pythonClient,
-		logger:       logger.With().Str("service", "ChatService").Logger(),
+		chatRepo:      chatRepo,
+		lectureRepo:   lectureRepo,
+		courseRepo:    courseRepo,
+		pythonClient:  pythonClient,
+		logger:        logger.With().Str("service", "ChatService").Logger(),
+		streams:       make(map[string]*ChatStream),
+		activeStreams: make(map[string]*ChatStream),
 	}
 }
 
 func (s *chatService) CreateChat(ctx context.Context, lectureID, userID, title string) (*model.Chat, error) {
 	// Verify lecture exists and user owns it
-	lecture, err := s.lectureRepo.GetLectureByID(ctx, lectureID)
-	if err != nil {
-		return nil, fmt.Errorf("lecture not found: %w", err)
-	}
-	if lecture.UserID != userID {
-		return nil, ErrUnauthorized
+	if err := s.verifyScopeAccess(ctx, model.ChatScope{LectureID: lectureID}, userID); err != nil {
+		return nil, err
 	}
 
 	if title == "" {
@@ -75,22 +105,32 @@ func (s *chatService) CreateChat(ctx context.Context, lectureID, userID, title s
 	return chat, nil
 }
 
-func (s *chatService) GetChat(ctx context.Context, chatID, userID string) (*model.Chat, error) {
-	chat, err := s.chatRepo.GetChat(ctx, chatID, userID)
+func (s *chatService) CreateCourseChat(ctx context.Context, courseID, userID, title string) (*model.Chat, error) {
+	if err := s.verifyScopeAccess(ctx, model.ChatScope{CourseID: courseID}, userID); err != nil {
+		return nil, err
+	}
+
+	if title == "" {
+		title = "New Chat"
+	}
+
+	chat, err := s.chatRepo.CreateCourseChat(ctx, courseID, userID, title)
 	if err != nil {
-		return nil, fmt.Errorf("getting chat: %w", err)
+		s.logger.Error().Err(err).Str("course_id", courseID).Str("user_id", userID).Msg("Failed to create course chat")
+		return nil, fmt.Errorf("creating course chat: %w", err)
 	}
+
 	return chat, nil
 }
 
+func (s *chatService) GetChat(ctx context.Context, scope model.ChatScope, chatID, userID string) (*model.Chat, error) {
+	return s.verifyChatAccess(ctx, scope, chatID, userID)
+}
+
 func (s *chatService) ListChats(ctx context.Context, lectureID, userID string, limit, offset int) ([]model.Chat, error) {
 	// Verify lecture exists and user owns it
-	lecture, err := s.lectureRepo.GetLectureByID(ctx, lectureID)
-	if err != nil {
-		return nil, fmt.Errorf("lecture not found: %w", err)
-	}
-	if lecture.UserID != userID {
-		return nil, ErrUnauthorized
+	if err := s.verifyScopeAccess(ctx, model.ChatScope{LectureID: lectureID}, userID); err != nil {
+		return nil, err
 	}
 
 	chats, err := s.chatRepo.ListChats(ctx, lectureID, userID, limit, offset)
@@ -102,20 +142,24 @@ func (s *chatService) ListChats(ctx context.Context, lectureID, userID string, l
 	return chats, nil
 }
 
-func (s *chatService) UpdateChat(ctx context.Context, chatID, userID, title string) (*model.Chat, error) {
-	// Verify chat ownership
-	chat, err := s.chatRepo.GetChat(ctx, chatID, userID)
-	if err != nil {
-		return nil, fmt.Errorf("chat not found: %w", err)
+func (s *chatService) ListCourseChats(ctx context.Context, courseID, userID string, limit, offset int) ([]model.Chat, error) {
+	if err := s.verifyScopeAccess(ctx, model.ChatScope{CourseID: courseID}, userID); err != nil {
+		return nil, err
 	}
 
-	// Verify lecture ownership
-	lecture, err := s.lectureRepo.GetLectureByID(ctx, chat.LectureID)
+	chats, err := s.chatRepo.ListCourseChats(ctx, courseID, userID, limit, offset)
 	if err != nil {
-		return nil, fmt.Errorf("lecture not found: %w", err)
+		s.logger.Error().Err(err).Str("course_id", courseID).Msg("Failed to list course chats")
+		return nil, fmt.Errorf("listing course chats: %w", err)
 	}
-	if lecture.UserID != userID {
-		return nil, ErrUnauthorized
+
+	return chats, nil
+}
+
+func (s *chatService) UpdateChat(ctx context.Context, scope model.ChatScope, chatID, userID, title string) (*model.Chat, error) {
+	// Verify chat ownership
+	if _, err := s.verifyChatAccess(ctx, scope, chatID, userID); err != nil {
+		return nil, err
 	}
 
 	updatedChat, err := s.chatRepo.UpdateChat(ctx, chatID, userID, title)
@@ -127,23 +171,12 @@ func (s *chatService) UpdateChat(ctx context.Context, chatID, userID, title stri
 	return updatedChat, nil
 }
 
-func (s *chatService) DeleteChat(ctx context.Context, chatID, userID string) error {
-	chat, err := s.chatRepo.GetChat(ctx, chatID, userID)
-	if err != nil {
-		return fmt.Errorf("chat not found: %w", err)
-	}
-
-	// Verify lecture ownership
-	lecture, err := s.lectureRepo.GetLectureByID(ctx, chat.LectureID)
-	if err != nil {
-		return fmt.Errorf("lecture not found: %w", err)
-	}
-	if lecture.UserID != userID {
-		return ErrUnauthorized
+func (s *chatService) DeleteChat(ctx context.Context, scope model.ChatScope, chatID, userID string) error {
+	if _, err := s.verifyChatAccess(ctx, scope, chatID, userID); err != nil {
+		return err
 	}
 
-	err = s.chatRepo.DeleteChat(ctx, chatID, userID)
-	if err != nil {
+	if err := s.chatRepo.DeleteChat(ctx, chatID, userID); err != nil {
 		s.logger.Error().Err(err).Str("chat_id", chatID).Msg("Failed to delete chat")
 		return fmt.Errorf("deleting chat: %w", err)
 	}
@@ -151,45 +184,188 @@ func (s *chatService) DeleteChat(ctx context.Context, chatID, userID string) err
 	return nil
 }
 
-func (s *chatService) CreateMessage(ctx context.Context, chatID, userID, role string, parts model.MessageParts, metadata map[string]interface{}) (*model.Message, error) {
+func (s *chatService) CreateMessage(ctx context.Context, scope model.ChatScope, chatID, userID, role string, parts model.MessageParts, metadata map[string]interface{}) (*model.Message, error) {
 	// Verify chat ownership
-	chat, err := s.chatRepo.GetChat(ctx, chatID, userID)
+	if _, err := s.verifyChatAccess(ctx, scope, chatID, userID); err != nil {
+		return nil, err
+	}
+
+	message, err := s.chatRepo.CreateMessage(ctx, chatID, role, parts, metadata)
 	if err != nil {
-		return nil, fmt.Errorf("chat not found: %w", err)
+		s.logger.Error().Err(err).Str("chat_id", chatID).Msg("Failed to create message")
+		return nil, fmt.Errorf("creating message: %w", err)
+	}
+
+	return message, nil
+}
+
+// CreateReply saves an assistant reply directly under the user message it answers, so a reply
+// that finishes after the user has switched branches still lands in the right place.
+func (s *chatService) CreateReply(ctx context.Context, scope model.ChatScope, chatID, userID, parentID string, parts model.MessageParts, metadata map[string]interface{}) (*model.Message, error) {
+	if _, err := s.verifyChatAccess(ctx, scope, chatID, userID); err != nil {
+		return nil, err
 	}
 
-	// Verify lecture ownership
-	lecture, err := s.lectureRepo.GetLectureByID(ctx, chat.LectureID)
+	message, err := s.chatRepo.CreateBranchMessage(ctx, chatID, &parentID, "assistant", parts, metadata)
 	if err != nil {
-		return nil, fmt.Errorf("lecture not found: %w", err)
+		s.logger.Error().Err(err).Str("chat_id", chatID).Str("parent_id", parentID).Msg("Failed to create reply")
+		return nil, fmt.Errorf("creating reply: %w", err)
 	}
-	if lecture.UserID != userID {
-		return nil, ErrUnauthorized
+	return message, nil
+}
+
+// EditMessage saves an edited copy of a user message as a new branch alongside the original,
+// which is kept with its replies so the user can switch back to it.
+func (s *chatService) EditMessage(ctx context.Context, scope model.ChatScope, chatID, userID, messageID string, parts model.MessageParts, metadata map[string]interface{}) (*model.Message, error) {
+	original, err := s.getMessage(ctx, scope, chatID, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if original.Role != "user" {
+		return nil, ErrMessageNotEditable
 	}
 
-	message, err := s.chatRepo.CreateMessage(ctx, chatID, role, parts, metadata)
+	message, err := s.chatRepo.CreateBranchMessage(ctx, chatID, original.ParentID, "user", parts, metadata)
 	if err != nil {
-		s.logger.Error().Err(err).Str("chat_id", chatID).Msg("Failed to create message")
-		return nil, fmt.Errorf("creating message: %w", err)
+		s.logger.Error().Err(err).Str("chat_id", chatID).Str("message_id", messageID).Msg("Failed to create edited message")
+		return nil, fmt.Errorf("creating edited message: %w", err)
 	}
+	return message, nil
+}
 
+// GetRegenerationTarget returns the user message whose reply should be regenerated. messageID names
+// the assistant reply to replace; when empty, the last reply on the active branch is used. A branch
+// ending in an unanswered user message regenerates that message's reply.
+func (s *chatService) GetRegenerationTarget(ctx context.Context, scope model.ChatScope, chatID, userID, messageID string) (*model.Message, error) {
+	var reply *model.Message
+	if messageID != "" {
+		message, err := s.getMessage(ctx, scope, chatID, userID, messageID)
+		if err != nil {
+			return nil, err
+		}
+		reply = message
+	} else {
+		messages, err := s.ListMessages(ctx, scope, chatID, userID, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(messages) == 0 {
+			return nil, ErrNothingToRegenerate
+		}
+		reply = &messages[0]
+	}
+
+	if reply.Role == "user" {
+		return reply, nil
+	}
+	if reply.ParentID == nil {
+		return nil, ErrNothingToRegenerate
+	}
+	prompt, err := s.chatRepo.GetMessage(ctx, chatID, *reply.ParentID)
+	if err != nil {
+		return nil, fmt.Errorf("getting message: %w", err)
+	}
+	if prompt == nil || prompt.Role != "user" {
+		return nil, ErrNothingToRegenerate
+	}
+	return prompt, nil
+}
+
+// SwitchBranch makes the branch containing messageID the active one, following it down to its
+// most recent message.
+func (s *chatService) SwitchBranch(ctx context.Context, scope model.ChatScope, chatID, userID, messageID string) error {
+	if _, err := s.verifyChatAccess(ctx, scope, chatID, userID); err != nil {
+		return err
+	}
+
+	found, err := s.chatRepo.SetActiveBranch(ctx, chatID, messageID)
+	if err != nil {
+		s.logger.Error().Err(err).Str("chat_id", chatID).Str("message_id", messageID).Msg("Failed to switch branch")
+		return fmt.Errorf("switching branch: %w", err)
+	}
+	if !found {
+		return ErrMessageNotFound
+	}
+	return nil
+}
+
+// getMessage returns a message of a chat the user owns.
+func (s *chatService) getMessage(ctx context.Context, scope model.ChatScope, chatID, userID, messageID string) (*model.Message, error) {
+	if _, err := s.verifyChatAccess(ctx, scope, chatID, userID); err != nil {
+		return nil, err
+	}
+
+	message, err := s.chatRepo.GetMessage(ctx, chatID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("getting message: %w", err)
+	}
+	if message == nil {
+		return nil, ErrMessageNotFound
+	}
 	return message, nil
 }
 
-func (s *chatService) ListMessages(ctx context.Context, chatID, userID string, limit int) ([]model.Message, error) {
-	// Verify chat ownership
+// verifyChatAccess checks that the user owns both the chat and the lecture or course it is about,
+// and that the chat is about scope, the lecture or course the request was made under.
+func (s *chatService) verifyChatAccess(ctx context.Context, scope model.ChatScope, chatID, userID string) (*model.Chat, error) {
 	chat, err := s.chatRepo.GetChat(ctx, chatID, userID)
 	if err != nil {
-		return nil, fmt.Errorf("chat not found: %w", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrChatNotFound
+		}
+		return nil, fmt.Errorf("getting chat: %w", err)
+	}
+	if chat.Scope() != scope {
+		return nil, ErrChatNotFound
 	}
+	if err := s.verifyScopeAccess(ctx, chat.Scope(), userID); err != nil {
+		return nil, err
+	}
+	return chat, nil
+}
 
-	// Verify lecture ownership
-	lecture, err := s.lectureRepo.GetLectureByID(ctx, chat.LectureID)
+// verifyScopeAccess checks that the user owns the lecture or course a chat is about.
+func (s *chatService) verifyScopeAccess(ctx context.Context, scope model.ChatScope, userID string) error {
+	if scope.CourseID != "" {
+		course, err := s.courseRepo.GetCourseByID(ctx, scope.CourseID)
+		if err != nil {
+			return fmt.Errorf("course not found: %w", err)
+		}
+		if course == nil || course.UserID != userID {
+			return ErrUnauthorized
+		}
+		return nil
+	}
+
+	lecture, err := s.lectureRepo.GetLectureByID(ctx, scope.LectureID)
 	if err != nil {
-		return nil, fmt.Errorf("lecture not found: %w", err)
+		return fmt.Errorf("lecture not found: %w", err)
 	}
-	if lecture.UserID != userID {
-		return nil, ErrUnauthorized
+	if lecture == nil || lecture.UserID != userID {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// scopeLectureIDs returns the lectures a chat answers from.
+func (s *chatService) scopeLectureIDs(ctx context.Context, scope model.ChatScope) ([]string, error) {
+	if scope.CourseID == "" {
+		return []string{scope.LectureID}, nil
+	}
+	lectureIDs, err := s.lectureRepo.GetLectureIDsByCourseID(ctx, scope.CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("listing course lectures: %w", err)
+	}
+	if lectureIDs == nil {
+		lectureIDs = []string{}
+	}
+	return lectureIDs, nil
+}
+
+func (s *chatService) ListMessages(ctx context.Context, scope model.ChatScope, chatID, userID string, limit int) ([]model.Message, error) {
+	// Verify chat ownership
+	if _, err := s.verifyChatAccess(ctx, scope, chatID, userID); err != nil {
+		return nil, err
 	}
 
 	messages, err := s.chatRepo.ListMessages(ctx, chatID, userID, limit)
@@ -209,73 +385,63 @@ func (s *chatService) GetMessageCount(ctx context.Context, chatID, userID string
 	return count, nil
 }
 
-func (s *chatService) GenerateAndUpdateTitle(ctx context.Context, lectureID, chatID, userID string, userMessageParts model.MessageParts, assistantMessageParts model.MessageParts) {
-	// Convert user message parts to map for Python service
-	userMessagePartsMap := make([]map[string]interface{}, len(userMessageParts))
-	for i, part := range userMessageParts {
-		m := map[string]interface{}{
-			"type": part.Type,
-			"text": part.Text,
-		}
-		if part.Reference != nil {
-			m["reference"] = part.Reference
-		}
-		if part.Data != nil {
-			m["data"] = part.Data
-		}
-		userMessagePartsMap[i] = m
-	}
-
-	// Convert assistant message parts to map for Python service
-	assistantMessagePartsMap := make([]map[string]interface{}, len(assistantMessageParts))
-	for i, part := range assistantMessageParts {
-		m := map[string]interface{}{
-			"type": part.Type,
-			"text": part.Text,
-		}
-		if part.Reference != nil {
-			m["reference"] = part.Reference
-		}
-		if part.Data != nil {
-			m["data"] = part.Data
-		}
-		assistantMessagePartsMap[i] = m
-	}
+func (s *chatService) GenerateAndUpdateTitle(ctx context.Context, scope model.ChatScope, chatID, userID string, userMessageParts model.MessageParts, assistantMessageParts model.MessageParts) {
+	userMessagePartsMap := messagePartsToMaps(userMessageParts)
+	assistantMessagePartsMap := messagePartsToMaps(assistantMessageParts)
 
 	// Generate title via Python service
-	title, err := s.pythonClient.GenerateChatTitle(ctx, lectureID, chatID, userID, userMessagePartsMap, assistantMessagePartsMap)
+	title, err := s.pythonClient.GenerateChatTitle(ctx, scope, chatID, userID, userMessagePartsMap, assistantMessagePartsMap)
 	if err != nil {
 		s.logger.Error().Err(err).Str("chat_id", chatID).Msg("Failed to generate chat title")
 		return
 	}
 
 	// Update chat title in database - frontend will poll to get the updated title
-	_, err = s.UpdateChat(ctx, chatID, userID, title)
+	_, err = s.UpdateChat(ctx, scope, chatID, userID, title)
 	if err != nil {
 		s.logger.Error().Err(err).Str("chat_id", chatID).Str("title", title).Msg("Failed to update chat title")
 		return
 	}
 }
 
-func (s *chatService) StreamChatResponse(ctx context.Context, lectureID, chatID, userID string, messageParts model.MessageParts, model string) (io.ReadCloser, error) {
-	// Verify chat ownership
-	chat, err := s.chatRepo.GetChat(ctx, chatID, userID)
+// StreamChatResponse streams the assistant's reply to userMessage, with the branch leading up to
+// it as context. scope is the lecture or course the request was made under, which must be the
+// one the chat is about.
+func (s *chatService) StreamChatResponse(ctx context.Context, scope model.ChatScope, chatID, userID string, userMessage *model.Message, modelName string) (io.ReadCloser, error) {
+	if _, err := s.verifyChatAccess(ctx, scope, chatID, userID); err != nil {
+		return nil, err
+	}
+	lectureIDs, err := s.scopeLectureIDs(ctx, scope)
 	if err != nil {
-		return nil, fmt.Errorf("chat not found: %w", err)
+		return nil, err
 	}
 
-	// Verify lecture ownership
-	lecture, err := s.lectureRepo.GetLectureByID(ctx, lectureID)
-	if err != nil {
-		return nil, fmt.Errorf("lecture not found: %w", err)
+	history := make([]ChatHistoryMessage, 0)
+	if userMessage.ParentID != nil {
+		earlier, err := s.chatRepo.ListMessagePath(ctx, chatID, *userMessage.ParentID, chatHistoryLimit)
+		if err != nil {
+			s.logger.Error().Err(err).Str("chat_id", chatID).Msg("Failed to load chat history")
+			return nil, fmt.Errorf("loading chat history: %w", err)
+		}
+		for _, message := range earlier {
+			history = append(history, ChatHistoryMessage{Role: message.Role, Parts: messagePartsToMaps(message.Parts)})
+		}
 	}
-	if lecture.UserID != userID || chat.LectureID != lectureID {
-		return nil, ErrUnauthorized
+
+	// Stream from Python service (Python will retrieve API key)
+	stream, err := s.pythonClient.StreamChat(ctx, scope, lectureIDs, chatID, userID, history, messagePartsToMaps(userMessage.Parts), modelName)
+	if err != nil {
+		s.logger.Error().Err(err).Str("lecture_id", scope.LectureID).Str("course_id", scope.CourseID).Str("chat_id", chatID).Msg("Failed to stream chat response")
+		return nil, fmt.Errorf("streaming chat response: %w", err)
 	}
 
-	// Convert message parts to map for JSON serialization
-	messagePartsMap := make([]map[string]interface{}, len(messageParts))
-	for i, part := range messageParts {
+	return stream, nil
+}
+
+// messagePartsToMaps converts message parts to maps for JSON serialization to the Python service.
+func messagePartsToMaps(parts model.MessageParts) []map[string]interface{} {
+	maps := make([]map[string]interface{}, len(parts))
+	for i, part := range parts {
 		m := map[string]interface{}{
 			"type": part.Type,
 			"text": part.Text,
@@ -286,15 +452,7 @@ func (s *chatService) StreamChatResponse(ctx context.Context, lectureID, chatID,
 		if part.Data != nil {
 			m["data"] = part.Data
 		}
-		messagePartsMap[i] = m
+		maps[i] = m
 	}
-
-	// Stream from Python service (Python will retrieve API key)
-	stream, err := s.pythonClient.StreamChat(ctx, lectureID, chatID, userID, messagePartsMap, model)
-	if err != nil {
-		s.logger.Error().Err(err).Str("lecture_id", lectureID).Str("chat_id", chatID).Msg("Failed to stream chat response")
-		return nil, fmt.Errorf("streaming chat response: %w", err)
-	}
-
-	return stream, nil
+	return maps
 }
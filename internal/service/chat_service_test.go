@@ -0,0 +1,32 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"app/internal/model"
+)
+
+func TestMessagePartsToMaps(t *testing.T) {
+	ref := &model.Reference{Type: model.ReferenceTypeSlide, ID: "3"}
+	data := &model.ReferencePart{Type: "reference", Reference: ref}
+	parts := model.MessageParts{
+		{Type: "text", Text: "Explain slide 3"},
+		{Type: "reference", Reference: ref},
+		{Type: "data-reference", Data: data},
+		{Type: "dynamic-tool", ToolCallID: "c1", ToolName: "search"},
+	}
+
+	want := []map[string]interface{}{
+		{"type": "text", "text": "Explain slide 3"},
+		{"type": "reference", "text": "", "reference": ref},
+		{"type": "data-reference", "text": "", "data": data},
+		{"type": "dynamic-tool", "text": ""},
+	}
+	if got := messagePartsToMaps(parts); !reflect.DeepEqual(got, want) {
+		t.Errorf("messagePartsToMaps = %v, want %v", got, want)
+	}
+	if got := messagePartsToMaps(nil); len(got) != 0 {
+		t.Errorf("messagePartsToMaps(nil) = %v, want none", got)
+	}
+}
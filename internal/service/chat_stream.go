@@ -0,0 +1,362 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"app/internal/logger"
+	"app/internal/model"
+)
+
+const (
+	// chatStreamTimeout bounds how long a reply may keep generating with nobody necessarily listening.
+	chatStreamTimeout = 10 * time.Minute
+	// chatStreamRetention is how long a finished stream stays available for clients to catch up on.
+	chatStreamRetention = 5 * time.Minute
+	// chatStreamErrorText is shown to the user when the reply fails mid-stream.
+	chatStreamErrorText = "The response was interrupted. Please try again."
+)
+
+var (
+	ErrChatStreamNotFound = errors.New("chat stream not found or expired")
+	ErrNoActiveChatStream = errors.New("no reply is being generated for this chat")
+)
+
+// ChatStreamEvent is one AI SDK stream part, numbered from 1 so a reconnecting client can
+// resume after the last one it received.
+type ChatStreamEvent struct {
+	ID   int
+	Data json.RawMessage
+}
+
+// ChatStream buffers the parts of an assistant reply while a background goroutine reads it from
+// the Python service, so the reply completes and is saved even if the client disconnects, and a
+// client that reconnects can replay what it missed. Streams live in the memory of the instance
+// that started them.
+type ChatStream struct {
+	ID     string
+	ChatID string
+	UserID string
+	Scope  model.ChatScope
+
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	events  []ChatStreamEvent
+	done    bool
+	stopped bool
+	changed chan struct{}
+}
+
+func newChatStream(scope model.ChatScope, chatID, userID string, cancel context.CancelFunc) *ChatStream {
+	return &ChatStream{
+		ID:      rand.Text(),
+		ChatID:  chatID,
+		UserID:  userID,
+		Scope:   scope,
+		cancel:  cancel,
+		changed: make(chan struct{}),
+	}
+}
+
+// Next returns the events after lastEventID and whether the stream has ended. When it has not,
+// the returned channel is closed as soon as more events arrive or the stream ends.
+func (s *ChatStream) Next(lastEventID int) ([]ChatStreamEvent, bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if lastEventID < 0 {
+		lastEventID = 0
+	}
+	var events []ChatStreamEvent
+	if lastEventID < len(s.events) {
+		events = append(events, s.events[lastEventID:]...)
+	}
+	return events, s.done, s.changed
+}
+
+// Wait blocks until the stream has ended and its reply has been saved, or ctx is done.
+func (s *ChatStream) Wait(ctx context.Context) error {
+	for {
+		_, done, changed := s.Next(math.MaxInt)
+		if done {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// stop cancels the upstream request; the reply ends as stopped rather than failed.
+func (s *ChatStream) stop() {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.cancel()
+}
+
+func (s *ChatStream) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+func (s *ChatStream) append(part map[string]interface{}) {
+	data, err := json.Marshal(part)
+	if err != nil {
+		// Parts are built from strings and maps, so this cannot happen in practice
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, ChatStreamEvent{ID: len(s.events) + 1, Data: data})
+	s.broadcast()
+}
+
+func (s *ChatStream) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done = true
+	s.broadcast()
+}
+
+// broadcast wakes everyone waiting on the stream. Callers must hold s.mu.
+func (s *ChatStream) broadcast() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// StartReply starts streaming the assistant's reply to userMessage in the background and returns
+// the stream to relay to the client. The reply outlives ctx; it is saved under userMessage once
+// it ends. A reply still being generated for the chat, e.g. when the user regenerates or edits
+// mid-stream, is stopped and saved first, so that a chat only ever has one reply to stop.
+func (s *chatService) StartReply(ctx context.Context, scope model.ChatScope, chatID, userID string, userMessage *model.Message, modelName string) (*ChatStream, error) {
+	streamCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), chatStreamTimeout)
+	stream := newChatStream(scope, chatID, userID, cancel)
+	if err := s.claimChat(ctx, stream); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	body, err := s.StreamChatResponse(streamCtx, scope, chatID, userID, userMessage, modelName)
+	if err != nil {
+		s.streamsMu.Lock()
+		if s.activeStreams[chatID] == stream {
+			delete(s.activeStreams, chatID)
+		}
+		s.streamsMu.Unlock()
+		cancel()
+		return nil, err
+	}
+
+	s.streamsMu.Lock()
+	s.streams[stream.ID] = stream
+	s.streamsMu.Unlock()
+
+	go func() {
+		defer cancel()
+		defer func() {
+			if err := body.Close(); err != nil {
+				s.logger.Error().Err(err).Msg("Failed to close stream")
+			}
+		}()
+		s.pumpReply(streamCtx, stream, body, scope, userMessage, modelName)
+		s.streamsMu.Lock()
+		if s.activeStreams[chatID] == stream {
+			delete(s.activeStreams, chatID)
+		}
+		s.streamsMu.Unlock()
+		stream.finish()
+		time.AfterFunc(chatStreamRetention, func() {
+			s.streamsMu.Lock()
+			delete(s.streams, stream.ID)
+			s.streamsMu.Unlock()
+		})
+	}()
+	return stream, nil
+}
+
+// claimChat makes stream the active stream of its chat, stopping the chat's running reply and
+// waiting until it has been saved. It fails if ctx is done first.
+func (s *chatService) claimChat(ctx context.Context, stream *ChatStream) error {
+	for {
+		s.streamsMu.Lock()
+		previous, ok := s.activeStreams[stream.ChatID]
+		if !ok {
+			s.activeStreams[stream.ChatID] = stream
+			s.streamsMu.Unlock()
+			return nil
+		}
+		s.streamsMu.Unlock()
+
+		if previous.UserID != stream.UserID {
+			return ErrUnauthorized
+		}
+		s.logger.Debug().Str("chat_id", stream.ChatID).Str("stream_id", previous.ID).Msg("Stopping the active reply before starting a new one")
+		previous.stop()
+		if err := previous.Wait(ctx); err != nil {
+			return fmt.Errorf("waiting for the active reply to stop: %w", err)
+		}
+	}
+}
+
+// GetChatStream returns a stream of one of the user's chats that is running or recently finished.
+func (s *chatService) GetChatStream(scope model.ChatScope, streamID, chatID, userID string) (*ChatStream, error) {
+	s.streamsMu.Lock()
+	stream, ok := s.streams[streamID]
+	s.streamsMu.Unlock()
+	if !ok || stream.ChatID != chatID || stream.UserID != userID || stream.Scope != scope {
+		return nil, ErrChatStreamNotFound
+	}
+	return stream, nil
+}
+
+// StopReply cancels the reply being generated for a chat, from whichever client asked for it.
+// The partial reply is kept and marked stopped, and the open stream ends with a finish part.
+// It returns once the reply has been saved, or ctx is done. Only replies running on this
+// instance can be stopped.
+func (s *chatService) StopReply(ctx context.Context, scope model.ChatScope, chatID, userID string) error {
+	s.streamsMu.Lock()
+	stream, ok := s.activeStreams[chatID]
+	s.streamsMu.Unlock()
+	if !ok || stream.UserID != userID || stream.Scope != scope {
+		return ErrNoActiveChatStream
+	}
+
+	stream.stop()
+	return stream.Wait(ctx)
+}
+
+// pumpReply converts the Python service stream to the AI SDK UI message stream protocol, then
+// saves the reply with how it ended.
+func (s *chatService) pumpReply(ctx context.Context, stream *ChatStream, body io.Reader, scope model.ChatScope, userMessage *model.Message, modelName string) {
+	chatID, userID := stream.ChatID, stream.UserID
+	log := logger.ForRequest(ctx, s.logger)
+	reader := bufio.NewReader(body)
+	reply := newReplyWriter(stream, fmt.Sprintf("part_%s_%d", chatID, time.Now().UnixNano()))
+
+	// Track how the reply ended so an interrupted one is saved as such and can be retried
+	status := model.MessageStatusComplete
+	var streamErr error
+
+	stream.append(map[string]interface{}{
+		"type":            "start",
+		"messageMetadata": map[string]interface{}{"stream_id": stream.ID},
+	})
+
+	for {
+		chunk, err := ParseSSEChunk(reader)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			if stream.isStopped() {
+				log.Debug().Str("chat_id", chatID).Msg("Stream stopped by user")
+				status = model.MessageStatusStopped
+			} else if errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled) {
+				log.Debug().Err(err).Str("chat_id", chatID).Msg("Stream reading stopped (context canceled)")
+				status, streamErr = model.MessageStatusAborted, err
+			} else {
+				log.Error().Err(err).Str("chat_id", chatID).Msg("Error reading from Python service stream")
+				status, streamErr = model.MessageStatusError, err
+			}
+			break
+		}
+
+		if chunk.Error != "" {
+			log.Error().Str("error", chunk.Error).Str("chat_id", chatID).Msg("Python service reported a stream error")
+			status, streamErr = model.MessageStatusError, errors.New(chunk.Error)
+			break
+		}
+
+		var citationErr error
+		if chunk.Type == ChatChunkReference {
+			citationErr = s.validateCitation(ctx, scope, chunk.Reference)
+		}
+		if citationErr != nil {
+			log.Warn().Err(citationErr).Str("chat_id", chatID).Msg("Dropping slide citation")
+		} else if err := reply.write(chunk); err != nil {
+			log.Warn().Err(err).Interface("chunk", chunk).Msg("Skipping invalid chunk")
+		}
+
+		if chunk.Done {
+			break
+		}
+	}
+
+	// A stop that lands as the upstream finishes may surface as a clean end of stream
+	if status == model.MessageStatusComplete && stream.isStopped() {
+		status = model.MessageStatusStopped
+	}
+
+	parts := reply.close()
+	// A failed reply ends with an error part instead of finish, so the client can offer a retry
+	if status == model.MessageStatusError {
+		stream.append(map[string]interface{}{
+			"type":      "error",
+			"errorText": chatStreamErrorText,
+		})
+	} else {
+		stream.append(map[string]interface{}{
+			"type":            "finish",
+			"messageMetadata": map[string]interface{}{"status": status},
+		})
+	}
+
+	if len(parts) == 0 && status == model.MessageStatusComplete {
+		log.Warn().Str("chat_id", chatID).Msg("No content to save for assistant message")
+		return
+	}
+	s.saveReply(ctx, scope, chatID, userID, userMessage, parts, modelName, status, streamErr)
+}
+
+// saveReply persists the reply and, for the first complete reply of a chat, generates its title.
+// It runs after the stream has ended, so it only keeps the values of ctx, such as the request ID.
+func (s *chatService) saveReply(ctx context.Context, scope model.ChatScope, chatID, userID string, userMessage *model.Message, assistantParts model.MessageParts, modelName, status string, streamErr error) {
+	log := logger.ForRequest(ctx, s.logger)
+	ctx = context.WithoutCancel(ctx)
+	assistantMetadata := map[string]interface{}{
+		"model":  modelName,
+		"status": status,
+	}
+	if streamErr != nil {
+		assistantMetadata["error"] = streamErr.Error()
+	}
+
+	saveCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if _, err := s.CreateReply(saveCtx, scope, chatID, userID, userMessage.ID, assistantParts, assistantMetadata); err != nil {
+		log.Error().Err(err).Str("chat_id", chatID).Msg("Failed to save assistant message")
+		return
+	}
+	if status != model.MessageStatusComplete {
+		return
+	}
+
+	// Title the chat after its first complete reply. Stopped or failed replies and the branches
+	// created by regenerating them are also messages, so the raw message count cannot tell.
+	replyCount, err := s.chatRepo.CountCompleteReplies(saveCtx, chatID)
+	if err != nil {
+		log.Error().Err(err).Str("chat_id", chatID).Msg("Failed to count complete replies for title generation")
+		return
+	}
+	if replyCount == 1 {
+		// Title generation happens asynchronously, frontend will poll for updates
+		titleCtx, titleCancel := context.WithTimeout(ctx, 30*time.Second)
+		go func() {
+			defer titleCancel()
+			s.GenerateAndUpdateTitle(titleCtx, scope, chatID, userID, userMessage.Parts, assistantParts)
+		}()
+	}
+}
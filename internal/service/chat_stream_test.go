@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"app/internal/model"
+)
+
+func TestChatStreamNext(t *testing.T) {
+	stream := newChatStream(model.ChatScope{}, "chat", "user", func() {})
+	for _, partType := range []string{"start", "text-start", "text-end"} {
+		stream.append(map[string]interface{}{"type": partType})
+	}
+
+	tests := []struct {
+		lastEventID int
+		wantIDs     []int
+	}{
+		{0, []int{1, 2, 3}},
+		{-1, []int{1, 2, 3}},
+		{1, []int{2, 3}},
+		{3, nil},
+		// An ID from a stream the client saw elsewhere must not resend everything
+		{10, nil},
+	}
+	for _, tt := range tests {
+		events, done, _ := stream.Next(tt.lastEventID)
+		if done {
+			t.Errorf("Next(%d) done = true before the stream finished", tt.lastEventID)
+		}
+		var ids []int
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		if len(ids) != len(tt.wantIDs) {
+			t.Errorf("Next(%d) ids = %v, want %v", tt.lastEventID, ids, tt.wantIDs)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.wantIDs[i] {
+				t.Errorf("Next(%d) ids = %v, want %v", tt.lastEventID, ids, tt.wantIDs)
+				break
+			}
+		}
+	}
+
+	events, _, _ := stream.Next(1)
+	if got := string(events[0].Data); got != `{"type":"text-start"}` {
+		t.Errorf("Next(1) first event = %s, want the text-start part", got)
+	}
+}
+
+func TestChatStreamNextSignalsChanges(t *testing.T) {
+	stream := newChatStream(model.ChatScope{}, "chat", "user", func() {})
+
+	_, _, changed := stream.Next(0)
+	select {
+	case <-changed:
+		t.Fatal("changed closed before anything was appended")
+	default:
+	}
+
+	stream.append(map[string]interface{}{"type": "start"})
+	select {
+	case <-changed:
+	default:
+		t.Fatal("changed not closed after an append")
+	}
+
+	events, done, changed := stream.Next(0)
+	if len(events) != 1 || done {
+		t.Fatalf("Next(0) = %d events, done %v; want 1 event, not done", len(events), done)
+	}
+
+	stream.finish()
+	select {
+	case <-changed:
+	default:
+		t.Fatal("changed not closed after finish")
+	}
+
+	// A client resuming after the end still gets the remaining events along with done
+	events, done, _ = stream.Next(0)
+	if len(events) != 1 || !done {
+		t.Errorf("Next(0) after finish = %d events, done %v; want 1 event, done", len(events), done)
+	}
+}
+
+func TestChatStreamWait(t *testing.T) {
+	stream := newChatStream(model.ChatScope{}, "chat", "user", func() {})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := stream.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait on a running stream = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	go func() {
+		stream.append(map[string]interface{}{"type": "start"})
+		stream.finish()
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := stream.Wait(ctx); err != nil {
+		t.Errorf("Wait on a finishing stream = %v, want nil", err)
+	}
+}
+
+func TestChatStreamStop(t *testing.T) {
+	canceled := false
+	stream := newChatStream(model.ChatScope{}, "chat", "user", func() { canceled = true })
+	if stream.isStopped() {
+		t.Fatal("isStopped = true for a new stream")
+	}
+	stream.stop()
+	if !stream.isStopped() || !canceled {
+		t.Errorf("after stop: isStopped = %v, canceled = %v; want both true", stream.isStopped(), canceled)
+	}
+}
@@ -4,30 +4,98 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"app/internal/api/v1/dto"
 	"app/internal/model"
+	"app/internal/pubsub"
 	"app/internal/repository"
 
 	"github.com/rs/zerolog"
 )
 
+var (
+	ErrDLQMessageNotFound = errors.New("dead letter message not found")
+	ErrInvalidDLQStatus   = errors.New("invalid dead letter message status")
+)
+
+const (
+	// dlqSubscriptionSuffix is the marker used in DLQ subscription names
+	// (e.g. "ingestion-dlq-sub-stg") that separates the source topic from the rest.
+	dlqSubscriptionSuffix = "-dlq-sub"
+	// deadLetterAttributePrefix marks the attributes Pub/Sub adds when dead-lettering a message.
+	deadLetterAttributePrefix = "CloudPubSubDeadLetter"
+	// retryAttemptAttribute carries the automatic retry count of a re-published ingestion job.
+	retryAttemptAttribute = "retry_attempt"
+	// maxIngestionRetryDelay caps the exponential backoff between automatic retries.
+	maxIngestionRetryDelay = 30 * time.Minute
+)
+
+// IngestionRetryPolicy bounds the automatic re-publishing of dead-lettered ingestion jobs.
+type IngestionRetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+}
+
+// backoff returns the delay before the given (1-based) retry attempt.
+func (p IngestionRetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < maxIngestionRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxIngestionRetryDelay)
+}
+
 // DLQService defines the interface for Dead Letter Queue operations.
 type DLQService interface {
 	ProcessAndSave(ctx context.Context, req *dto.PubSubPushRequest) error
+	// ListMessages returns persisted dead-letter messages matching the filter, newest first.
+	ListMessages(ctx context.Context, filter repository.DLQListFilter, limit, offset int) ([]model.DeadLetterMessage, error)
+	// GetMessage returns a single dead-letter message.
+	GetMessage(ctx context.Context, id string) (*model.DeadLetterMessage, error)
+	// UpdateStatus marks a dead-letter message as processed, ignored or unprocessed.
+	UpdateStatus(ctx context.Context, id, status string) (*model.DeadLetterMessage, error)
+	// Replay republishes the message payload to its source topic and records who replayed it.
+	Replay(ctx context.Context, id, userID string) (*model.DeadLetterMessage, DLQReplayResult, error)
+}
+
+// DLQReplayResult tells where a replayed message went. Replayed ingestion jobs are queued in the
+// outbox and published by the dispatcher; other messages are published right away.
+type DLQReplayResult struct {
+	PublishedMessageID string
+	OutboxMessageID    string
 }
 
 // dlqService is the implementation of DLQService.
 type dlqService struct {
-	repo      repository.DLQRepository
-	dlqLogger zerolog.Logger
+	repo           repository.DLQRepository
+	lectureRepo    repository.LectureRepository
+	publisher      pubsub.Publisher
+	ingestionTopic string
+	retryPolicy    IngestionRetryPolicy
+	dlqLogger      zerolog.Logger
 }
 
 // NewDLQService creates a new DLQService.
-func NewDLQService(repo repository.DLQRepository, logger zerolog.Logger) DLQService {
+func NewDLQService(
+	repo repository.DLQRepository,
+	lectureRepo repository.LectureRepository,
+	publisher pubsub.Publisher,
+	ingestionTopic string,
+	retryPolicy IngestionRetryPolicy,
+	logger zerolog.Logger,
+) DLQService {
 	return &dlqService{
-		repo:      repo,
-		dlqLogger: logger.With().Str("service", "DLQService").Logger(),
+		repo:           repo,
+		lectureRepo:    lectureRepo,
+		publisher:      publisher,
+		ingestionTopic: ingestionTopic,
+		retryPolicy:    retryPolicy,
+		dlqLogger:      logger.With().Str("service", "DLQService").Logger(),
 	}
 }
 
@@ -60,10 +128,268 @@ func (s *dlqService) ProcessAndSave(ctx context.Context, req *dto.PubSubPushRequ
 	}
 
 	// Save to the database
-	if err := s.repo.Create(ctx, dbMessage); err != nil {
+	created, err := s.repo.Create(ctx, dbMessage)
+	if err != nil {
 		s.dlqLogger.Error().Err(err).Str("subscription", dbMessage.SubscriptionName).Msg("Failed to save DLQ message")
 		return err
 	}
-	// Success is expected, no need to log it
+	if !created {
+		// A redelivery of a message that was already saved and acted on.
+		s.dlqLogger.Info().Str("subscription", dbMessage.SubscriptionName).Str("message_id", dbMessage.MessageID).Msg("Ignoring redelivered DLQ message")
+		return nil
+	}
+
+	// Ingestion failures leave the lecture stuck mid-pipeline, so resolve them right away.
+	if topic, err := sourceTopicFromSubscription(req.Subscription); err == nil && topic == s.ingestionTopic {
+		s.handleFailedIngestion(ctx, dbMessage, req.Message.Attributes)
+	}
 	return nil
 }
+
+// handleFailedIngestion either schedules another ingestion attempt for the lecture
+// referenced by a dead-lettered job or, once the retry budget is spent, marks it failed.
+// Errors are logged rather than returned because the DLQ message is already persisted.
+func (s *dlqService) handleFailedIngestion(ctx context.Context, message *model.DeadLetterMessage, attributes map[string]string) {
+	var payload ingestionPayload
+	if err := json.Unmarshal(message.Payload, &payload); err != nil || payload.LectureID == "" {
+		s.dlqLogger.Warn().Err(err).Str("dlq_id", message.ID).Msg("Dead-lettered ingestion job has no parsable lecture ID")
+		return
+	}
+	log := s.dlqLogger.With().
+		Str("dlq_id", message.ID).
+		Str("lecture_id", payload.LectureID).
+		Str("request_id", attributes[pubsub.RequestIDAttribute]).
+		Logger()
+
+	lecture, err := s.lectureRepo.GetLectureByID(ctx, payload.LectureID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load lecture for dead-lettered ingestion job")
+		return
+	}
+	if lecture == nil || !isLectureInFlight(lecture.Status) {
+		// The lecture was deleted, a later delivery already succeeded or it has been marked
+		// failed since; only lectures still waiting on the pipeline are recovered.
+		log.Info().Msg("Skipping recovery of dead-lettered ingestion job")
+		return
+	}
+
+	attempt, _ := strconv.Atoi(attributes[retryAttemptAttribute])
+	if attempt >= s.retryPolicy.MaxAttempts {
+		details := model.EmbeddingErrorDetails{
+			"reason":         "ingestion_failed",
+			"message":        "Lecture processing failed after all automatic retries",
+			"attempts":       attempt + 1,
+			"dlq_message_id": message.ID,
+			"failed_at":      time.Now().UTC(),
+		}
+		if err := s.lectureRepo.UpdateLectureStatus(ctx, lecture.ID, "failed", details); err != nil {
+			log.Error().Err(err).Msg("Failed to mark lecture as failed after exhausting ingestion retries")
+			return
+		}
+		log.Warn().Int("attempts", attempt+1).Msg("Gave up on ingestion after exhausting automatic retries")
+		return
+	}
+
+	nextAttempt := attempt + 1
+	delay := s.retryPolicy.backoff(nextAttempt)
+	details := model.EmbeddingErrorDetails{
+		"reason":         "ingestion_retrying",
+		"message":        "Lecture processing failed and will be retried automatically",
+		"retry_attempt":  nextAttempt,
+		"max_retries":    s.retryPolicy.MaxAttempts,
+		"next_retry_at":  time.Now().Add(delay).UTC(),
+		"dlq_message_id": message.ID,
+	}
+	// The retry is a delayed outbox message, so it survives restarts of the API process. It keeps
+	// the ID of the request that uploaded the lecture, so every attempt can be traced back to it.
+	retryAttributes := map[string]string{retryAttemptAttribute: strconv.Itoa(nextAttempt)}
+	if requestID := attributes[pubsub.RequestIDAttribute]; requestID != "" {
+		retryAttributes[pubsub.RequestIDAttribute] = requestID
+	}
+	retry := &model.OutboxMessage{
+		Topic:       s.ingestionTopic,
+		Payload:     message.Payload,
+		Attributes:  pubsub.WithTraceContext(ctx, retryAttributes),
+		LectureID:   &lecture.ID,
+		AvailableAt: time.Now().Add(delay),
+	}
+	if err := s.lectureRepo.UpdateLectureStatusWithOutbox(ctx, lecture.ID, "pending_processing", details, retry); err != nil {
+		log.Error().Err(err).Msg("Failed to schedule ingestion retry")
+		return
+	}
+	if _, err := s.repo.UpdateStatus(ctx, message.ID, "processed"); err != nil {
+		log.Warn().Err(err).Msg("Failed to mark auto-retried DLQ message as processed")
+	}
+
+	log.Info().Int("retry_attempt", nextAttempt).Dur("delay", delay).Msg("Scheduled automatic ingestion retry")
+}
+
+// ListMessages returns persisted dead-letter messages matching the filter, newest first.
+func (s *dlqService) ListMessages(ctx context.Context, filter repository.DLQListFilter, limit, offset int) ([]model.DeadLetterMessage, error) {
+	if filter.Status != "" && !isValidDLQStatus(filter.Status) {
+		return nil, ErrInvalidDLQStatus
+	}
+	messages, err := s.repo.List(ctx, filter, limit, offset)
+	if err != nil {
+		s.dlqLogger.Error().Err(err).Msg("Failed to list DLQ messages")
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetMessage returns a single dead-letter message.
+func (s *dlqService) GetMessage(ctx context.Context, id string) (*model.DeadLetterMessage, error) {
+	message, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		s.dlqLogger.Error().Err(err).Str("dlq_id", id).Msg("Failed to get DLQ message")
+		return nil, err
+	}
+	if message == nil {
+		return nil, ErrDLQMessageNotFound
+	}
+	return message, nil
+}
+
+// UpdateStatus marks a dead-letter message as processed, ignored or unprocessed.
+func (s *dlqService) UpdateStatus(ctx context.Context, id, status string) (*model.DeadLetterMessage, error) {
+	if !isValidDLQStatus(status) {
+		return nil, ErrInvalidDLQStatus
+	}
+	message, err := s.repo.UpdateStatus(ctx, id, status)
+	if err != nil {
+		s.dlqLogger.Error().Err(err).Str("dlq_id", id).Str("status", status).Msg("Failed to update DLQ message status")
+		return nil, err
+	}
+	if message == nil {
+		return nil, ErrDLQMessageNotFound
+	}
+	return message, nil
+}
+
+// Replay republishes the message payload to its source topic and records who replayed it.
+// Replaying an ingestion job moves its lecture back to pending_processing, as an automatic
+// retry does. The job is then queued in the outbox in the same transaction, so the lecture is
+// never left pending without a job on its way.
+func (s *dlqService) Replay(ctx context.Context, id, userID string) (*model.DeadLetterMessage, DLQReplayResult, error) {
+	message, err := s.GetMessage(ctx, id)
+	if err != nil {
+		return nil, DLQReplayResult{}, err
+	}
+
+	topic, err := sourceTopicFromSubscription(message.SubscriptionName)
+	if err != nil {
+		s.dlqLogger.Error().Err(err).Str("dlq_id", id).Str("subscription", message.SubscriptionName).Msg("Cannot resolve source topic for DLQ message")
+		return nil, DLQReplayResult{}, err
+	}
+
+	var result DLQReplayResult
+	attributes := republishableAttributes(message.Attributes)
+	if topic == s.ingestionTopic {
+		if result.OutboxMessageID, err = s.queueIngestionReplay(ctx, message, attributes); err != nil {
+			return nil, DLQReplayResult{}, err
+		}
+	}
+	if result.OutboxMessageID == "" {
+		if result.PublishedMessageID, err = s.publisher.Publish(ctx, topic, message.Payload, attributes); err != nil {
+			s.dlqLogger.Error().Err(err).Str("dlq_id", id).Str("topic", topic).Msg("Failed to replay DLQ message")
+			return nil, DLQReplayResult{}, fmt.Errorf("replaying dead letter message: %w", err)
+		}
+	}
+
+	// The payload is already on its way, so a failure here only loses the audit trail. Reporting
+	// it as an error would invite the admin to retry and replay the message twice.
+	updated, err := s.repo.RecordReplay(ctx, id, userID)
+	if err != nil || updated == nil {
+		s.dlqLogger.Error().
+			Err(err).
+			Str("dlq_id", id).
+			Str("published_message_id", result.PublishedMessageID).
+			Str("outbox_message_id", result.OutboxMessageID).
+			Msg("Replayed DLQ message but failed to record replay")
+		updated = message
+	}
+
+	s.dlqLogger.Info().
+		Str("dlq_id", id).
+		Str("topic", topic).
+		Str("published_message_id", result.PublishedMessageID).
+		Str("outbox_message_id", result.OutboxMessageID).
+		Str("replayed_by", userID).
+		Msg("Replayed DLQ message")
+
+	return updated, result, nil
+}
+
+// queueIngestionReplay moves the lecture of a dead-lettered ingestion job back to
+// pending_processing, clearing its error details, and queues the job in the outbox in the same
+// transaction. It returns the ID of the outbox message, or an empty ID when the lecture was
+// deleted or has completed since, in which case the job is left for the caller to publish.
+func (s *dlqService) queueIngestionReplay(ctx context.Context, message *model.DeadLetterMessage, attributes map[string]string) (string, error) {
+	var payload ingestionPayload
+	if err := json.Unmarshal(message.Payload, &payload); err != nil || payload.LectureID == "" {
+		s.dlqLogger.Warn().Err(err).Str("dlq_id", message.ID).Msg("Replayed ingestion job has no parsable lecture ID")
+		return "", nil
+	}
+	log := s.dlqLogger.With().Str("dlq_id", message.ID).Str("lecture_id", payload.LectureID).Logger()
+
+	lecture, err := s.lectureRepo.GetLectureByID(ctx, payload.LectureID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load lecture for replayed ingestion job")
+		return "", fmt.Errorf("failed to retrieve lecture: %w", err)
+	}
+	if lecture == nil || lecture.Status == "complete" {
+		return "", nil
+	}
+
+	replay := &model.OutboxMessage{
+		Topic:      s.ingestionTopic,
+		Payload:    message.Payload,
+		Attributes: pubsub.WithTraceContext(ctx, attributes),
+		LectureID:  &lecture.ID,
+	}
+	if err := s.lectureRepo.UpdateLectureStatusWithOutbox(ctx, lecture.ID, "pending_processing", nil, replay); err != nil {
+		log.Error().Err(err).Msg("Failed to queue replayed ingestion job")
+		return "", fmt.Errorf("failed to queue ingestion job: %w", err)
+	}
+	return replay.ID, nil
+}
+
+func isValidDLQStatus(status string) bool {
+	switch status {
+	case "unprocessed", "processed", "ignored":
+		return true
+	default:
+		return false
+	}
+}
+
+// sourceTopicFromSubscription derives the source topic from a DLQ subscription name.
+// Subscriptions are named "<topic>-dlq-sub[-env]" and may be given as a full
+// resource path ("projects/<project>/subscriptions/<name>").
+func sourceTopicFromSubscription(subscription string) (string, error) {
+	name := subscription[strings.LastIndex(subscription, "/")+1:]
+	idx := strings.Index(name, dlqSubscriptionSuffix)
+	if idx <= 0 {
+		return "", fmt.Errorf("subscription %q does not follow the <topic>%s naming convention", subscription, dlqSubscriptionSuffix)
+	}
+	return name[:idx], nil
+}
+
+// republishableAttributes decodes stored message attributes and drops the ones
+// Pub/Sub added while dead-lettering, so a replay looks like the original message.
+// The automatic retry count is dropped too, giving a manual replay a fresh retry budget.
+func republishableAttributes(raw []byte) map[string]string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var attributes map[string]string
+	if err := json.Unmarshal(raw, &attributes); err != nil {
+		return nil
+	}
+	for key := range attributes {
+		if strings.HasPrefix(key, deadLetterAttributePrefix) || key == retryAttemptAttribute {
+			delete(attributes, key)
+		}
+	}
+	return attributes
+}
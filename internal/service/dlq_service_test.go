@@ -0,0 +1,80 @@
+package service
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestSourceTopicFromSubscription(t *testing.T) {
+	tests := []struct {
+		subscription string
+		want         string
+		wantErr      bool
+	}{
+		{"ingestion-dlq-sub", "ingestion", false},
+		{"ingestion-dlq-sub-stg", "ingestion", false},
+		{"projects/miniclue/subscriptions/ingestion-dlq-sub-prod", "ingestion", false},
+		{"image-analysis-dlq-sub", "image-analysis", false},
+		{"ingestion-sub", "", true},
+		{"-dlq-sub", "", true},
+		{"projects/miniclue/subscriptions/", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := sourceTopicFromSubscription(tt.subscription)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("sourceTopicFromSubscription(%q) error = %v, wantErr %v", tt.subscription, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("sourceTopicFromSubscription(%q) = %q, want %q", tt.subscription, got, tt.want)
+		}
+	}
+}
+
+func TestIngestionRetryPolicyBackoff(t *testing.T) {
+	policy := IngestionRetryPolicy{MaxAttempts: 10, BaseDelay: time.Minute}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{5, 16 * time.Minute},
+		{6, maxIngestionRetryDelay},
+		{1000, maxIngestionRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := policy.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+
+	// A base delay above the cap is capped too
+	if got := (IngestionRetryPolicy{BaseDelay: time.Hour}).backoff(1); got != maxIngestionRetryDelay {
+		t.Errorf("backoff(1) with a 1h base = %v, want %v", got, maxIngestionRetryDelay)
+	}
+}
+
+func TestRepublishableAttributes(t *testing.T) {
+	raw := []byte(`{
+		"request_id": "req-1",
+		"retry_attempt": "3",
+		"CloudPubSubDeadLetterSourceDeliveryCount": "5",
+		"CloudPubSubDeadLetterSourceSubscription": "ingestion-sub"
+	}`)
+	want := map[string]string{"request_id": "req-1"}
+	if got := republishableAttributes(raw); !maps.Equal(got, want) {
+		t.Errorf("republishableAttributes() = %v, want %v", got, want)
+	}
+
+	if got := republishableAttributes(nil); got != nil {
+		t.Errorf("republishableAttributes(nil) = %v, want nil", got)
+	}
+	if got := republishableAttributes([]byte("not json")); got != nil {
+		t.Errorf("republishableAttributes(invalid) = %v, want nil", got)
+	}
+}
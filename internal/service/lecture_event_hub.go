@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"app/internal/model"
+	"app/internal/repository"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// lectureEventBuffer is how many events a slow subscriber may fall behind before events are dropped.
+	lectureEventBuffer = 16
+	// lectureEventReconnectDelay and lectureEventMaxReconnectDelay bound the backoff after the
+	// listening connection fails.
+	lectureEventReconnectDelay    = time.Second
+	lectureEventMaxReconnectDelay = time.Minute
+)
+
+// LectureEventSubscriber delivers change notifications for a single lecture.
+type LectureEventSubscriber interface {
+	// Subscribe returns a channel of events for the lecture and a function that ends the subscription.
+	Subscribe(lectureID string) (<-chan model.LectureEvent, func())
+}
+
+// LectureEventHub listens for lecture change notifications from Postgres and fans them out
+// to subscribers. Every lecture event carries the lecture's full progress, so a subscriber that
+// misses one (after falling behind, or while the listener reconnects) catches up on the next.
+type LectureEventHub struct {
+	listener    repository.LectureEventListener
+	mu          sync.Mutex
+	subscribers map[string]map[chan model.LectureEvent]struct{}
+	logger      zerolog.Logger
+}
+
+// NewLectureEventHub creates a new LectureEventHub.
+func NewLectureEventHub(listener repository.LectureEventListener, logger zerolog.Logger) *LectureEventHub {
+	return &LectureEventHub{
+		listener:    listener,
+		subscribers: make(map[string]map[chan model.LectureEvent]struct{}),
+		logger:      logger.With().Str("service", "LectureEventHub").Logger(),
+	}
+}
+
+// Subscribe returns a channel of events for the lecture and a function that ends the subscription.
+func (h *LectureEventHub) Subscribe(lectureID string) (<-chan model.LectureEvent, func()) {
+	ch := make(chan model.LectureEvent, lectureEventBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[lectureID] == nil {
+		h.subscribers[lectureID] = make(map[chan model.LectureEvent]struct{})
+	}
+	h.subscribers[lectureID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[lectureID], ch)
+		if len(h.subscribers[lectureID]) == 0 {
+			delete(h.subscribers, lectureID)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// Run listens for notifications until ctx is cancelled, reconnecting with backoff when the
+// connection fails.
+func (h *LectureEventHub) Run(ctx context.Context) {
+	h.logger.Info().Msg("Starting lecture event hub")
+	delay := lectureEventReconnectDelay
+	for {
+		started := time.Now()
+		err := h.listener.Listen(ctx, h.dispatch)
+		if ctx.Err() != nil {
+			h.logger.Info().Msg("Stopped lecture event hub")
+			return
+		}
+		// A connection that stayed up for a while is not part of a failure streak.
+		if time.Since(started) > lectureEventMaxReconnectDelay {
+			delay = lectureEventReconnectDelay
+		}
+		h.logger.Error().Err(err).Dur("retry_in", delay).Msg("Lecture event listener disconnected")
+
+		select {
+		case <-ctx.Done():
+			h.logger.Info().Msg("Stopped lecture event hub")
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, lectureEventMaxReconnectDelay)
+	}
+}
+
+func (h *LectureEventHub) dispatch(payload string) {
+	var event model.LectureEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		h.logger.Warn().Err(err).Str("payload", payload).Msg("Ignoring malformed lecture event")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[event.LectureID] {
+		select {
+		case ch <- event:
+		default:
+			h.logger.Warn().Str("lecture_id", event.LectureID).Str("type", event.Type).Msg("Dropping lecture event for slow subscriber")
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// lectureFormat describes a file type that can be uploaded as a lecture.
+type lectureFormat struct {
+	FileType  string
+	Extension string
+	MimeType  string
+	// magic is the header the file must start with.
+	magic []byte
+}
+
+// zipMagic is shared by the Office Open XML formats, which are ZIP containers.
+var zipMagic = []byte("PK\x03\x04")
+
+// lectureFormats lists the supported upload formats, keyed by file type.
+var lectureFormats = map[string]lectureFormat{
+	"pdf":  {FileType: "pdf", Extension: "pdf", MimeType: "application/pdf", magic: []byte("%PDF")},
+	"pptx": {FileType: "pptx", Extension: "pptx", MimeType: "application/vnd.openxmlformats-officedocument.presentationml.presentation", magic: zipMagic},
+	"docx": {FileType: "docx", Extension: "docx", MimeType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", magic: zipMagic},
+	"png":  {FileType: "png", Extension: "png", MimeType: "image/png", magic: []byte("\x89PNG\r\n\x1a\n")},
+	"jpeg": {FileType: "jpeg", Extension: "jpg", MimeType: "image/jpeg", magic: []byte("\xff\xd8\xff")},
+}
+
+// lectureFormatAliases maps alternative extensions to their file type.
+var lectureFormatAliases = map[string]string{
+	"jpg": "jpeg",
+}
+
+// resolveLectureFormat returns the format for a declared file type or, when none is declared,
+// for the filename's extension. Both are matched case-insensitively.
+func resolveLectureFormat(fileType, filename string) (lectureFormat, bool) {
+	if fileType == "" {
+		fileType = strings.TrimPrefix(filepath.Ext(filename), ".")
+	}
+	fileType = strings.ToLower(fileType)
+	if alias, ok := lectureFormatAliases[fileType]; ok {
+		fileType = alias
+	}
+	format, ok := lectureFormats[fileType]
+	return format, ok
+}
+
+// lectureFormatFor returns the format recorded on a lecture, falling back to PDF for
+// lectures uploaded before other formats were supported.
+func lectureFormatFor(fileType string) lectureFormat {
+	if format, ok := lectureFormats[fileType]; ok {
+		return format
+	}
+	return lectureFormats["pdf"]
+}
+
+// matchesHeader reports whether header starts with the format's magic bytes.
+func (f lectureFormat) matchesHeader(header []byte) bool {
+	return bytes.HasPrefix(header, f.magic)
+}
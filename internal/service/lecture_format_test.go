@@ -0,0 +1,73 @@
+package service
+
+import "testing"
+
+func TestResolveLectureFormat(t *testing.T) {
+	tests := []struct {
+		fileType string
+		filename string
+		want     string
+		wantOK   bool
+	}{
+		{"pdf", "notes.bin", "pdf", true},
+		{"PDF", "notes.pdf", "pdf", true},
+		{"PPTX", "", "pptx", true},
+		{"Docx", "", "docx", true},
+		{"jpg", "", "jpeg", true},
+		{"JPG", "", "jpeg", true},
+		{"", "slides.pptx", "pptx", true},
+		{"", "Slides.PPTX", "pptx", true},
+		{"", "photo.JPG", "jpeg", true},
+		{"", "scan.png", "png", true},
+		{"", "archive.tar.gz", "", false},
+		{"", "no-extension", "", false},
+		{"exe", "slides.pdf", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		format, ok := resolveLectureFormat(tt.fileType, tt.filename)
+		if ok != tt.wantOK {
+			t.Errorf("resolveLectureFormat(%q, %q) ok = %v, want %v", tt.fileType, tt.filename, ok, tt.wantOK)
+			continue
+		}
+		if format.FileType != tt.want {
+			t.Errorf("resolveLectureFormat(%q, %q) = %q, want %q", tt.fileType, tt.filename, format.FileType, tt.want)
+		}
+	}
+}
+
+func TestLectureFormatFor(t *testing.T) {
+	if got := lectureFormatFor("pptx").FileType; got != "pptx" {
+		t.Errorf("lectureFormatFor(pptx) = %q, want pptx", got)
+	}
+	// Lectures from before other formats were supported have no usable file type
+	for _, fileType := range []string{"", "unknown"} {
+		if got := lectureFormatFor(fileType).FileType; got != "pdf" {
+			t.Errorf("lectureFormatFor(%q) = %q, want pdf", fileType, got)
+		}
+	}
+}
+
+func TestLectureFormatMatchesHeader(t *testing.T) {
+	tests := []struct {
+		fileType string
+		header   string
+		want     bool
+	}{
+		{"pdf", "%PDF-1.7", true},
+		{"pdf", "PK\x03\x04", false},
+		{"pptx", "PK\x03\x04\x14\x00", true},
+		{"docx", "PK\x03\x04\x14\x00", true},
+		{"docx", "%PDF-1.7", false},
+		{"png", "\x89PNG\r\n\x1a\n", true},
+		{"png", "\x89PNG", false},
+		{"jpeg", "\xff\xd8\xff\xe0", true},
+		{"jpeg", "\xff\xd8", false},
+		{"pdf", "", false},
+	}
+	for _, tt := range tests {
+		if got := lectureFormats[tt.fileType].matchesHeader([]byte(tt.header)); got != tt.want {
+			t.Errorf("%s.matchesHeader(%q) = %v, want %v", tt.fileType, tt.header, got, tt.want)
+		}
+	}
+}
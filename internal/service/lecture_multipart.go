@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"app/internal/model"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+var (
+	ErrLectureNotUploading     = errors.New("lecture is not awaiting an upload")
+	ErrMultipartUploadNotFound = errors.New("lecture has no multipart upload in progress")
+	ErrInvalidUploadParts      = errors.New("invalid upload parts")
+)
+
+const (
+	// defaultMultipartPartSize is used unless the file is too large to fit in maxMultipartParts parts.
+	// It must stay above the 5 MiB minimum S3 enforces for every part but the last.
+	defaultMultipartPartSize = 16 * 1024 * 1024
+	// maxMultipartParts is the S3 limit on parts per upload.
+	maxMultipartParts = 10000
+	// maxPresignedPartsPerRequest bounds how many part URLs are signed in one call.
+	maxPresignedPartsPerRequest = 100
+	// multipartPartURLExpiry is longer than a single PUT's expiry so slow connections can finish a part.
+	multipartPartURLExpiry = time.Hour
+)
+
+// MultipartUpload describes a multipart upload started for a lecture.
+type MultipartUpload struct {
+	UploadID      string
+	PartSizeBytes int64
+	PartCount     int32
+	ContentType   string
+}
+
+// PresignedPart is a presigned URL for uploading one part of a multipart upload.
+type PresignedPart struct {
+	PartNumber int32
+	URL        string
+	ExpiresAt  time.Time
+}
+
+// UploadedPart is a part that storage has already received.
+type UploadedPart struct {
+	PartNumber   int32
+	ETag         string
+	SizeBytes    int64
+	LastModified *time.Time
+}
+
+// InitiateMultipartUpload starts a multipart upload for a lecture that is still awaiting its file.
+// Any multipart upload already in progress for the lecture is aborted first.
+func (s *lectureService) InitiateMultipartUpload(ctx context.Context, lectureID, userID string, sizeBytes int64) (*MultipartUpload, error) {
+	lecture, err := s.getUploadingLecture(ctx, lectureID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if sizeBytes > s.maxUploadSize {
+		return nil, fmt.Errorf("%w: file is %d bytes, the limit is %d bytes", ErrUploadTooLarge, sizeBytes, s.maxUploadSize)
+	}
+
+	if lecture.MultipartUploadID != nil {
+		s.abortMultipartUpload(ctx, lecture.StoragePath, *lecture.MultipartUploadID)
+	}
+
+	format := lectureFormatFor(lecture.FileType)
+	out, err := s.s3Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s.bucketName),
+		Key:         aws.String(lecture.StoragePath),
+		ContentType: aws.String(format.MimeType),
+	})
+	if err != nil {
+		s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to create multipart upload")
+		return nil, fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+	uploadID := aws.ToString(out.UploadId)
+
+	if err := s.repo.SetMultipartUploadID(ctx, lectureID, &uploadID); err != nil {
+		s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to record multipart upload")
+		s.abortMultipartUpload(ctx, lecture.StoragePath, uploadID)
+		return nil, fmt.Errorf("failed to record multipart upload: %w", err)
+	}
+
+	partSize := multipartPartSize(sizeBytes)
+	return &MultipartUpload{
+		UploadID:      uploadID,
+		PartSizeBytes: partSize,
+		PartCount:     int32((sizeBytes + partSize - 1) / partSize),
+		ContentType:   format.MimeType,
+	}, nil
+}
+
+// PresignUploadParts returns presigned URLs for the given parts of the lecture's multipart upload.
+func (s *lectureService) PresignUploadParts(ctx context.Context, lectureID, userID string, partNumbers []int32) ([]PresignedPart, error) {
+	if len(partNumbers) == 0 || len(partNumbers) > maxPresignedPartsPerRequest {
+		return nil, fmt.Errorf("%w: between 1 and %d part numbers must be requested", ErrInvalidUploadParts, maxPresignedPartsPerRequest)
+	}
+	for _, n := range partNumbers {
+		if n < 1 || n > maxMultipartParts {
+			return nil, fmt.Errorf("%w: part number %d is out of range", ErrInvalidUploadParts, n)
+		}
+	}
+
+	lecture, uploadID, err := s.getMultipartLecture(ctx, lectureID, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Parts go straight to storage, so asking for part URLs is the only sign the upload is still
+	// alive; keep the upload janitor from deleting it.
+	if err := s.repo.TouchUploadingLecture(ctx, lectureID); err != nil {
+		s.lectureLogger.Warn().Err(err).Str("lecture_id", lectureID).Msg("Failed to mark multipart upload as active")
+	}
+
+	parts := make([]PresignedPart, 0, len(partNumbers))
+	for _, n := range partNumbers {
+		request, err := s.presignClient.PresignUploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String(s.bucketName),
+			Key:        aws.String(lecture.StoragePath),
+			UploadId:   aws.String(uploadID),
+			PartNumber: aws.Int32(n),
+		}, s3.WithPresignExpires(multipartPartURLExpiry))
+		if err != nil {
+			s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Int32("part_number", n).Msg("Failed to presign upload part")
+			return nil, fmt.Errorf("failed to presign part %d: %w", n, err)
+		}
+		parts = append(parts, PresignedPart{PartNumber: n, URL: request.URL, ExpiresAt: time.Now().Add(multipartPartURLExpiry)})
+	}
+	return parts, nil
+}
+
+// ListUploadedParts returns the parts storage has received so far, so a client can resume.
+func (s *lectureService) ListUploadedParts(ctx context.Context, lectureID, userID string) ([]UploadedPart, error) {
+	lecture, uploadID, err := s.getMultipartLecture(ctx, lectureID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.listUploadedParts(ctx, lecture.StoragePath, uploadID)
+}
+
+// AbortMultipartUpload cancels the lecture's multipart upload and discards the uploaded parts.
+// The lecture stays in 'uploading' so the client can start over.
+func (s *lectureService) AbortMultipartUpload(ctx context.Context, lectureID, userID string) error {
+	lecture, uploadID, err := s.getMultipartLecture(ctx, lectureID, userID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.s3Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucketName),
+		Key:      aws.String(lecture.StoragePath),
+		UploadId: aws.String(uploadID),
+	}); err != nil {
+		var notFound *types.NoSuchUpload
+		if !errors.As(err, &notFound) {
+			s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to abort multipart upload")
+			return fmt.Errorf("failed to abort multipart upload: %w", err)
+		}
+	}
+	if err := s.repo.SetMultipartUploadID(ctx, lectureID, nil); err != nil {
+		s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to clear aborted multipart upload")
+		return fmt.Errorf("failed to clear multipart upload: %w", err)
+	}
+	return nil
+}
+
+// completeMultipartUpload assembles the uploaded parts into the lecture's object. When no parts
+// are given, every part storage has received is used.
+func (s *lectureService) completeMultipartUpload(ctx context.Context, lecture *model.Lecture, parts []UploadedPart) error {
+	uploadID := *lecture.MultipartUploadID
+	if len(parts) == 0 {
+		listed, err := s.listUploadedParts(ctx, lecture.StoragePath, uploadID)
+		if err != nil {
+			return err
+		}
+		parts = listed
+	}
+	if len(parts) == 0 {
+		return fmt.Errorf("%w: no parts have been uploaded", ErrInvalidUploadParts)
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	completed := make([]types.CompletedPart, 0, len(parts))
+	for _, part := range parts {
+		completed = append(completed, types.CompletedPart{
+			PartNumber: aws.Int32(part.PartNumber),
+			ETag:       aws.String(part.ETag),
+		})
+	}
+
+	_, err := s.s3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucketName),
+		Key:             aws.String(lecture.StoragePath),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			switch apiErr.ErrorCode() {
+			case "InvalidPart", "InvalidPartOrder", "EntityTooSmall":
+				return fmt.Errorf("%w: %s", ErrInvalidUploadParts, apiErr.ErrorMessage())
+			}
+		}
+		s.lectureLogger.Error().Err(err).Str("lecture_id", lecture.ID).Msg("Failed to complete multipart upload")
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	if err := s.repo.SetMultipartUploadID(ctx, lecture.ID, nil); err != nil {
+		// The object is assembled; a stale ID is only noise for the sweeper.
+		s.lectureLogger.Warn().Err(err).Str("lecture_id", lecture.ID).Msg("Failed to clear completed multipart upload")
+	}
+	lecture.MultipartUploadID = nil
+	return nil
+}
+
+func (s *lectureService) listUploadedParts(ctx context.Context, storagePath, uploadID string) ([]UploadedPart, error) {
+	paginator := s3.NewListPartsPaginator(s.s3Client, &s3.ListPartsInput{
+		Bucket:   aws.String(s.bucketName),
+		Key:      aws.String(storagePath),
+		UploadId: aws.String(uploadID),
+	})
+	var parts []UploadedPart
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			var notFound *types.NoSuchUpload
+			if errors.As(err, &notFound) {
+				return nil, ErrMultipartUploadNotFound
+			}
+			s.lectureLogger.Error().Err(err).Str("upload_id", uploadID).Msg("Failed to list uploaded parts")
+			return nil, fmt.Errorf("failed to list uploaded parts: %w", err)
+		}
+		for _, part := range page.Parts {
+			parts = append(parts, UploadedPart{
+				PartNumber:   aws.ToInt32(part.PartNumber),
+				ETag:         aws.ToString(part.ETag),
+				SizeBytes:    aws.ToInt64(part.Size),
+				LastModified: part.LastModified,
+			})
+		}
+	}
+	return parts, nil
+}
+
+// abortMultipartUpload is a best-effort abort used during cleanup.
+func (s *lectureService) abortMultipartUpload(ctx context.Context, storagePath, uploadID string) {
+	if _, err := s.s3Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucketName),
+		Key:      aws.String(storagePath),
+		UploadId: aws.String(uploadID),
+	}); err != nil {
+		s.lectureLogger.Warn().Err(err).Str("upload_id", uploadID).Msg("Failed to abort multipart upload")
+	}
+}
+
+// getUploadingLecture loads a lecture owned by userID that is still awaiting its file.
+func (s *lectureService) getUploadingLecture(ctx context.Context, lectureID, userID string) (*model.Lecture, error) {
+	lecture, err := s.repo.GetLectureByID(ctx, lectureID)
+	if err != nil {
+		s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to get lecture for multipart upload")
+		return nil, fmt.Errorf("failed to retrieve lecture: %w", err)
+	}
+	if lecture == nil || lecture.UserID != userID {
+		return nil, ErrLectureNotFound
+	}
+	if lecture.Status != "uploading" {
+		return nil, ErrLectureNotUploading
+	}
+	return lecture, nil
+}
+
+// getMultipartLecture loads an uploading lecture together with its active multipart upload ID.
+func (s *lectureService) getMultipartLecture(ctx context.Context, lectureID, userID string) (*model.Lecture, string, error) {
+	lecture, err := s.getUploadingLecture(ctx, lectureID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if lecture.MultipartUploadID == nil {
+		return nil, "", ErrMultipartUploadNotFound
+	}
+	return lecture, *lecture.MultipartUploadID, nil
+}
+
+// multipartPartSize picks a part size that keeps the upload within maxMultipartParts parts.
+func multipartPartSize(sizeBytes int64) int64 {
+	partSize := int64(defaultMultipartPartSize)
+	if minSize := (sizeBytes + maxMultipartParts - 1) / maxMultipartParts; minSize > partSize {
+		partSize = minSize
+	}
+	return partSize
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"app/internal/model"
+)
+
+const (
+	// progressEstimateSampleSize is how many recently completed lectures an ETA is averaged over.
+	progressEstimateSampleSize = 50
+	// minEstimateSamples is the fewest similar lectures needed before their average is trusted;
+	// with fewer, the estimate falls back to lectures of any size.
+	minEstimateSamples = 3
+)
+
+// LectureProgress summarises how far a lecture has got through processing.
+type LectureProgress struct {
+	LectureID          string
+	Status             string
+	Stage              string
+	Percent            int
+	TotalSlides        int
+	TotalSubImages     int
+	ProcessedSubImages int
+	EmbeddingsComplete bool
+	StartedAt          *time.Time
+	CompletedAt        *time.Time
+	Elapsed            time.Duration
+	// EstimatedRemaining is nil when the lecture is not in flight or there is nothing to estimate from.
+	EstimatedRemaining *time.Duration
+}
+
+// NewLectureProgress derives the stage, percentage and elapsed time of a lecture as of now.
+// It does not estimate the remaining time, which needs processing history.
+func NewLectureProgress(lecture *model.Lecture, now time.Time) LectureProgress {
+	progress := LectureProgress{
+		LectureID:          lecture.ID,
+		Status:             lecture.Status,
+		TotalSlides:        lecture.TotalSlides,
+		TotalSubImages:     lecture.TotalSubImages,
+		ProcessedSubImages: lecture.ProcessedSubImages,
+		EmbeddingsComplete: lecture.EmbeddingsComplete,
+		StartedAt:          lecture.ProcessingStartedAt,
+		CompletedAt:        lecture.CompletedAt,
+	}
+
+	switch lecture.Status {
+	case "uploading":
+		progress.Stage, progress.Percent = "uploading", 0
+	case "pending_processing":
+		progress.Stage, progress.Percent = "queued", 5
+	case "parsing":
+		progress.Stage, progress.Percent = "parsing", 10
+	case "processing":
+		switch {
+		case lecture.TotalSubImages > 0 && lecture.ProcessedSubImages < lecture.TotalSubImages:
+			progress.Stage = "analyzing_images"
+			progress.Percent = 20 + 60*lecture.ProcessedSubImages/lecture.TotalSubImages
+		case !lecture.EmbeddingsComplete:
+			progress.Stage, progress.Percent = "embedding", 80
+		default:
+			progress.Stage, progress.Percent = "finalizing", 95
+		}
+	case "complete":
+		progress.Stage, progress.Percent = "complete", 100
+	case "failed":
+		progress.Stage, progress.Percent = "failed", 0
+	default:
+		progress.Stage = lecture.Status
+	}
+
+	if lecture.ProcessingStartedAt != nil {
+		end := now
+		switch {
+		case lecture.CompletedAt != nil:
+			end = *lecture.CompletedAt
+		case lecture.Status == "failed":
+			end = lecture.UpdatedAt
+		}
+		if end.After(*lecture.ProcessingStartedAt) {
+			progress.Elapsed = end.Sub(*lecture.ProcessingStartedAt)
+		}
+	}
+	return progress
+}
+
+// GetLectureProgress returns the progress of one of the user's lectures, with an ETA while it is in flight.
+func (s *lectureService) GetLectureProgress(ctx context.Context, lectureID, userID string) (*LectureProgress, error) {
+	lecture, err := s.repo.GetLectureByID(ctx, lectureID)
+	if err != nil {
+		s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to get lecture for progress")
+		return nil, fmt.Errorf("failed to retrieve lecture: %w", err)
+	}
+	if lecture == nil || lecture.UserID != userID {
+		return nil, ErrLectureNotFound
+	}
+
+	progress := NewLectureProgress(lecture, time.Now())
+	if isLectureInFlight(lecture.Status) {
+		if err := s.estimateRemaining(ctx, &progress, map[int]time.Duration{}); err != nil {
+			return nil, err
+		}
+	}
+	return &progress, nil
+}
+
+// GetInFlightLectureProgress returns the progress of every lecture the user has queued or processing.
+func (s *lectureService) GetInFlightLectureProgress(ctx context.Context, userID string) ([]LectureProgress, error) {
+	lectures, err := s.repo.GetInFlightLecturesByUserID(ctx, userID)
+	if err != nil {
+		s.lectureLogger.Error().Err(err).Str("user_id", userID).Msg("Failed to get in-flight lectures")
+		return nil, fmt.Errorf("failed to retrieve in-flight lectures: %w", err)
+	}
+
+	now := time.Now()
+	// Lectures with the same slide count share an estimate, so cache averages for this call
+	averages := map[int]time.Duration{}
+	progress := make([]LectureProgress, 0, len(lectures))
+	for i := range lectures {
+		p := NewLectureProgress(&lectures[i], now)
+		if err := s.estimateRemaining(ctx, &p, averages); err != nil {
+			return nil, err
+		}
+		progress = append(progress, p)
+	}
+	return progress, nil
+}
+
+// estimateRemaining fills in the ETA from how long recent lectures of a similar slide count took.
+// Once a lecture has outrun that average, its own rate of progress is extrapolated instead.
+func (s *lectureService) estimateRemaining(ctx context.Context, progress *LectureProgress, averages map[int]time.Duration) error {
+	average, ok := averages[progress.TotalSlides]
+	if !ok {
+		var err error
+		average, err = s.averageProcessingDuration(ctx, progress.TotalSlides)
+		if err != nil {
+			s.lectureLogger.Error().Err(err).Str("lecture_id", progress.LectureID).Msg("Failed to estimate lecture processing time")
+			return fmt.Errorf("failed to estimate processing time: %w", err)
+		}
+		averages[progress.TotalSlides] = average
+	}
+
+	remaining := average - progress.Elapsed
+	if remaining <= 0 {
+		if progress.Percent == 0 || progress.Elapsed == 0 {
+			return nil
+		}
+		remaining = progress.Elapsed * time.Duration(100-progress.Percent) / time.Duration(progress.Percent)
+	}
+	remaining = remaining.Round(time.Second)
+	progress.EstimatedRemaining = &remaining
+	return nil
+}
+
+// averageProcessingDuration averages recent lectures within 25% of totalSlides, falling back to
+// lectures of any size when there are too few similar ones. Zero means there is no history.
+// The slide count is unknown until parsing finishes, in which case every size is considered.
+func (s *lectureService) averageProcessingDuration(ctx context.Context, totalSlides int) (time.Duration, error) {
+	if totalSlides > 0 {
+		spread := max(totalSlides/4, 5)
+		average, count, err := s.repo.GetAverageProcessingDuration(ctx, max(totalSlides-spread, 0), totalSlides+spread, progressEstimateSampleSize)
+		if err != nil {
+			return 0, err
+		}
+		if count >= minEstimateSamples {
+			return average, nil
+		}
+	}
+	average, _, err := s.repo.GetAverageProcessingDuration(ctx, 0, math.MaxInt32, progressEstimateSampleSize)
+	return average, err
+}
+
+func isLectureInFlight(status string) bool {
+	switch status {
+	case "pending_processing", "parsing", "processing":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"app/internal/model"
+)
+
+func TestNewLectureProgressStage(t *testing.T) {
+	tests := []struct {
+		lecture     model.Lecture
+		wantStage   string
+		wantPercent int
+	}{
+		{model.Lecture{Status: "uploading"}, "uploading", 0},
+		{model.Lecture{Status: "pending_processing"}, "queued", 5},
+		{model.Lecture{Status: "parsing"}, "parsing", 10},
+		{model.Lecture{Status: "processing", TotalSubImages: 10}, "analyzing_images", 20},
+		{model.Lecture{Status: "processing", TotalSubImages: 10, ProcessedSubImages: 5}, "analyzing_images", 50},
+		{model.Lecture{Status: "processing", TotalSubImages: 3, ProcessedSubImages: 2}, "analyzing_images", 60},
+		{model.Lecture{Status: "processing", TotalSubImages: 10, ProcessedSubImages: 10}, "embedding", 80},
+		{model.Lecture{Status: "processing"}, "embedding", 80},
+		{model.Lecture{Status: "processing", TotalSubImages: 10, ProcessedSubImages: 10, EmbeddingsComplete: true}, "finalizing", 95},
+		{model.Lecture{Status: "processing", EmbeddingsComplete: true}, "finalizing", 95},
+		{model.Lecture{Status: "complete", EmbeddingsComplete: true}, "complete", 100},
+		{model.Lecture{Status: "failed", TotalSubImages: 10, ProcessedSubImages: 5}, "failed", 0},
+		{model.Lecture{Status: "archived"}, "archived", 0},
+	}
+	for _, tt := range tests {
+		got := NewLectureProgress(&tt.lecture, time.Now())
+		if got.Stage != tt.wantStage || got.Percent != tt.wantPercent {
+			t.Errorf("NewLectureProgress(%+v) = %s %d%%, want %s %d%%", tt.lecture, got.Stage, got.Percent, tt.wantStage, tt.wantPercent)
+		}
+	}
+}
+
+func TestNewLectureProgressElapsed(t *testing.T) {
+	now := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name    string
+		lecture model.Lecture
+		want    time.Duration
+	}{
+		{"not started", model.Lecture{Status: "pending_processing"}, 0},
+		{"in flight", model.Lecture{Status: "processing", ProcessingStartedAt: at(-3 * time.Minute)}, 3 * time.Minute},
+		{"complete", model.Lecture{Status: "complete", ProcessingStartedAt: at(-time.Hour), CompletedAt: at(-50 * time.Minute)}, 10 * time.Minute},
+		{"failed", model.Lecture{Status: "failed", ProcessingStartedAt: at(-time.Hour), UpdatedAt: *at(-55 * time.Minute)}, 5 * time.Minute},
+		// Clock skew between instances must not produce a negative duration
+		{"started in the future", model.Lecture{Status: "processing", ProcessingStartedAt: at(time.Minute)}, 0},
+	}
+	for _, tt := range tests {
+		if got := NewLectureProgress(&tt.lecture, now).Elapsed; got != tt.want {
+			t.Errorf("%s: Elapsed = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
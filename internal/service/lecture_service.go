@@ -3,7 +3,10 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"time"
 
 	"app/internal/model"
@@ -22,13 +25,63 @@ type LectureService interface {
 	GetLecturesByCourseID(ctx context.Context, courseID string, limit, offset int) ([]model.Lecture, error)
 	GetLectureByID(ctx context.Context, lectureID string) (*model.Lecture, error)
 	DeleteLecture(ctx context.Context, lectureID string) error
+	DeleteAbandonedUpload(ctx context.Context, lectureID string, updatedBefore time.Time) (bool, error)
 	UpdateLecture(ctx context.Context, l *model.Lecture) error
 
 	GetPresignedURL(ctx context.Context, storagePath string) (string, error)
 
-	InitiateUpload(ctx context.Context, courseID, userID, filename string) (*model.Lecture, string, error)
-	InitiateBatchUpload(ctx context.Context, courseID, userID string, filenames []string) ([]*model.Lecture, []string, error)
-	CompleteUpload(ctx context.Context, lectureID, userID string) (*model.Lecture, error)
+	InitiateUpload(ctx context.Context, courseID, userID string, file UploadFile) (*model.Lecture, string, error)
+	InitiateBatchUpload(ctx context.Context, courseID, userID string, files []UploadFile) ([]*model.Lecture, []string, error)
+	CompleteUpload(ctx context.Context, lectureID, userID string, parts []UploadedPart) (*model.Lecture, error)
+	ReprocessLecture(ctx context.Context, lectureID, userID string) (*model.Lecture, error)
+	GetLectureProgress(ctx context.Context, lectureID, userID string) (*LectureProgress, error)
+	GetInFlightLectureProgress(ctx context.Context, userID string) ([]LectureProgress, error)
+
+	InitiateMultipartUpload(ctx context.Context, lectureID, userID string, sizeBytes int64) (*MultipartUpload, error)
+	PresignUploadParts(ctx context.Context, lectureID, userID string, partNumbers []int32) ([]PresignedPart, error)
+	ListUploadedParts(ctx context.Context, lectureID, userID string) ([]UploadedPart, error)
+	AbortMultipartUpload(ctx context.Context, lectureID, userID string) error
+}
+
+var (
+	ErrLectureNotReprocessable = errors.New("lecture is already queued or processing")
+	ErrLectureFileMissing      = errors.New("lecture file not found in storage")
+	ErrUploadTooLarge          = errors.New("file exceeds the maximum upload size")
+	ErrUploadRejected          = errors.New("uploaded file failed validation")
+	ErrUploadAlreadyCompleted  = errors.New("lecture upload has already been completed")
+	ErrUnsupportedFileType     = errors.New("unsupported file type")
+	ErrUploadSizeRequired      = errors.New("the size of the file must be declared")
+)
+
+// fileHeaderLength is how many leading bytes of an upload are read to verify its format.
+const fileHeaderLength = 8
+
+// UploadFile describes a file the client intends to upload.
+type UploadFile struct {
+	Filename string
+	// FileType is the declared format (e.g. "pdf", "pptx"). When empty it is derived
+	// from the filename's extension.
+	FileType string
+	// SizeBytes is the declared size of the file, which the upload must match exactly.
+	SizeBytes int64
+}
+
+// reprocessStaleAfter is how long an in-flight lecture must go without progress
+// before it is considered stuck and may be reprocessed. Progress is read from updated_at,
+// which schema triggers bump whenever the pipeline changes the lecture's status or progress
+// counters and while it stores embeddings. It assumes no pipeline step runs this long
+// without recording any of those.
+const reprocessStaleAfter = 30 * time.Minute
+
+// ingestionPayload is the message body consumed by the ingestion worker.
+type ingestionPayload struct {
+	LectureID          string `json:"lecture_id"`
+	StoragePath        string `json:"storage_path"`
+	CustomerIdentifier string `json:"customer_identifier"`
+	Name               string `json:"name"`
+	Email              string `json:"email"`
+	FileType           string `json:"file_type"`
+	MimeType           string `json:"mime_type"`
 }
 
 // lectureService is the implementation of LectureService
@@ -38,8 +91,8 @@ type lectureService struct {
 	s3Client       *s3.Client
 	presignClient  *s3.PresignClient
 	bucketName     string
-	publisher      pubsub.Publisher
 	ingestionTopic string
+	maxUploadSize  int64
 	lectureLogger  zerolog.Logger
 }
 
@@ -49,8 +102,8 @@ func NewLectureService(
 	userRepo repository.UserRepository,
 	s3Client *s3.Client,
 	bucketName string,
-	publisher pubsub.Publisher,
 	ingestionTopic string,
+	maxUploadSize int64,
 	logger zerolog.Logger,
 ) LectureService {
 	return &lectureService{
@@ -59,20 +112,28 @@ func NewLectureService(
 		s3Client:       s3Client,
 		presignClient:  s3.NewPresignClient(s3Client),
 		bucketName:     bucketName,
-		publisher:      publisher,
 		ingestionTopic: ingestionTopic,
+		maxUploadSize:  maxUploadSize,
 		lectureLogger:  logger.With().Str("service", "LectureService").Logger(),
 	}
 }
 
 // InitiateUpload creates a lecture record and returns a presigned URL for upload.
-func (s *lectureService) InitiateUpload(ctx context.Context, courseID, userID, filename string) (*model.Lecture, string, error) {
+// The URL pins the content type and, when declared, the exact file size.
+func (s *lectureService) InitiateUpload(ctx context.Context, courseID, userID string, file UploadFile) (*model.Lecture, string, error) {
+	format, err := s.checkUploadFile(file)
+	if err != nil {
+		return nil, "", err
+	}
+
 	// 1. Create lecture record with 'uploading' status
 	lecture := &model.Lecture{
 		CourseID: courseID,
 		UserID:   userID,
-		Title:    filename, // Use filename as the initial title
+		Title:    file.Filename, // Use filename as the initial title
 		Status:   "uploading",
+		FileType: format.FileType,
+		MimeType: format.MimeType,
 	}
 	createdLecture, err := s.repo.CreateLecture(ctx, lecture)
 	if err != nil {
@@ -81,8 +142,8 @@ func (s *lectureService) InitiateUpload(ctx context.Context, courseID, userID, f
 	}
 
 	// 2. Generate presigned URL for direct S3 upload
-	storagePath := fmt.Sprintf("lectures/%s/original.pdf", createdLecture.ID)
-	presignedURL, err := s.getPresignedPutURL(ctx, storagePath)
+	storagePath := fmt.Sprintf("lectures/%s/original.%s", createdLecture.ID, format.Extension)
+	presignedURL, err := s.getPresignedPutURL(ctx, storagePath, format.MimeType, file.SizeBytes)
 	if err != nil {
 		// Attempt to clean up the created lecture record on failure
 		_ = s.repo.DeleteLecture(ctx, createdLecture.ID)
@@ -102,27 +163,33 @@ func (s *lectureService) InitiateUpload(ctx context.Context, courseID, userID, f
 }
 
 // InitiateBatchUpload creates multiple lecture records and returns presigned URLs for batch upload.
-func (s *lectureService) InitiateBatchUpload(ctx context.Context, courseID, userID string, filenames []string) ([]*model.Lecture, []string, error) {
-	if len(filenames) == 0 {
+func (s *lectureService) InitiateBatchUpload(ctx context.Context, courseID, userID string, files []UploadFile) ([]*model.Lecture, []string, error) {
+	if len(files) == 0 {
 		return nil, nil, fmt.Errorf("no filenames provided")
 	}
-	if len(filenames) > 10 {
+	if len(files) > 10 {
 		return nil, nil, fmt.Errorf("too many files: maximum 10 allowed")
 	}
+	// Reject invalid files up front so no lecture records are created for the batch
+	for _, file := range files {
+		if _, err := s.checkUploadFile(file); err != nil {
+			return nil, nil, err
+		}
+	}
 
 	var lectures []*model.Lecture
 	var presignedURLs []string
 
-	// Process each filename
-	for _, filename := range filenames {
-		lecture, presignedURL, err := s.InitiateUpload(ctx, courseID, userID, filename)
+	// Process each file
+	for _, file := range files {
+		lecture, presignedURL, err := s.InitiateUpload(ctx, courseID, userID, file)
 		if err != nil {
 			// If any file fails, clean up any successfully created lectures
 			for _, createdLecture := range lectures {
 				_ = s.repo.DeleteLecture(ctx, createdLecture.ID)
 			}
-			s.lectureLogger.Error().Err(err).Str("filename", filename).Msg("Failed to initiate upload for file in batch")
-			return nil, nil, fmt.Errorf("failed to initiate upload for %s: %w", filename, err)
+			s.lectureLogger.Error().Err(err).Str("filename", file.Filename).Msg("Failed to initiate upload for file in batch")
+			return nil, nil, fmt.Errorf("failed to initiate upload for %s: %w", file.Filename, err)
 		}
 
 		lectures = append(lectures, lecture)
@@ -132,8 +199,27 @@ func (s *lectureService) InitiateBatchUpload(ctx context.Context, courseID, user
 	return lectures, presignedURLs, nil
 }
 
-// CompleteUpload finalizes the upload, updates the lecture status, and triggers the processing pipeline.
-func (s *lectureService) CompleteUpload(ctx context.Context, lectureID, userID string) (*model.Lecture, error) {
+// checkUploadFile resolves the format of a file the client intends to upload and enforces the size limit.
+func (s *lectureService) checkUploadFile(file UploadFile) (lectureFormat, error) {
+	format, ok := resolveLectureFormat(file.FileType, file.Filename)
+	if !ok {
+		return lectureFormat{}, fmt.Errorf("%w: %s", ErrUnsupportedFileType, file.Filename)
+	}
+	if file.SizeBytes <= 0 {
+		return lectureFormat{}, fmt.Errorf("%w: %s", ErrUploadSizeRequired, file.Filename)
+	}
+	if file.SizeBytes > s.maxUploadSize {
+		return lectureFormat{}, fmt.Errorf("%w: %s is %d bytes, the limit is %d bytes", ErrUploadTooLarge, file.Filename, file.SizeBytes, s.maxUploadSize)
+	}
+	return format, nil
+}
+
+// CompleteUpload finalizes the upload, updates the lecture status, and queues the ingestion job.
+// If a multipart upload is in progress it is assembled from parts first (all uploaded parts when
+// none are given). The job is written to the outbox in the same transaction as the status change
+// and published asynchronously by the outbox dispatcher. Only lectures that are still uploading
+// can be completed; use ReprocessLecture to queue a lecture again.
+func (s *lectureService) CompleteUpload(ctx context.Context, lectureID, userID string, parts []UploadedPart) (*model.Lecture, error) {
 	// 1. Retrieve the lecture
 	lecture, err := s.repo.GetLectureByID(ctx, lectureID)
 	if err != nil {
@@ -146,61 +232,205 @@ func (s *lectureService) CompleteUpload(ctx context.Context, lectureID, userID s
 	if lecture.UserID != userID {
 		return nil, fmt.Errorf("user does not own this lecture")
 	}
+	if lecture.Status != "uploading" {
+		return nil, ErrUploadAlreadyCompleted
+	}
+
+	if lecture.MultipartUploadID != nil {
+		if err := s.completeMultipartUpload(ctx, lecture, parts); err != nil {
+			return nil, err
+		}
+	}
+
+	// Verify the uploaded object before handing it to the pipeline
+	if err := s.validateUploadedFile(ctx, lecture); err != nil {
+		return nil, err
+	}
+
+	// 2. Update status to 'pending_processing' and queue the ingestion job
+	job, err := s.newIngestionJob(ctx, lecture)
+	if err != nil {
+		s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to build ingestion job")
+		return nil, fmt.Errorf("failed to build ingestion job: %w", err)
+	}
+	claimed, err := s.repo.ClaimUploadedLecture(ctx, lectureID, job)
+	if err != nil {
+		s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to update lecture status to pending")
+		return nil, fmt.Errorf("failed to update lecture status: %w", err)
+	}
+	if !claimed {
+		// A concurrent completion got there first
+		return nil, ErrUploadAlreadyCompleted
+	}
+
+	lecture.Status = "pending_processing"
+	lecture.EmbeddingErrorDetails = nil
+	return lecture, nil
+}
+
+// validateUploadedFile checks that the uploaded object exists, is within the size limit and
+// starts with the header of the lecture's declared format. Rejected lectures are marked failed
+// with a structured reason and their object is removed, since nothing will ever process it.
+func (s *lectureService) validateUploadedFile(ctx context.Context, lecture *model.Lecture) error {
+	log := s.lectureLogger.With().Str("lecture_id", lecture.ID).Str("storage_path", lecture.StoragePath).Logger()
+
+	head, err := s.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(lecture.StoragePath),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if !errors.As(err, &notFound) {
+			// Storage may be briefly unavailable; leave the lecture uploading so the client can retry
+			log.Error().Err(err).Msg("Failed to check uploaded file in S3")
+			return fmt.Errorf("failed to check uploaded file: %w", err)
+		}
+		log.Error().Err(err).Msg("File not found in S3 at expected path")
+		s.rejectUpload(ctx, lecture, model.EmbeddingErrorDetails{
+			"reason":  "file_missing",
+			"message": "The uploaded file was not found in storage",
+		}, false)
+		return fmt.Errorf("file not found in storage: %w", err)
+	}
+
+	size := aws.ToInt64(head.ContentLength)
+	if size > s.maxUploadSize {
+		log.Warn().Int64("size_bytes", size).Msg("Rejecting upload that exceeds the maximum size")
+		s.rejectUpload(ctx, lecture, model.EmbeddingErrorDetails{
+			"reason":         "file_too_large",
+			"message":        "The uploaded file exceeds the maximum upload size",
+			"size_bytes":     size,
+			"max_size_bytes": s.maxUploadSize,
+		}, true)
+		return fmt.Errorf("%w: file is %d bytes, the limit is %d bytes", ErrUploadRejected, size, s.maxUploadSize)
+	}
+
+	obj, err := s.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(lecture.StoragePath),
+		Range:  aws.String(fmt.Sprintf("bytes=0-%d", fileHeaderLength-1)),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read uploaded file header")
+		return fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	defer obj.Body.Close()
+
+	header := make([]byte, fileHeaderLength)
+	n, err := io.ReadFull(obj.Body, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		log.Error().Err(err).Msg("Failed to read uploaded file header")
+		return fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	format := lectureFormatFor(lecture.FileType)
+	if !format.matchesHeader(header[:n]) {
+		log.Warn().Int64("size_bytes", size).Str("file_type", format.FileType).Msg("Rejecting upload that does not match its declared format")
+		s.rejectUpload(ctx, lecture, model.EmbeddingErrorDetails{
+			"reason":     "invalid_file_type",
+			"message":    fmt.Sprintf("The uploaded file is not a valid %s file", strings.ToUpper(format.FileType)),
+			"file_type":  format.FileType,
+			"size_bytes": size,
+		}, true)
+		return fmt.Errorf("%w: file is not a valid %s file", ErrUploadRejected, format.FileType)
+	}
+
+	return nil
+}
+
+// rejectUpload marks the lecture failed with the given details and optionally deletes the object.
+func (s *lectureService) rejectUpload(ctx context.Context, lecture *model.Lecture, details model.EmbeddingErrorDetails, deleteObject bool) {
+	details["failed_at"] = time.Now().UTC()
+	if err := s.repo.UpdateLectureStatus(ctx, lecture.ID, "failed", details); err != nil {
+		s.lectureLogger.Error().Err(err).Str("lecture_id", lecture.ID).Msg("Failed to mark rejected upload as failed")
+	}
+	if !deleteObject {
+		return
+	}
+	if _, err := s.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(lecture.StoragePath),
+	}); err != nil {
+		s.lectureLogger.Warn().Err(err).Str("storage_path", lecture.StoragePath).Msg("Failed to delete rejected upload")
+	}
+}
 
-	// Optional: Verify the object exists in S3 before proceeding
+// ReprocessLecture re-queues a lecture whose ingestion failed or never started.
+// Lectures that are already queued or processing are rejected unless they have made
+// no progress for reprocessStaleAfter, so the same lecture cannot be queued twice.
+func (s *lectureService) ReprocessLecture(ctx context.Context, lectureID, userID string) (*model.Lecture, error) {
+	lecture, err := s.repo.GetLectureByID(ctx, lectureID)
+	if err != nil {
+		s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to get lecture for reprocessing")
+		return nil, fmt.Errorf("failed to retrieve lecture: %w", err)
+	}
+	if lecture == nil || lecture.UserID != userID {
+		return nil, ErrLectureNotFound
+	}
+	if lecture.StoragePath == "" {
+		return nil, ErrLectureFileMissing
+	}
+
+	// Verify the uploaded file is still in storage before queueing any work
 	_, err = s.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(s.bucketName),
 		Key:    aws.String(lecture.StoragePath),
 	})
 	if err != nil {
-		s.lectureLogger.Error().Err(err).Str("storage_path", lecture.StoragePath).Msg("File not found in S3 at expected path")
-		lecture.Status = "failed"
-		_ = s.repo.UpdateLecture(ctx, lecture) // Mark as failed
-		return nil, fmt.Errorf("file not found in storage: %w", err)
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, ErrLectureFileMissing
+		}
+		s.lectureLogger.Error().Err(err).Str("storage_path", lecture.StoragePath).Msg("Failed to check lecture file in S3")
+		return nil, fmt.Errorf("failed to check lecture file: %w", err)
 	}
 
-	// 2. Update status to 'pending_processing'
-	lecture.Status = "pending_processing"
-	if err := s.repo.UpdateLecture(ctx, lecture); err != nil {
-		s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to update lecture status to pending")
+	job, err := s.newIngestionJob(ctx, lecture)
+	if err != nil {
+		s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to build ingestion job for reprocessing")
+		return nil, fmt.Errorf("failed to build ingestion job: %w", err)
+	}
+	claimed, err := s.repo.ClaimLectureForReprocessing(ctx, lectureID, time.Now().Add(-reprocessStaleAfter), job)
+	if err != nil {
+		s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to claim lecture for reprocessing")
 		return nil, fmt.Errorf("failed to update lecture status: %w", err)
 	}
+	if !claimed {
+		return nil, ErrLectureNotReprocessable
+	}
 
-	// 3. Publish ingestion job
-	user, err := s.userRepo.GetUserByID(ctx, userID)
+	lecture.Status = "pending_processing"
+	lecture.EmbeddingErrorDetails = nil
+	return lecture, nil
+}
+
+// newIngestionJob builds the outbox message for a lecture's ingestion job, enriched with the owner's profile.
+func (s *lectureService) newIngestionJob(ctx context.Context, lecture *model.Lecture) (*model.OutboxMessage, error) {
+	format := lectureFormatFor(lecture.FileType)
+	user, err := s.userRepo.GetUserByID(ctx, lecture.UserID)
 	if err != nil {
-		s.lectureLogger.Warn().Err(err).Str("user_id", userID).Msg("Could not fetch user details for ingestion job enrichment")
+		s.lectureLogger.Warn().Err(err).Str("user_id", lecture.UserID).Msg("Could not fetch user details for ingestion job enrichment")
 	}
 	name, email := "", ""
 	if user != nil {
 		name, email = user.Name, user.Email
 	}
 
-	payload := struct {
-		LectureID          string `json:"lecture_id"`
-		StoragePath        string `json:"storage_path"`
-		CustomerIdentifier string `json:"customer_identifier"`
-		Name               string `json:"name"`
-		Email              string `json:"email"`
-	}{
-		LectureID:          lectureID,
+	payload := ingestionPayload{
+		LectureID:          lecture.ID,
 		StoragePath:        lecture.StoragePath,
-		CustomerIdentifier: userID,
+		CustomerIdentifier: lecture.UserID,
 		Name:               name,
 		Email:              email,
+		FileType:           format.FileType,
+		MimeType:           format.MimeType,
 	}
 	data, err := json.Marshal(payload)
 	if err != nil {
-		s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to marshal ingestion payload")
-		// Don't return error, but log it. The lecture is uploaded, but processing needs manual trigger.
-	} else {
-		if _, err := s.publisher.Publish(ctx, s.ingestionTopic, data); err != nil {
-			s.lectureLogger.Error().Err(err).Str("topic", s.ingestionTopic).Msg("Failed to publish ingestion job")
-			// Don't return an error here either.
-		}
+		return nil, fmt.Errorf("marshaling ingestion payload: %w", err)
 	}
-
-	return lecture, nil
+	// The job is published later by the outbox dispatcher, so the request ID and trace are captured now
+	attributes := pubsub.WithTraceContext(ctx, pubsub.WithRequestID(ctx, nil))
+	return &model.OutboxMessage{Topic: s.ingestionTopic, Payload: data, Attributes: attributes, LectureID: &lecture.ID}, nil
 }
 
 // GetLecturesByCourseID retrieves lectures for a given course with pagination
@@ -235,6 +465,44 @@ func (s *lectureService) DeleteLecture(ctx context.Context, lectureID string) er
 		return fmt.Errorf("lecture not found")
 	}
 
+	// Storage cleanup failures are logged but do not stop the DB record from being deleted
+	s.deleteLectureStorage(ctx, lecture)
+
+	// Delete lecture and cascade database cleanup
+	if err := s.repo.DeleteLecture(ctx, lectureID); err != nil {
+		s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to delete lecture from database")
+		return err
+	}
+	return nil
+}
+
+// DeleteAbandonedUpload deletes a lecture that is still uploading and has not changed since
+// updatedBefore, then removes its objects from storage. It reports false, deleting nothing,
+// when the lecture no longer qualifies, for instance because its upload was completed meanwhile.
+func (s *lectureService) DeleteAbandonedUpload(ctx context.Context, lectureID string, updatedBefore time.Time) (bool, error) {
+	lecture, err := s.repo.DeleteStaleUploadingLecture(ctx, lectureID, updatedBefore)
+	if err != nil {
+		s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to delete abandoned upload from database")
+		return false, err
+	}
+	if lecture == nil {
+		return false, nil
+	}
+	s.deleteLectureStorage(ctx, lecture)
+	return true, nil
+}
+
+// deleteLectureStorage aborts the lecture's unfinished multipart upload, if any, and deletes
+// every object under its storage folder. Failures are logged, since the objects are unreachable
+// once the lecture is gone.
+func (s *lectureService) deleteLectureStorage(ctx context.Context, lecture *model.Lecture) {
+	lectureID := lecture.ID
+
+	// Abort an unfinished multipart upload so its parts do not linger in storage
+	if lecture.MultipartUploadID != nil {
+		s.abortMultipartUpload(ctx, lecture.StoragePath, *lecture.MultipartUploadID)
+	}
+
 	// Delete all objects under the lecture's storage folder from S3
 	prefix := fmt.Sprintf("lectures/%s/", lectureID)
 	paginator := s3.NewListObjectsV2Paginator(s.s3Client, &s3.ListObjectsV2Input{
@@ -258,16 +526,8 @@ func (s *lectureService) DeleteLecture(ctx context.Context, lectureID string) er
 			Delete: &types.Delete{Objects: toDelete, Quiet: aws.Bool(true)},
 		}); err != nil {
 			s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to delete objects from S3")
-			// This is not a fatal error, we can still proceed to delete the DB record.
 		}
 	}
-
-	// Delete lecture and cascade database cleanup
-	if err := s.repo.DeleteLecture(ctx, lectureID); err != nil {
-		s.lectureLogger.Error().Err(err).Str("lecture_id", lectureID).Msg("Failed to delete lecture from database")
-		return err
-	}
-	return nil
 }
 
 // UpdateLecture applies title and accessed_at changes to a lecture
@@ -293,11 +553,15 @@ func (s *lectureService) GetPresignedURL(ctx context.Context, storagePath string
 }
 
 // getPresignedPutURL generates a presigned URL for uploading an object.
-func (s *lectureService) getPresignedPutURL(ctx context.Context, objectKey string) (string, error) {
-	request, err := s.presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(s.bucketName),
-		Key:    aws.String(objectKey),
-	}, s3.WithPresignExpires(15*time.Minute))
+// The content type and length are signed, so storage rejects uploads of any other type or size.
+func (s *lectureService) getPresignedPutURL(ctx context.Context, objectKey, contentType string, sizeBytes int64) (string, error) {
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucketName),
+		Key:           aws.String(objectKey),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(sizeBytes),
+	}
+	request, err := s.presignClient.PresignPutObject(ctx, input, s3.WithPresignExpires(15*time.Minute))
 	if err != nil {
 		s.lectureLogger.Error().Err(err).Str("object_key", objectKey).Msg("Failed to generate presigned PUT URL")
 		return "", fmt.Errorf("failed to generate presigned PUT URL: %w", err)
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"app/internal/repository"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rs/zerolog"
+)
+
+// MultipartUploadSweeper periodically aborts multipart lecture uploads that were started but
+// never completed, so their parts stop taking up storage.
+type MultipartUploadSweeper struct {
+	s3Client    *s3.Client
+	bucketName  string
+	lectureRepo repository.LectureRepository
+	ttl         time.Duration
+	interval    time.Duration
+	logger      zerolog.Logger
+}
+
+// NewMultipartUploadSweeper creates a sweeper that aborts uploads older than ttl every interval.
+func NewMultipartUploadSweeper(s3Client *s3.Client, bucketName string, lectureRepo repository.LectureRepository, ttl, interval time.Duration, logger zerolog.Logger) *MultipartUploadSweeper {
+	return &MultipartUploadSweeper{
+		s3Client:    s3Client,
+		bucketName:  bucketName,
+		lectureRepo: lectureRepo,
+		ttl:         ttl,
+		interval:    interval,
+		logger:      logger.With().Str("service", "MultipartUploadSweeper").Logger(),
+	}
+}
+
+// Run sweeps every interval until ctx is cancelled.
+func (s *MultipartUploadSweeper) Run(ctx context.Context) {
+	s.logger.Info().Dur("ttl", s.ttl).Dur("interval", s.interval).Msg("Starting multipart upload sweeper")
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.sweep(ctx)
+		select {
+		case <-ctx.Done():
+			s.logger.Info().Msg("Stopped multipart upload sweeper")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *MultipartUploadSweeper) sweep(ctx context.Context) {
+	cutoff := time.Now().Add(-s.ttl)
+	scanned, aborted, failed := 0, 0, 0
+
+	paginator := s3.NewListMultipartUploadsPaginator(s.s3Client, &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(s.bucketName),
+		Prefix: aws.String("lectures/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error().Err(err).Msg("Failed to list multipart uploads")
+			}
+			break
+		}
+		for _, upload := range page.Uploads {
+			scanned++
+			if upload.Initiated == nil || upload.Initiated.After(cutoff) {
+				continue
+			}
+			uploadID := aws.ToString(upload.UploadId)
+			if _, err := s.s3Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(s.bucketName),
+				Key:      upload.Key,
+				UploadId: upload.UploadId,
+			}); err != nil {
+				failed++
+				s.logger.Warn().Err(err).Str("upload_id", uploadID).Str("key", aws.ToString(upload.Key)).Msg("Failed to abort abandoned multipart upload")
+				continue
+			}
+			if err := s.lectureRepo.ClearMultipartUploadID(ctx, uploadID); err != nil {
+				s.logger.Warn().Err(err).Str("upload_id", uploadID).Msg("Failed to detach aborted multipart upload from lecture")
+			}
+			aborted++
+		}
+	}
+
+	s.logger.Info().
+		Int("scanned", scanned).
+		Int("aborted", aborted).
+		Int("failed", failed).
+		Msg("Multipart upload sweep finished")
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"app/internal/model"
+	"app/internal/pubsub"
+	"app/internal/repository"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// outboxPublishTimeout bounds a single publish attempt.
+	outboxPublishTimeout = 30 * time.Second
+	// outboxLease hides claimed messages from other dispatchers while they are being published.
+	// It must exceed outboxPublishTimeout; if the process dies mid-publish the message becomes
+	// due again once the lease expires, which makes delivery at-least-once.
+	outboxLease = 2 * time.Minute
+	// outboxBaseRetryDelay and outboxMaxRetryDelay bound the exponential backoff between attempts.
+	outboxBaseRetryDelay = 5 * time.Second
+	outboxMaxRetryDelay  = 10 * time.Minute
+	// outboxUpdateTimeout bounds recording the outcome of an attempt, which still runs during shutdown.
+	outboxUpdateTimeout = 5 * time.Second
+)
+
+// OutboxDispatcherConfig controls how often and how persistently the outbox is drained.
+type OutboxDispatcherConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+}
+
+// OutboxDispatcher publishes messages recorded in the transactional outbox to Pub/Sub.
+type OutboxDispatcher struct {
+	repo      repository.OutboxRepository
+	publisher pubsub.Publisher
+	cfg       OutboxDispatcherConfig
+	logger    zerolog.Logger
+}
+
+// NewOutboxDispatcher creates a new OutboxDispatcher.
+func NewOutboxDispatcher(repo repository.OutboxRepository, publisher pubsub.Publisher, cfg OutboxDispatcherConfig, logger zerolog.Logger) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		repo:      repo,
+		publisher: publisher,
+		cfg:       cfg,
+		logger:    logger.With().Str("service", "OutboxDispatcher").Logger(),
+	}
+}
+
+// Run drains the outbox every poll interval until ctx is cancelled.
+func (d *OutboxDispatcher) Run(ctx context.Context) {
+	d.logger.Info().Dur("poll_interval", d.cfg.PollInterval).Msg("Starting outbox dispatcher")
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.drain(ctx)
+		select {
+		case <-ctx.Done():
+			d.logger.Info().Msg("Stopped outbox dispatcher")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain publishes due messages batch by batch until none are left.
+func (d *OutboxDispatcher) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		messages, err := d.repo.ClaimDue(ctx, d.cfg.BatchSize, time.Now().Add(outboxLease))
+		if err != nil {
+			if ctx.Err() == nil {
+				d.logger.Error().Err(err).Msg("Failed to claim due outbox messages")
+			}
+			return
+		}
+		for i := range messages {
+			d.dispatch(ctx, &messages[i])
+		}
+		if len(messages) < d.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (d *OutboxDispatcher) dispatch(ctx context.Context, message *model.OutboxMessage) {
+	log := d.logger.With().Str("outbox_id", message.ID).Str("topic", message.Topic).Int("attempt", message.Attempts).Logger()
+
+	publishCtx, cancel := context.WithTimeout(ctx, outboxPublishTimeout)
+	publishedID, publishErr := d.publisher.Publish(publishCtx, message.Topic, message.Payload, message.Attributes)
+	cancel()
+
+	// Record the outcome even if shutdown has begun, so a published message is not sent again.
+	updateCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), outboxUpdateTimeout)
+	defer cancel()
+
+	if publishErr == nil {
+		if err := d.repo.MarkPublished(updateCtx, message.ID, publishedID); err != nil {
+			log.Error().Err(err).Str("published_message_id", publishedID).Msg("Published outbox message but failed to mark it as published")
+			return
+		}
+		log.Debug().Str("published_message_id", publishedID).Msg("Published outbox message")
+		return
+	}
+
+	if message.Attempts >= d.cfg.MaxAttempts {
+		// Fail the lecture too, so the user sees the error and can reprocess it
+		details := model.EmbeddingErrorDetails{
+			"reason":    "publish_failed",
+			"message":   "Lecture processing could not be started. Please try again.",
+			"attempts":  message.Attempts,
+			"failed_at": time.Now().UTC(),
+		}
+		if err := d.repo.MarkFailed(updateCtx, message.ID, publishErr.Error(), details); err != nil {
+			log.Error().Err(err).Msg("Failed to record failed outbox message")
+			return
+		}
+		log.Error().Err(publishErr).Msg("Giving up on outbox message after exhausting publish attempts")
+		return
+	}
+
+	nextAttemptAt := time.Now().Add(outboxBackoff(message.Attempts))
+	if err := d.repo.MarkAttemptFailed(updateCtx, message.ID, publishErr.Error(), nextAttemptAt); err != nil {
+		log.Error().Err(err).Msg("Failed to record failed outbox publish")
+		return
+	}
+	log.Warn().Err(publishErr).Time("next_attempt_at", nextAttemptAt).Msg("Failed to publish outbox message, will retry")
+}
+
+// outboxBackoff returns the delay after the given (1-based) failed attempt.
+func outboxBackoff(attempt int) time.Duration {
+	delay := outboxBaseRetryDelay
+	for i := 1; i < attempt && delay < outboxMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxMaxRetryDelay)
+}
@@ -10,12 +10,20 @@ import (
 	"net/http"
 	"strings"
 
+	"app/internal/logger"
+	"app/internal/model"
+
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type PythonClient interface {
-	StreamChat(ctx context.Context, lectureID, chatID, userID string, messageParts []map[string]interface{}, model string) (io.ReadCloser, error)
-	GenerateChatTitle(ctx context.Context, lectureID, chatID, userID string, userMessageParts []map[string]interface{}, assistantMessageParts []map[string]interface{}) (string, error)
+	// StreamChat streams a reply grounded in the lectures with lectureIDs, which for a lecture chat
+	// is only the chat's lecture.
+	StreamChat(ctx context.Context, scope model.ChatScope, lectureIDs []string, chatID, userID string, history []ChatHistoryMessage, messageParts []map[string]interface{}, modelName string) (io.ReadCloser, error)
+	GenerateChatTitle(ctx context.Context, scope model.ChatScope, chatID, userID string, userMessageParts []map[string]interface{}, assistantMessageParts []map[string]interface{}) (string, error)
+	// EmbedQuery embeds text with the same model as lecture chunks, using the user's API key.
+	EmbedQuery(ctx context.Context, userID, text string) ([]float32, error)
 }
 
 type pythonClient struct {
@@ -27,29 +35,46 @@ type pythonClient struct {
 func NewPythonClient(baseURL string, logger zerolog.Logger) PythonClient {
 	return &pythonClient{
 		baseURL: baseURL,
-		client:  &http.Client{
+		client: &http.Client{
 			// No timeout for streaming - rely on context cancellation instead
 			// This allows long-running streaming responses without premature cancellation
+			// The transport records a client span per call and sends the W3C trace context headers
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
 		},
 		logger: logger.With().Str("service", "PythonClient").Logger(),
 	}
 }
 
+// ChatRequest asks for a reply in a chat. Course chats leave LectureID empty and set CourseID;
+// LectureIDs always lists the lectures to answer from.
 type ChatRequest struct {
-	LectureID string                   `json:"lecture_id"`
-	ChatID    string                   `json:"chat_id"`
-	UserID    string                   `json:"user_id"`
-	Message   []map[string]interface{} `json:"message"`
-	Model     string                   `json:"model"`
+	LectureID  string                   `json:"lecture_id,omitempty"`
+	CourseID   string                   `json:"course_id,omitempty"`
+	LectureIDs []string                 `json:"lecture_ids"`
+	ChatID     string                   `json:"chat_id"`
+	UserID     string                   `json:"user_id"`
+	History    []ChatHistoryMessage     `json:"history"`
+	Message    []map[string]interface{} `json:"message"`
+	Model      string                   `json:"model"`
+}
+
+// ChatHistoryMessage is an earlier message on the active branch of a chat. The history is sent
+// with each request because a chat may hold several branches, only one of which is being continued.
+type ChatHistoryMessage struct {
+	Role  string                   `json:"role"`
+	Parts []map[string]interface{} `json:"parts"`
 }
 
-func (c *pythonClient) StreamChat(ctx context.Context, lectureID, chatID, userID string, messageParts []map[string]interface{}, model string) (io.ReadCloser, error) {
+func (c *pythonClient) StreamChat(ctx context.Context, scope model.ChatScope, lectureIDs []string, chatID, userID string, history []ChatHistoryMessage, messageParts []map[string]interface{}, modelName string) (io.ReadCloser, error) {
 	reqBody := ChatRequest{
-		LectureID: lectureID,
-		ChatID:    chatID,
-		UserID:    userID,
-		Message:   messageParts,
-		Model:     model,
+		LectureID:  scope.LectureID,
+		CourseID:   scope.CourseID,
+		LectureIDs: lectureIDs,
+		ChatID:     chatID,
+		UserID:     userID,
+		History:    history,
+		Message:    messageParts,
+		Model:      modelName,
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -63,7 +88,7 @@ func (c *pythonClient) StreamChat(ctx context.Context, lectureID, chatID, userID
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	setRequestHeaders(req)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -93,7 +118,8 @@ func (c *pythonClient) StreamChat(ctx context.Context, lectureID, chatID, userID
 }
 
 type TitleRequest struct {
-	LectureID        string                   `json:"lecture_id"`
+	LectureID        string                   `json:"lecture_id,omitempty"`
+	CourseID         string                   `json:"course_id,omitempty"`
 	ChatID           string                   `json:"chat_id"`
 	UserID           string                   `json:"user_id"`
 	UserMessage      []map[string]interface{} `json:"user_message"`
@@ -104,9 +130,10 @@ type TitleResponse struct {
 	Title string `json:"title"`
 }
 
-func (c *pythonClient) GenerateChatTitle(ctx context.Context, lectureID, chatID, userID string, userMessageParts []map[string]interface{}, assistantMessageParts []map[string]interface{}) (string, error) {
+func (c *pythonClient) GenerateChatTitle(ctx context.Context, scope model.ChatScope, chatID, userID string, userMessageParts []map[string]interface{}, assistantMessageParts []map[string]interface{}) (string, error) {
 	reqBody := TitleRequest{
-		LectureID:        lectureID,
+		LectureID:        scope.LectureID,
+		CourseID:         scope.CourseID,
 		ChatID:           chatID,
 		UserID:           userID,
 		UserMessage:      userMessageParts,
@@ -124,7 +151,7 @@ func (c *pythonClient) GenerateChatTitle(ctx context.Context, lectureID, chatID,
 		return "", fmt.Errorf("creating request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	setRequestHeaders(req)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -160,10 +187,114 @@ func (c *pythonClient) GenerateChatTitle(ctx context.Context, lectureID, chatID,
 	return titleResp.Title, nil
 }
 
+type EmbeddingRequest struct {
+	UserID string `json:"user_id"`
+	Text   string `json:"text"`
+}
+
+type EmbeddingResponse struct {
+	Embedding []float32 `json:"embedding"`
+}
+
+func (c *pythonClient) EmbedQuery(ctx context.Context, userID, text string) ([]float32, error) {
+	reqBody := EmbeddingRequest{
+		UserID: userID,
+		Text:   text,
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request body: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/embeddings", c.baseURL)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	setRequestHeaders(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request to Python service: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			c.logger.Warn().Err(closeErr).Msg("Failed to close response body")
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, readErr := io.ReadAll(resp.Body)
+		if readErr != nil {
+			c.logger.Warn().Err(readErr).Int("status_code", resp.StatusCode).Msg("Failed to read error body from Python service")
+			return nil, fmt.Errorf("python service returned status %d", resp.StatusCode)
+		}
+
+		errorMsg := string(bodyBytes)
+		c.logger.Error().
+			Int("status_code", resp.StatusCode).
+			Str("error_body", errorMsg).
+			Msg("Python service returned error")
+
+		return nil, fmt.Errorf("python service returned status %d: %s", resp.StatusCode, errorMsg)
+	}
+
+	var embeddingResp EmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embeddingResp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if len(embeddingResp.Embedding) == 0 {
+		return nil, fmt.Errorf("python service returned an empty embedding")
+	}
+
+	return embeddingResp.Embedding, nil
+}
+
+// Chunk types sent by the Python chat stream
+const (
+	ChatChunkText       = "text"
+	ChatChunkReasoning  = "reasoning"
+	ChatChunkReference  = "reference"
+	ChatChunkSource     = "source"
+	ChatChunkToolCall   = "tool-call"
+	ChatChunkToolResult = "tool-result"
+)
+
+// ChatStreamChunk is one event of the Python chat stream. Type selects which of the fields is
+// set; a chunk without a type is text, as sent by services predating typed chunks.
+type ChatStreamChunk struct {
+	Type      string               `json:"type"`
+	Content   string               `json:"content"`             // text and reasoning
+	Reference *model.ReferencePart `json:"reference,omitempty"` // e.g. a slide citation, see model.ReferenceTypeSlide
+	Source    *ChatSourceChunk     `json:"source,omitempty"`    // source citation
+	Tool      *ChatToolChunk       `json:"tool,omitempty"`      // tool call or its result
+	Done      bool                 `json:"done"`
+	Error     string               `json:"error,omitempty"`
+}
+
+// ChatSourceChunk cites a source of the answer: a web page when URL is set, otherwise a document.
+type ChatSourceChunk struct {
+	ID        string `json:"id"`
+	URL       string `json:"url,omitempty"`
+	Title     string `json:"title,omitempty"`
+	MediaType string `json:"media_type,omitempty"`
+	Filename  string `json:"filename,omitempty"`
+}
+
+// ChatToolChunk carries the input of a tool call, or its output for a tool-result chunk.
+type ChatToolChunk struct {
+	CallID string      `json:"call_id"`
+	Name   string      `json:"name"`
+	Input  interface{} `json:"input,omitempty"`
+	Output interface{} `json:"output,omitempty"`
+}
+
 // ParseSSEChunk parses a single SSE chunk from the stream.
 // SSE format: "data: <json>\n\n" where blank line separates events.
 // Handles comments (lines starting with ":") and empty lines.
-func ParseSSEChunk(reader *bufio.Reader) (map[string]interface{}, error) {
+func ParseSSEChunk(reader *bufio.Reader) (*ChatStreamChunk, error) {
 	var dataLine string
 	foundData := false
 
@@ -215,10 +346,22 @@ func ParseSSEChunk(reader *bufio.Reader) (map[string]interface{}, error) {
 		return nil, fmt.Errorf("no data line found in SSE chunk")
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal([]byte(dataLine), &result); err != nil {
+	var chunk ChatStreamChunk
+	if err := json.Unmarshal([]byte(dataLine), &chunk); err != nil {
 		return nil, fmt.Errorf("unmarshaling SSE data %q: %w", dataLine, err)
 	}
+	if chunk.Type == "" {
+		chunk.Type = ChatChunkText
+	}
 
-	return result, nil
+	return &chunk, nil
+}
+
+// setRequestHeaders sets the headers of a JSON request, including the ID of the API request
+// it is made for, so that both services' logs can be correlated.
+func setRequestHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	if requestID := logger.RequestIDFromContext(req.Context()); requestID != "" {
+		req.Header.Set(logger.RequestIDHeader, requestID)
+	}
 }
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"app/internal/model"
+	"app/internal/repository"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// searchQueryMaxLength bounds search queries, in characters.
+	searchQueryMaxLength = 256
+	// embeddingProvider is the provider whose key the Python service embeds queries with; it must
+	// match the model lecture chunks were embedded with.
+	embeddingProvider = "openai"
+)
+
+var (
+	ErrInvalidSearchQuery   = errors.New("search query must be between 1 and 256 characters")
+	ErrEmbeddingKeyRequired = errors.New("an OpenAI API key is required for semantic search")
+)
+
+// SearchService searches a user's courses, lectures, notes and chats.
+type SearchService interface {
+	// Search returns the user's content matching query, best matches first. Snippets are HTML
+	// with matched terms wrapped in <mark> elements and everything else escaped.
+	Search(ctx context.Context, userID, query string, filter model.SearchFilter, limit, offset int) ([]model.SearchResult, error)
+	// SemanticSearch returns the lecture chunks closest in meaning to query, from one lecture, one
+	// course or all of the user's lectures. The lecture or course must belong to the user.
+	SemanticSearch(ctx context.Context, userID, query string, filter model.SearchFilter, limit int) ([]model.ChunkMatch, error)
+}
+
+type searchService struct {
+	repo         repository.SearchRepository
+	lectureRepo  repository.LectureRepository
+	courseRepo   repository.CourseRepository
+	userRepo     repository.UserRepository
+	pythonClient PythonClient
+	searchLogger zerolog.Logger
+}
+
+// NewSearchService creates a new SearchService.
+func NewSearchService(
+	repo repository.SearchRepository,
+	lectureRepo repository.LectureRepository,
+	courseRepo repository.CourseRepository,
+	userRepo repository.UserRepository,
+	pythonClient PythonClient,
+	logger zerolog.Logger,
+) SearchService {
+	return &searchService{
+		repo:         repo,
+		lectureRepo:  lectureRepo,
+		courseRepo:   courseRepo,
+		userRepo:     userRepo,
+		pythonClient: pythonClient,
+		searchLogger: logger.With().Str("service", "SearchService").Logger(),
+	}
+}
+
+func (s *searchService) Search(ctx context.Context, userID, query string, filter model.SearchFilter, limit, offset int) ([]model.SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || utf8.RuneCountInString(query) > searchQueryMaxLength {
+		return nil, ErrInvalidSearchQuery
+	}
+
+	results, err := s.repo.Search(ctx, userID, query, filter, limit, offset)
+	if err != nil {
+		s.searchLogger.Error().Err(err).Str("user_id", userID).Msg("Failed to search")
+		return nil, err
+	}
+	for i := range results {
+		results[i].Snippet = highlightSnippet(results[i].Snippet)
+	}
+	return results, nil
+}
+
+func (s *searchService) SemanticSearch(ctx context.Context, userID, query string, filter model.SearchFilter, limit int) ([]model.ChunkMatch, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || utf8.RuneCountInString(query) > searchQueryMaxLength {
+		return nil, ErrInvalidSearchQuery
+	}
+	if err := s.verifyScope(ctx, userID, filter); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		s.searchLogger.Error().Err(err).Str("user_id", userID).Msg("Failed to get user for semantic search")
+		return nil, err
+	}
+	if user == nil || !user.APIKeysProvided[embeddingProvider] {
+		return nil, ErrEmbeddingKeyRequired
+	}
+
+	embedding, err := s.pythonClient.EmbedQuery(ctx, userID, query)
+	if err != nil {
+		s.searchLogger.Error().Err(err).Str("user_id", userID).Msg("Failed to embed search query")
+		return nil, fmt.Errorf("embedding search query: %w", err)
+	}
+
+	matches, err := s.repo.SearchChunks(ctx, userID, embedding, filter, limit)
+	if err != nil {
+		s.searchLogger.Error().Err(err).Str("user_id", userID).Msg("Failed to search lecture chunks")
+		return nil, err
+	}
+	return matches, nil
+}
+
+// verifyScope checks that the lecture and course being searched belong to the user, the same way
+// lectures are authorized through their course.
+func (s *searchService) verifyScope(ctx context.Context, userID string, filter model.SearchFilter) error {
+	if filter.LectureID != "" {
+		lecture, err := s.lectureRepo.GetLectureByID(ctx, filter.LectureID)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve lecture: %w", err)
+		}
+		if lecture == nil {
+			return ErrLectureNotFound
+		}
+		course, err := s.courseRepo.GetCourseByID(ctx, lecture.CourseID)
+		if err != nil || course == nil || course.UserID != userID {
+			return ErrLectureNotFound
+		}
+	}
+	if filter.CourseID != "" {
+		course, err := s.courseRepo.GetCourseByID(ctx, filter.CourseID)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve course: %w", err)
+		}
+		if course == nil || course.UserID != userID {
+			return ErrCourseNotFound
+		}
+	}
+	return nil
+}
+
+// highlightSnippet escapes a snippet from the database and turns its highlight markers into
+// <mark> elements, so clients can render it as HTML without trusting the user's text.
+func highlightSnippet(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, model.SearchHighlightStart, "<mark>")
+	return strings.ReplaceAll(snippet, model.SearchHighlightStop, "</mark>")
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"testing"
+
+	"app/internal/model"
+)
+
+func TestHighlightSnippet(t *testing.T) {
+	start, stop := model.SearchHighlightStart, model.SearchHighlightStop
+	tests := []struct {
+		snippet string
+		want    string
+	}{
+		{"", ""},
+		{"plain text", "plain text"},
+		{"the " + start + "entropy" + stop + " of a system", "the <mark>entropy</mark> of a system"},
+		{start + "a" + stop + " and " + start + "b" + stop, "<mark>a</mark> and <mark>b</mark>"},
+		{"<script>alert(1)</script>", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{start + "<img src=x onerror=alert(1)>" + stop, "<mark>&lt;img src=x onerror=alert(1)&gt;</mark>"},
+		{`<a href="javascript:x">'q'</a>`, "&lt;a href=&#34;javascript:x&#34;&gt;&#39;q&#39;&lt;/a&gt;"},
+		{"Tom & Jerry", "Tom &amp; Jerry"},
+		// Markup the user typed is never taken for a highlight
+		{"<mark>fake</mark>", "&lt;mark&gt;fake&lt;/mark&gt;"},
+		{"&lt;already escaped&gt;", "&amp;lt;already escaped&amp;gt;"},
+	}
+	for _, tt := range tests {
+		if got := highlightSnippet(tt.snippet); got != tt.want {
+			t.Errorf("highlightSnippet(%q) = %q, want %q", tt.snippet, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"app/internal/repository"
+
+	"github.com/rs/zerolog"
+)
+
+// uploadJanitorBatchSize bounds how many abandoned lectures are cleaned up per run.
+const uploadJanitorBatchSize = 100
+
+// UploadJanitorConfig controls which lectures count as abandoned and how often they are cleaned up.
+type UploadJanitorConfig struct {
+	// TTL is how long a lecture may stay in 'uploading' without changes.
+	TTL      time.Duration
+	Interval time.Duration
+	// DryRun logs the lectures that would be deleted without deleting them.
+	DryRun bool
+}
+
+// UploadJanitor periodically deletes lectures whose upload was started but never completed,
+// along with any partial objects in storage.
+type UploadJanitor struct {
+	lectureRepo    repository.LectureRepository
+	lectureService LectureService
+	cfg            UploadJanitorConfig
+	logger         zerolog.Logger
+}
+
+// NewUploadJanitor creates a new UploadJanitor.
+func NewUploadJanitor(lectureRepo repository.LectureRepository, lectureService LectureService, cfg UploadJanitorConfig, logger zerolog.Logger) *UploadJanitor {
+	return &UploadJanitor{
+		lectureRepo:    lectureRepo,
+		lectureService: lectureService,
+		cfg:            cfg,
+		logger:         logger.With().Str("service", "UploadJanitor").Logger(),
+	}
+}
+
+// Run cleans up abandoned uploads every interval until ctx is cancelled.
+func (j *UploadJanitor) Run(ctx context.Context) {
+	j.logger.Info().Dur("ttl", j.cfg.TTL).Dur("interval", j.cfg.Interval).Bool("dry_run", j.cfg.DryRun).Msg("Starting upload janitor")
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		j.cleanup(ctx)
+		select {
+		case <-ctx.Done():
+			j.logger.Info().Msg("Stopped upload janitor")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *UploadJanitor) cleanup(ctx context.Context) {
+	start := time.Now()
+	staleBefore := start.Add(-j.cfg.TTL)
+	lectures, err := j.lectureRepo.GetStaleUploadingLectures(ctx, staleBefore, uploadJanitorBatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			j.logger.Error().Err(err).Msg("Failed to find abandoned uploads")
+		}
+		return
+	}
+
+	deleted, skipped, failed := 0, 0, 0
+	for _, lecture := range lectures {
+		log := j.logger.With().
+			Str("lecture_id", lecture.ID).
+			Str("user_id", lecture.UserID).
+			Time("updated_at", lecture.UpdatedAt).
+			Logger()
+		if j.cfg.DryRun {
+			log.Info().Msg("Would delete abandoned upload (dry run)")
+			continue
+		}
+		// The delete re-checks the status and age, so an upload completed or resumed since the
+		// lecture was found is kept. Storage is only cleaned up once the lecture is gone.
+		ok, err := j.lectureService.DeleteAbandonedUpload(ctx, lecture.ID, staleBefore)
+		if err != nil {
+			failed++
+			log.Error().Err(err).Msg("Failed to delete abandoned upload")
+			continue
+		}
+		if !ok {
+			skipped++
+			log.Info().Msg("Kept upload that became active again")
+			continue
+		}
+		deleted++
+	}
+
+	j.logger.Info().
+		Bool("dry_run", j.cfg.DryRun).
+		Int("found", len(lectures)).
+		Int("deleted", deleted).
+		Int("skipped", skipped).
+		Int("failed", failed).
+		Bool("more_pending", len(lectures) == uploadJanitorBatchSize).
+		Dur("duration", time.Since(start)).
+		Msg("Upload janitor run finished")
+}
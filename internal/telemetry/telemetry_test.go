@@ -0,0 +1,27 @@
+package telemetry
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSpanName(t *testing.T) {
+	tests := []struct {
+		method string
+		target string
+		want   string
+	}{
+		{"GET", "/v1/lectures", "GET /v1/lectures"},
+		{"GET", "/v1/lectures/3f2b1c4e-1111-4a2b-9c3d-123456789abc", "GET /v1/lectures/{id}"},
+		{"POST", "/v1/lectures/3F2B1C4E-1111-4A2B-9C3D-123456789ABC/chats/0b8e2f9a-2222-4c3d-8e4f-abcdefabcdef/stream", "POST /v1/lectures/{id}/chats/{id}/stream"},
+		{"GET", "/v1/lectures/3f2b1c4e-1111-4a2b-9c3d-123456789abc?x=1", "GET /v1/lectures/{id}"},
+		{"GET", "/v1/admin/users/not-a-uuid", "GET /v1/admin/users/not-a-uuid"},
+		{"GET", "/v1/lectures/3f2b1c4e11114a2b9c3d123456789abcdef", "GET /v1/lectures/3f2b1c4e11114a2b9c3d123456789abcdef"},
+		{"GET", "/v1/lectures/3f2b1c4e-1111-4a2b-9c3d-123456789abz", "GET /v1/lectures/3f2b1c4e-1111-4a2b-9c3d-123456789abz"},
+	}
+	for _, tt := range tests {
+		if got := SpanName("", httptest.NewRequest(tt.method, tt.target, nil)); got != tt.want {
+			t.Errorf("SpanName(%s %s) = %q, want %q", tt.method, tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package util
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksMinRefreshInterval limits how often a token with an unknown key ID can trigger a JWKS
+// fetch, so forged key IDs cannot be used to hammer the auth server.
+const jwksMinRefreshInterval = time.Minute
+
+// Claims are the claims of a Supabase Auth access token.
+type Claims struct {
+	Email        string         `json:"email"`
+	Phone        string         `json:"phone"`
+	Role         string         `json:"role"`
+	SessionID    string         `json:"session_id"`
+	AAL          string         `json:"aal"`
+	IsAnonymous  bool           `json:"is_anonymous"`
+	AppMetadata  map[string]any `json:"app_metadata"`
+	UserMetadata map[string]any `json:"user_metadata"`
+	jwt.RegisteredClaims
+}
+
+// HasRole reports whether Supabase Auth grants the role through the "roles" array of the user's
+// app_metadata, which only the service role can write. The top-level "role" claim is the
+// Postgres role of the session, not an application role.
+func (c *Claims) HasRole(role string) bool {
+	roles, _ := c.AppMetadata["roles"].([]any)
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTVerifierConfig configures a JWTVerifier. At least one of JWKSURL and Secret must be set.
+type JWTVerifierConfig struct {
+	// JWKSURL serves the project's public signing keys, for RS256 and ES256 tokens
+	JWKSURL string
+	// Secret is the legacy shared secret; HS256 tokens are rejected when it is empty
+	Secret   string
+	Issuer   string
+	Audience string
+	// Leeway is the clock skew tolerated when checking exp, nbf and iat
+	Leeway time.Duration
+	// CacheTTL is how long fetched keys are used before the JWKS is fetched again
+	CacheTTL   time.Duration
+	HTTPClient *http.Client
+}
+
+// JWTVerifier verifies Supabase Auth access tokens: the algorithm must be one the verifier was
+// configured for, the signature must match a key from the JWKS (or the shared secret for HS256),
+// and iss, aud and exp must be present and valid.
+type JWTVerifier struct {
+	cfg    JWTVerifierConfig
+	parser *jwt.Parser
+
+	mu         sync.Mutex
+	keys       map[string]any // public keys by key ID
+	fetchedAt  time.Time
+	attemptAt  time.Time
+	fetchErr   error         // of the last fetch
+	fetching   chan struct{} // closed when the running fetch ends; nil when none is running
+	httpClient *http.Client
+}
+
+// NewJWTVerifier creates a JWTVerifier. Keys are fetched on first use.
+func NewJWTVerifier(cfg JWTVerifierConfig) (*JWTVerifier, error) {
+	if cfg.JWKSURL == "" && cfg.Secret == "" {
+		return nil, errors.New("a JWKS URL or a JWT secret is required")
+	}
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("a JWT issuer and audience are required")
+	}
+
+	var methods []string
+	if cfg.JWKSURL != "" {
+		methods = append(methods, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg())
+	}
+	if cfg.Secret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &JWTVerifier{
+		cfg: cfg,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(methods),
+			jwt.WithIssuer(cfg.Issuer),
+			jwt.WithAudience(cfg.Audience),
+			jwt.WithExpirationRequired(),
+			jwt.WithIssuedAt(),
+			jwt.WithLeeway(cfg.Leeway),
+		),
+		keys:       map[string]any{},
+		httpClient: httpClient,
+	}, nil
+}
+
+// Verify checks the token and returns its claims.
+func (v *JWTVerifier) Verify(ctx context.Context, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := v.parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			return []byte(v.cfg.Secret), nil
+		}
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no key ID")
+		}
+		return v.publicKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return claims, nil
+}
+
+// publicKey returns the signing key with the given ID, fetching the JWKS when the cache has
+// expired or, at most once per jwksMinRefreshInterval, when the key is unknown, as after a
+// key rotation. Only one fetch runs at a time and the lock is not held during it: known keys
+// are used while it runs, and only requests with an unknown key wait for it. If a fetch fails,
+// the cached keys are used until one succeeds.
+func (v *JWTVerifier) publicKey(ctx context.Context, kid string) (any, error) {
+	v.mu.Lock()
+	key, known := v.keys[kid]
+	expired := time.Since(v.fetchedAt) > v.cfg.CacheTTL
+	done := v.fetching
+	if done == nil && (expired || !known) && time.Since(v.attemptAt) > jwksMinRefreshInterval {
+		done = make(chan struct{})
+		v.fetching, v.attemptAt = done, time.Now()
+		// A canceled request must not fail the fetch for everyone waiting on it
+		go v.refreshKeys(context.WithoutCancel(ctx), done)
+	}
+	v.mu.Unlock()
+
+	if known {
+		return key, nil
+	}
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	if v.fetchErr != nil {
+		return nil, v.fetchErr
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// refreshKeys fetches the JWKS and swaps in its keys, then closes done.
+func (v *JWTVerifier) refreshKeys(ctx context.Context, done chan struct{}) {
+	keys, err := v.fetchKeys(ctx)
+
+	v.mu.Lock()
+	v.fetchErr = err
+	if err == nil {
+		v.keys, v.fetchedAt = keys, time.Now()
+	}
+	v.fetching = nil
+	v.mu.Unlock()
+	close(done)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (v *JWTVerifier) fetchKeys(ctx context.Context) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.cfg.JWKSURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating JWKS request: %w", err)
+	}
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decoding JWKS: %w", err)
+	}
+
+	// A malformed key is skipped rather than failing the fetch, so the other keys, such as the
+	// one rotated in, can still be used
+	keys := make(map[string]any, len(set.Keys))
+	var keyErrs []error
+	for _, k := range set.Keys {
+		if k.Kid == "" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			keyErrs = append(keyErrs, fmt.Errorf("parsing JWKS key %q: %w", k.Kid, err))
+			continue
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	if len(keys) == 0 && len(keyErrs) > 0 {
+		return nil, errors.Join(keyErrs...)
+	}
+	return keys, nil
+}
+
+// publicKey converts an RSA or P-256 key. Other key types are skipped with a nil key.
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decoding modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decoding exponent: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != 32 {
+			return nil, errors.New("invalid x coordinate")
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil || len(y) != 32 {
+			return nil, errors.New("invalid y coordinate")
+		}
+		// Reject points that are not on the curve
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("invalid point: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,382 @@
+package util
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://project.supabase.co/auth/v1"
+	testAudience = "authenticated"
+	testSecret   = "legacy-secret"
+)
+
+var (
+	testRSAKey = mustRSAKey()
+	testECKey  = mustECKey()
+)
+
+func mustRSAKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func mustECKey() *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) jsonWebKey {
+	return jsonWebKey{Kty: "RSA", Kid: kid, Use: "sig", N: b64(key.N.Bytes()), E: b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) jsonWebKey {
+	return jsonWebKey{Kty: "EC", Kid: kid, Use: "sig", Crv: "P-256", X: b64(key.X.FillBytes(make([]byte, 32))), Y: b64(key.Y.FillBytes(make([]byte, 32)))}
+}
+
+// jwksServer serves a JWKS that tests can replace, and counts how often it is fetched.
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    []jsonWebKey
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, keys ...jsonWebKey) *jwksServer {
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKeys(keys ...jsonWebKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func newTestVerifier(t *testing.T, jwksURL, secret string) *JWTVerifier {
+	t.Helper()
+	v, err := NewJWTVerifier(JWTVerifierConfig{
+		JWKSURL:  jwksURL,
+		Secret:   secret,
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		Leeway:   30 * time.Second,
+		CacheTTL: 10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() error = %v", err)
+	}
+	return v
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"sub":  "user-1",
+		"iss":  testIssuer,
+		"aud":  testAudience,
+		"iat":  now.Unix(),
+		"exp":  now.Add(time.Hour).Unix(),
+		"role": "authenticated",
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return signed
+}
+
+func TestJWTVerifierAcceptsConfiguredAlgorithms(t *testing.T) {
+	server := newJWKSServer(t, rsaJWK("rsa-1", &testRSAKey.PublicKey), ecJWK("ec-1", &testECKey.PublicKey))
+	v := newTestVerifier(t, server.URL, testSecret)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"RS256", sign(t, jwt.SigningMethodRS256, "rsa-1", testRSAKey, validClaims())},
+		{"ES256", sign(t, jwt.SigningMethodES256, "ec-1", testECKey, validClaims())},
+		{"HS256", sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), validClaims())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Verify(context.Background(), tt.token)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if claims.Subject != "user-1" || claims.Role != "authenticated" {
+				t.Errorf("Verify() claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestJWTVerifierRejectsInvalidTokens(t *testing.T) {
+	server := newJWKSServer(t, rsaJWK("rsa-1", &testRSAKey.PublicKey), ecJWK("ec-1", &testECKey.PublicKey))
+	v := newTestVerifier(t, server.URL, "")
+
+	with := func(change func(jwt.MapClaims)) jwt.MapClaims {
+		claims := validClaims()
+		change(claims)
+		return claims
+	}
+	otherRSAKey := mustRSAKey()
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"HS256 without a configured secret", sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), validClaims())},
+		{"HS384", sign(t, jwt.SigningMethodHS384, "", []byte(testSecret), validClaims())},
+		{"RS384", sign(t, jwt.SigningMethodRS384, "rsa-1", testRSAKey, validClaims())},
+		{"unsigned", sign(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType, validClaims())},
+		{"signed with another key", sign(t, jwt.SigningMethodRS256, "rsa-1", otherRSAKey, validClaims())},
+		{"key of another type", sign(t, jwt.SigningMethodRS256, "ec-1", testRSAKey, validClaims())},
+		{"no key ID", sign(t, jwt.SigningMethodRS256, "", testRSAKey, validClaims())},
+		{"wrong issuer", sign(t, jwt.SigningMethodRS256, "rsa-1", testRSAKey, with(func(c jwt.MapClaims) { c["iss"] = "https://evil.example/auth/v1" }))},
+		{"wrong audience", sign(t, jwt.SigningMethodRS256, "rsa-1", testRSAKey, with(func(c jwt.MapClaims) { c["aud"] = "anon" }))},
+		{"no audience", sign(t, jwt.SigningMethodRS256, "rsa-1", testRSAKey, with(func(c jwt.MapClaims) { delete(c, "aud") }))},
+		{"expired beyond leeway", sign(t, jwt.SigningMethodRS256, "rsa-1", testRSAKey, with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }))},
+		{"no expiry", sign(t, jwt.SigningMethodRS256, "rsa-1", testRSAKey, with(func(c jwt.MapClaims) { delete(c, "exp") }))},
+		{"issued in the future", sign(t, jwt.SigningMethodRS256, "rsa-1", testRSAKey, with(func(c jwt.MapClaims) { c["iat"] = time.Now().Add(time.Hour).Unix() }))},
+		{"no subject", sign(t, jwt.SigningMethodRS256, "rsa-1", testRSAKey, with(func(c jwt.MapClaims) { delete(c, "sub") }))},
+		{"malformed", "not.a.token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.Verify(context.Background(), tt.token); err == nil {
+				t.Error("Verify() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestJWTVerifierToleratesClockSkew(t *testing.T) {
+	server := newJWKSServer(t, rsaJWK("rsa-1", &testRSAKey.PublicKey))
+	v := newTestVerifier(t, server.URL, "")
+
+	claims := validClaims()
+	claims["exp"] = time.Now().Add(-10 * time.Second).Unix()
+	claims["iat"] = time.Now().Add(10 * time.Second).Unix()
+	if _, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "rsa-1", testRSAKey, claims)); err != nil {
+		t.Errorf("Verify() error = %v, want skew within the leeway to be tolerated", err)
+	}
+}
+
+func TestJWTVerifierRequiresIssuerAndKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  JWTVerifierConfig
+	}{
+		{"no keys", JWTVerifierConfig{Issuer: testIssuer, Audience: testAudience}},
+		{"no issuer", JWTVerifierConfig{Secret: testSecret, Audience: testAudience}},
+		{"no audience", JWTVerifierConfig{Secret: testSecret, Issuer: testIssuer}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewJWTVerifier(tt.cfg); err == nil {
+				t.Error("NewJWTVerifier() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestJWTVerifierCachesKeys(t *testing.T) {
+	server := newJWKSServer(t, rsaJWK("rsa-1", &testRSAKey.PublicKey))
+	v := newTestVerifier(t, server.URL, "")
+
+	token := sign(t, jwt.SigningMethodRS256, "rsa-1", testRSAKey, validClaims())
+	for range 3 {
+		if _, err := v.Verify(context.Background(), token); err != nil {
+			t.Fatalf("Verify() error = %v", err)
+		}
+	}
+	if got := server.fetches.Load(); got != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", got)
+	}
+}
+
+func TestJWTVerifierThrottlesUnknownKeyRefreshes(t *testing.T) {
+	server := newJWKSServer(t, rsaJWK("rsa-1", &testRSAKey.PublicKey))
+	v := newTestVerifier(t, server.URL, "")
+
+	if _, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "rsa-1", testRSAKey, validClaims())); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	// Forged key IDs must not trigger a fetch each
+	for _, kid := range []string{"forged-1", "forged-2", "forged-3"} {
+		if _, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, kid, testRSAKey, validClaims())); err == nil {
+			t.Fatalf("Verify() with key %q succeeded, want an error", kid)
+		}
+	}
+	if got := server.fetches.Load(); got != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", got)
+	}
+}
+
+func TestJWTVerifierPicksUpRotatedKeys(t *testing.T) {
+	server := newJWKSServer(t, rsaJWK("rsa-1", &testRSAKey.PublicKey))
+	v := newTestVerifier(t, server.URL, "")
+	if _, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "rsa-1", testRSAKey, validClaims())); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	server.setKeys(rsaJWK("rsa-1", &testRSAKey.PublicKey), ecJWK("ec-2", &testECKey.PublicKey))
+	// Pretend the last fetch was long enough ago for an unknown key to trigger another
+	v.mu.Lock()
+	v.attemptAt = time.Now().Add(-2 * jwksMinRefreshInterval)
+	v.mu.Unlock()
+
+	if _, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodES256, "ec-2", testECKey, validClaims())); err != nil {
+		t.Fatalf("Verify() with the rotated key error = %v", err)
+	}
+	if got := server.fetches.Load(); got != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", got)
+	}
+}
+
+func TestJWTVerifierFetchesOnceForConcurrentRequests(t *testing.T) {
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []jsonWebKey{rsaJWK("rsa-1", &testRSAKey.PublicKey)}})
+	}))
+	t.Cleanup(server.Close)
+	v := newTestVerifier(t, server.URL, "")
+
+	token := sign(t, jwt.SigningMethodRS256, "rsa-1", testRSAKey, validClaims())
+	errs := make(chan error, 10)
+	for range cap(errs) {
+		go func() {
+			_, err := v.Verify(context.Background(), token)
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	for range cap(errs) {
+		if err := <-errs; err != nil {
+			t.Errorf("Verify() error = %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", got)
+	}
+}
+
+func TestJWTVerifierWaitIsBoundedByContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(func() {
+		close(release)
+		server.Close()
+	})
+	v := newTestVerifier(t, server.URL, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := v.Verify(ctx, sign(t, jwt.SigningMethodRS256, "rsa-1", testRSAKey, validClaims())); err == nil {
+		t.Error("Verify() succeeded, want an error once the context is done")
+	}
+}
+
+func TestJWTVerifierSkipsUnparsableKeys(t *testing.T) {
+	badKey := rsaJWK("rsa-bad", &testRSAKey.PublicKey)
+	badKey.E = "!!"
+	server := newJWKSServer(t, badKey, ecJWK("ec-1", &testECKey.PublicKey))
+	v := newTestVerifier(t, server.URL, "")
+
+	if _, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodES256, "ec-1", testECKey, validClaims())); err != nil {
+		t.Errorf("Verify() error = %v, want the valid key to be used", err)
+	}
+}
+
+func TestJSONWebKeyPublicKey(t *testing.T) {
+	offCurve := ecJWK("ec-1", &testECKey.PublicKey)
+	offCurve.Y = b64(new(big.Int).Add(testECKey.Y, big.NewInt(1)).FillBytes(make([]byte, 32)))
+	smallExponent := rsaJWK("rsa-1", &testRSAKey.PublicKey)
+	smallExponent.E = b64([]byte{1})
+
+	tests := []struct {
+		name    string
+		key     jsonWebKey
+		wantKey bool
+		wantErr bool
+	}{
+		{"RSA", rsaJWK("rsa-1", &testRSAKey.PublicKey), true, false},
+		{"P-256", ecJWK("ec-1", &testECKey.PublicKey), true, false},
+		{"point off the curve", offCurve, false, true},
+		{"short coordinate", jsonWebKey{Kty: "EC", Crv: "P-256", X: b64([]byte{1}), Y: b64([]byte{1})}, false, true},
+		{"RSA exponent too small", smallExponent, false, true},
+		{"unsupported curve is skipped", jsonWebKey{Kty: "EC", Crv: "P-384"}, false, false},
+		{"unsupported key type is skipped", jsonWebKey{Kty: "oct"}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := tt.key.publicKey()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("publicKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (key != nil) != tt.wantKey {
+				t.Errorf("publicKey() = %v, wantKey %v", key, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestClaimsHasRole(t *testing.T) {
+	claims := &Claims{AppMetadata: map[string]any{"roles": []any{"admin"}}, Role: "support"}
+	if !claims.HasRole("admin") {
+		t.Error(`HasRole("admin") = false, want true`)
+	}
+	// The top-level role claim is the Postgres role, not an application role
+	if claims.HasRole("support") {
+		t.Error(`HasRole("support") = true, want false`)
+	}
+	if (&Claims{}).HasRole("admin") {
+		t.Error(`HasRole("admin") without app_metadata = true, want false`)
+	}
+}
@@ -17,10 +17,12 @@ CREATE TABLE IF NOT EXISTS courses (
   description TEXT        DEFAULT '',
   is_default  BOOLEAN     NOT NULL DEFAULT FALSE,
   created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', title)) STORED
 );
 CREATE UNIQUE INDEX IF NOT EXISTS idx_unique_default_course_per_user ON courses(user_id) WHERE is_default;
 CREATE INDEX IF NOT EXISTS idx_courses_user_id ON courses(user_id);
+CREATE INDEX IF NOT EXISTS idx_courses_search ON courses USING GIN(search_vector);
 
 -------------------------------------------------------------------------------
 -- 2. User Profile Table
@@ -35,6 +37,7 @@ CREATE TABLE IF NOT EXISTS user_profiles (
   created_at            TIMESTAMPTZ NOT NULL DEFAULT NOW(),
   updated_at            TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
+CREATE INDEX IF NOT EXISTS idx_user_profiles_email ON user_profiles(lower(email));
 
 -------------------------------------------------------------------------------
 -- 3. Lecture Table
@@ -54,6 +57,9 @@ CREATE TABLE IF NOT EXISTS lectures (
   course_id                 UUID            NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
   title                     TEXT            NOT NULL,
   storage_path              TEXT            NOT NULL DEFAULT '',
+  file_type                 TEXT            NOT NULL DEFAULT 'pdf',
+  mime_type                 TEXT            NOT NULL DEFAULT 'application/pdf',
+  multipart_upload_id       TEXT            DEFAULT NULL, -- Active S3 multipart upload, if any
   status                    lecture_status  NOT NULL DEFAULT 'uploading',
 
   -- Error details
@@ -69,11 +75,62 @@ CREATE TABLE IF NOT EXISTS lectures (
   created_at                TIMESTAMPTZ     NOT NULL DEFAULT NOW(),
   updated_at                TIMESTAMPTZ     NOT NULL DEFAULT NOW(),
   accessed_at               TIMESTAMPTZ     NOT NULL DEFAULT NOW(),
-  completed_at              TIMESTAMPTZ     DEFAULT NULL
+  processing_started_at     TIMESTAMPTZ     DEFAULT NULL,
+  completed_at              TIMESTAMPTZ     DEFAULT NULL,
+
+  -- Full-text search
+  search_vector             TSVECTOR        GENERATED ALWAYS AS (to_tsvector('english', title)) STORED
 );
 
 CREATE INDEX IF NOT EXISTS idx_lectures_user_id   ON lectures(user_id);
 CREATE INDEX IF NOT EXISTS idx_lectures_course_id ON lectures(course_id);
+CREATE INDEX IF NOT EXISTS idx_lectures_uploading ON lectures(updated_at) WHERE status = 'uploading';
+CREATE INDEX IF NOT EXISTS idx_lectures_in_flight ON lectures(user_id) WHERE status IN ('pending_processing', 'parsing', 'processing');
+CREATE INDEX IF NOT EXISTS idx_lectures_completed ON lectures(completed_at DESC) WHERE status = 'complete';
+CREATE INDEX IF NOT EXISTS idx_lectures_search    ON lectures USING GIN(search_vector);
+
+-- Stamp when a processing run starts and finishes, so progress endpoints can report
+-- elapsed time and estimate completion from past runs.
+CREATE OR REPLACE FUNCTION set_lecture_processing_timestamps() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+  IF NEW.status = 'pending_processing' THEN
+    NEW.processing_started_at := NOW();
+    NEW.completed_at := NULL;
+  ELSIF NEW.status = 'complete' AND NEW.completed_at IS NULL THEN
+    NEW.completed_at := NOW();
+  END IF;
+  RETURN NEW;
+END;
+$$;
+
+CREATE OR REPLACE TRIGGER lectures_set_processing_timestamps
+  BEFORE UPDATE ON lectures
+  FOR EACH ROW
+  WHEN (OLD.status IS DISTINCT FROM NEW.status)
+  EXECUTE FUNCTION set_lecture_processing_timestamps();
+
+-- Bump updated_at whenever the pipeline records progress, whether or not the writer sets it.
+-- Reprocessing treats an in-flight lecture whose updated_at is older than 30 minutes as stuck.
+CREATE OR REPLACE FUNCTION touch_lecture_on_progress() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+  NEW.updated_at := NOW();
+  RETURN NEW;
+END;
+$$;
+
+CREATE OR REPLACE TRIGGER lectures_touch_on_progress
+  BEFORE UPDATE ON lectures
+  FOR EACH ROW
+  WHEN (
+    OLD.status IS DISTINCT FROM NEW.status
+    OR OLD.total_slides IS DISTINCT FROM NEW.total_slides
+    OR OLD.total_sub_images IS DISTINCT FROM NEW.total_sub_images
+    OR OLD.processed_sub_images IS DISTINCT FROM NEW.processed_sub_images
+    OR OLD.embeddings_complete IS DISTINCT FROM NEW.embeddings_complete
+  )
+  EXECUTE FUNCTION touch_lecture_on_progress();
 
 -------------------------------------------------------------------------------
 -- 4. Slide Table
@@ -126,6 +183,23 @@ CREATE TABLE IF NOT EXISTS embeddings (
 CREATE INDEX IF NOT EXISTS idx_embeddings_vector ON embeddings USING ivfflat(vector) WITH (lists = 100);
 CREATE INDEX IF NOT EXISTS idx_embeddings_lecture_slide ON embeddings(lecture_id, slide_number);
 
+-- Embedding does not change the lecture row until it finishes, so count each stored embedding
+-- as progress of its lecture. The touch is throttled to once a minute per lecture.
+CREATE OR REPLACE FUNCTION touch_lecture_on_embedding() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+  UPDATE lectures
+  SET updated_at = NOW()
+  WHERE id = NEW.lecture_id AND updated_at < NOW() - INTERVAL '1 minute';
+  RETURN NULL;
+END;
+$$;
+
+CREATE OR REPLACE TRIGGER embeddings_touch_lecture
+  AFTER INSERT ON embeddings
+  FOR EACH ROW
+  EXECUTE FUNCTION touch_lecture_on_embedding();
+
 -------------------------------------------------------------------------------
 -- 7. Slide Images Table
 -------------------------------------------------------------------------------
@@ -156,39 +230,57 @@ CREATE TABLE IF NOT EXISTS notes (
   lecture_id UUID        NOT NULL REFERENCES lectures(id) ON DELETE CASCADE,
   content    TEXT        NOT NULL,
   created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', content)) STORED
 );
 CREATE INDEX IF NOT EXISTS idx_notes_user_id    ON notes(user_id);
 CREATE INDEX IF NOT EXISTS idx_notes_lecture_id ON notes(lecture_id);
+CREATE INDEX IF NOT EXISTS idx_notes_search     ON notes USING GIN(search_vector);
 
 -------------------------------------------------------------------------------
 -- 9. Chat Table
 -------------------------------------------------------------------------------
 CREATE TABLE IF NOT EXISTS chats (
-  id         UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
-  lecture_id UUID        NOT NULL REFERENCES lectures(id) ON DELETE CASCADE,
-  user_id    UUID        NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
-  title      TEXT        NOT NULL,
-  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+  id                UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+  lecture_id        UUID        REFERENCES lectures(id) ON DELETE CASCADE, -- Set for chats about one lecture
+  course_id         UUID        REFERENCES courses(id) ON DELETE CASCADE,  -- Set for chats across all lectures of a course
+  user_id           UUID        NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+  title             TEXT        NOT NULL,
+  active_message_id UUID        DEFAULT NULL, -- Leaf of the branch shown to the user; NULL for chats that predate branching
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT chats_one_scope CHECK ((lecture_id IS NULL) <> (course_id IS NULL))
 );
 CREATE INDEX IF NOT EXISTS idx_chats_lecture_id ON chats(lecture_id);
 CREATE INDEX IF NOT EXISTS idx_chats_user_id ON chats(user_id);
 CREATE INDEX IF NOT EXISTS idx_chats_lecture_user ON chats(lecture_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_chats_course_user ON chats(course_id, user_id) WHERE course_id IS NOT NULL;
 
 -------------------------------------------------------------------------------
 -- 10. Message Table
 -------------------------------------------------------------------------------
+-- The text parts of a message, which is what full-text search matches and highlights.
+CREATE OR REPLACE FUNCTION message_plain_text(parts JSONB) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+  SELECT COALESCE(string_agg(part->>'text', ' '), '')
+  FROM jsonb_array_elements(parts) AS part
+  WHERE part->>'type' = 'text'
+$$;
+
 CREATE TABLE IF NOT EXISTS messages (
   id         UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
   chat_id    UUID        NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+  parent_id  UUID        REFERENCES messages(id) ON DELETE CASCADE, -- Previous message in the branch; NULL for the first
   role       VARCHAR     NOT NULL CHECK (role IN ('user', 'assistant')),
   parts      JSONB       NOT NULL,
   metadata   JSONB       NOT NULL DEFAULT '{}'::JSONB,
-  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', message_plain_text(parts))) STORED
 );
 CREATE INDEX IF NOT EXISTS idx_messages_chat_id ON messages(chat_id);
 CREATE INDEX IF NOT EXISTS idx_messages_created_at ON messages(created_at);
+CREATE INDEX IF NOT EXISTS idx_messages_parent_id ON messages(parent_id);
+CREATE INDEX IF NOT EXISTS idx_messages_search ON messages USING GIN(search_vector);
 
 -------------------------------------------------------------------------------
 -- 11. Dead-Letter Queue Table
@@ -206,10 +298,20 @@ CREATE TABLE IF NOT EXISTS dead_letter_messages (
   payload             JSONB NOT NULL,
   attributes          JSONB,
   status              dlq_message_status NOT NULL DEFAULT 'unprocessed',
+
+  -- Replay audit trail
+  replay_count        INT NOT NULL DEFAULT 0,
+  last_replayed_at    TIMESTAMPTZ DEFAULT NULL,
+  last_replayed_by    UUID REFERENCES auth.users(id) ON DELETE SET NULL,
+
   created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-  updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+  updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  -- Push delivery is at-least-once, so a redelivered message must not be saved twice
+  UNIQUE(subscription_name, message_id)
 );
 CREATE INDEX IF NOT EXISTS idx_dead_letter_messages_status ON dead_letter_messages(status);
+CREATE INDEX IF NOT EXISTS idx_dead_letter_messages_subscription ON dead_letter_messages(subscription_name);
 CREATE INDEX IF NOT EXISTS idx_dead_letter_messages_created_at ON dead_letter_messages(created_at);
 
 -------------------------------------------------------------------------------
@@ -224,7 +326,101 @@ CREATE TABLE IF NOT EXISTS waitlist (
 CREATE INDEX IF NOT EXISTS idx_waitlist_email ON waitlist(email);
 
 -------------------------------------------------------------------------------
--- 13. Row-Level Security (RLS) Policies
+-- 13. Outbox Table
+-------------------------------------------------------------------------------
+CREATE TYPE outbox_message_status AS ENUM (
+  'pending',
+  'published',
+  'failed'
+);
+
+CREATE TABLE IF NOT EXISTS outbox_messages (
+  id                   UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+  topic                TEXT        NOT NULL,
+  payload              JSONB       NOT NULL,
+  attributes           JSONB,
+  -- Lecture whose ingestion the message starts; marked failed if the message cannot be published
+  lecture_id           UUID        REFERENCES lectures(id) ON DELETE SET NULL,
+  status               outbox_message_status NOT NULL DEFAULT 'pending',
+  attempts             INT         NOT NULL DEFAULT 0,
+  last_error           TEXT,
+  available_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  published_at         TIMESTAMPTZ DEFAULT NULL,
+  published_message_id TEXT,
+  created_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at           TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_pending ON outbox_messages(available_at) WHERE status = 'pending';
+
+-------------------------------------------------------------------------------
+-- 14. User Roles Table
+-------------------------------------------------------------------------------
+-- Operator roles granted in the database, e.g. 'admin'. Roles can also be granted through the
+-- 'roles' array of a user's app_metadata in Supabase Auth.
+CREATE TABLE IF NOT EXISTS user_roles (
+  user_id    UUID        NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+  role       TEXT        NOT NULL,
+  granted_by UUID        REFERENCES auth.users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, role)
+);
+
+-------------------------------------------------------------------------------
+-- 15. Lecture Event Notifications
+-------------------------------------------------------------------------------
+-- The API LISTENs on 'lecture_events' to push processing updates to clients over SSE.
+CREATE OR REPLACE FUNCTION notify_lecture_event() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+  PERFORM pg_notify('lecture_events', json_build_object(
+    'type', 'lecture',
+    'lecture_id', NEW.id,
+    'status', NEW.status,
+    'total_slides', NEW.total_slides,
+    'total_sub_images', NEW.total_sub_images,
+    'processed_sub_images', NEW.processed_sub_images,
+    'embeddings_complete', NEW.embeddings_complete,
+    'updated_at', NEW.updated_at
+  )::text);
+  RETURN NEW;
+END;
+$$;
+
+CREATE OR REPLACE TRIGGER lectures_notify_event
+  AFTER UPDATE ON lectures
+  FOR EACH ROW
+  WHEN (
+    OLD.status IS DISTINCT FROM NEW.status
+    OR OLD.total_slides IS DISTINCT FROM NEW.total_slides
+    OR OLD.total_sub_images IS DISTINCT FROM NEW.total_sub_images
+    OR OLD.processed_sub_images IS DISTINCT FROM NEW.processed_sub_images
+    OR OLD.embeddings_complete IS DISTINCT FROM NEW.embeddings_complete
+  )
+  EXECUTE FUNCTION notify_lecture_event();
+
+CREATE OR REPLACE FUNCTION notify_chat_title_event() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+  PERFORM pg_notify('lecture_events', json_build_object(
+    'type', 'chat_title',
+    'lecture_id', NEW.lecture_id,
+    'chat_id', NEW.id,
+    'title', NEW.title,
+    'updated_at', NEW.updated_at
+  )::text);
+  RETURN NEW;
+END;
+$$;
+
+-- Only lecture chats are announced; course chats have no lecture stream to join.
+CREATE OR REPLACE TRIGGER chats_notify_title_event
+  AFTER UPDATE ON chats
+  FOR EACH ROW
+  WHEN (OLD.title IS DISTINCT FROM NEW.title AND NEW.lecture_id IS NOT NULL)
+  EXECUTE FUNCTION notify_chat_title_event();
+
+-------------------------------------------------------------------------------
+-- 16. Row-Level Security (RLS) Policies
 -------------------------------------------------------------------------------
 
 -- Enable RLS for all relevant tables
@@ -240,6 +436,8 @@ ALTER TABLE public.chats ENABLE ROW LEVEL SECURITY;
 ALTER TABLE public.messages ENABLE ROW LEVEL SECURITY;
 ALTER TABLE public.dead_letter_messages ENABLE ROW LEVEL SECURITY;
 ALTER TABLE public.waitlist ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.outbox_messages ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.user_roles ENABLE ROW LEVEL SECURITY;
 
 -- 1. courses: Users can manage their own courses fully.
 CREATE POLICY "Allow all access to own courses" ON public.courses
@@ -293,16 +491,20 @@ CREATE POLICY "Allow all access to own notes" ON public.notes
     EXISTS (SELECT 1 FROM lectures WHERE lectures.id = notes.lecture_id AND lectures.user_id = auth.uid())
   );
 
--- 11. chats: Users can manage their own chats for lectures they own.
+-- 11. chats: Users can manage their own chats for lectures or courses they own.
 CREATE POLICY "Allow all access to own chats" ON public.chats
   FOR ALL
   USING (
-    auth.uid() = user_id AND
-    EXISTS (SELECT 1 FROM lectures WHERE lectures.id = chats.lecture_id AND lectures.user_id = auth.uid())
+    auth.uid() = user_id AND (
+      EXISTS (SELECT 1 FROM lectures WHERE lectures.id = chats.lecture_id AND lectures.user_id = auth.uid()) OR
+      EXISTS (SELECT 1 FROM courses WHERE courses.id = chats.course_id AND courses.user_id = auth.uid())
+    )
   )
   WITH CHECK (
-    auth.uid() = user_id AND
-    EXISTS (SELECT 1 FROM lectures WHERE lectures.id = chats.lecture_id AND lectures.user_id = auth.uid())
+    auth.uid() = user_id AND (
+      EXISTS (SELECT 1 FROM lectures WHERE lectures.id = chats.lecture_id AND lectures.user_id = auth.uid()) OR
+      EXISTS (SELECT 1 FROM courses WHERE courses.id = chats.course_id AND courses.user_id = auth.uid())
+    )
   );
 
 -- 12. messages: Users can manage messages for chats they own.
@@ -313,10 +515,17 @@ CREATE POLICY "Allow all access to own messages" ON public.messages
       SELECT 1 FROM chats
       WHERE chats.id = messages.chat_id
         AND chats.user_id = auth.uid()
-        AND EXISTS (
-          SELECT 1 FROM lectures
-          WHERE lectures.id = chats.lecture_id
-            AND lectures.user_id = auth.uid()
+        AND (
+          EXISTS (
+            SELECT 1 FROM lectures
+            WHERE lectures.id = chats.lecture_id
+              AND lectures.user_id = auth.uid()
+          ) OR
+          EXISTS (
+            SELECT 1 FROM courses
+            WHERE courses.id = chats.course_id
+              AND courses.user_id = auth.uid()
+          )
         )
     )
   )
@@ -325,10 +534,17 @@ CREATE POLICY "Allow all access to own messages" ON public.messages
       SELECT 1 FROM chats
       WHERE chats.id = messages.chat_id
         AND chats.user_id = auth.uid()
-        AND EXISTS (
-          SELECT 1 FROM lectures
-          WHERE lectures.id = chats.lecture_id
-            AND lectures.user_id = auth.uid()
+        AND (
+          EXISTS (
+            SELECT 1 FROM lectures
+            WHERE lectures.id = chats.lecture_id
+              AND lectures.user_id = auth.uid()
+          ) OR
+          EXISTS (
+            SELECT 1 FROM courses
+            WHERE courses.id = chats.course_id
+              AND courses.user_id = auth.uid()
+          )
         )
     )
   );
@@ -343,4 +559,17 @@ CREATE POLICY "Deny all access to dead_letter_messages" ON public.dead_letter_me
 -- 14. Waitlist Table
 CREATE POLICY "Allow anyone to insert into waitlist" ON public.waitlist
   FOR INSERT
-  WITH CHECK (true);
\ No newline at end of file
+  WITH CHECK (true);
+
+-- 15. outbox_messages: No access for regular users.
+-- These rows are written and drained by the API service only.
+CREATE POLICY "Deny all access to outbox_messages" ON public.outbox_messages
+  FOR ALL
+  USING (false)
+  WITH CHECK (false);
+
+-- 16. user_roles: No access for regular users, so nobody can grant themselves a role.
+CREATE POLICY "Deny all access to user_roles" ON public.user_roles
+  FOR ALL
+  USING (false)
+  WITH CHECK (false);
\ No newline at end of file